@@ -11,7 +11,7 @@ import (
 	"time"
 
 	"github.com/nehmeroumani/pill.go/clean"
-	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/nehmeroumani/pill.go/unified"
 	"github.com/valyala/fasthttp"
 )
 
@@ -209,83 +209,25 @@ func xorToken(a, b []byte) []byte {
 	return res
 }
 
-func GetRequestCSRFToken(r *http.Request) *CSRFToken {
-	// 1. Check the HTTP header first.
-	requestToken := r.Header.Get(tokenRequestHeader)
-
-	// 2. Fall back to the POST (form) value.
-	if requestToken == "" {
-		requestToken = r.PostFormValue(tokenFieldName)
-	}
-
-	// 3. Finally, fall back to the multipart form (if set).
-	if requestToken == "" && r.MultipartForm != nil {
-		vals := r.MultipartForm.Value[tokenFieldName]
-
-		if len(vals) > 0 {
-			requestToken = vals[0]
-		}
-	}
-
-	// Decode the "issued" (pad + masked) token sent in the request. Return a
-	// nil byte slice on a decoding error (this will fail upstream).
-	decodedRequestToken, _ := base64.StdEncoding.DecodeString(requestToken)
-
-	realTokenCookie, err1 := r.Cookie(tokenCookieName)
-	if err1 != nil {
-		return nil
-	}
-	realToken := realTokenCookie.Value
-	if !encryptedToken {
-		decodedRealToken, err2 := base64.StdEncoding.DecodeString(realTokenCookie.Value)
-		if err2 != nil {
-			clean.Error(err2)
-			return nil
-		}
-		realToken = string(decodedRealToken)
-	}
-	if encryptedToken {
-		var err3 error
-		if realToken, err3 = decrypt([]byte(encryptionKey), realToken); err3 != nil {
-			clean.Error(err3)
-			return nil
-		}
-	}
-	csrfToken := &CSRFToken{}
-	csrfToken.RealToken = realToken
-	csrfToken.MaskedToken = string(decodedRequestToken)
-	return csrfToken
-}
-
-func GetFastHttpRequestCSRFToken(requestCtx *fasthttp.RequestCtx) *CSRFToken {
+// GetContextCSRFToken returns the token sent with the request, in the X-Csrf-Token
+// header or the csrf_token form field, along with the real one from its cookie.
+func GetContextCSRFToken(c unified.Context) *CSRFToken {
 	// 1. Check the HTTP header first.
-	requestToken := helpers.BytesToString(requestCtx.Request.Header.Peek(tokenRequestHeader))
+	requestToken := c.Header(tokenRequestHeader)
 
-	// 2. Fall back to the POST (form) value.
+	// 2. Fall back to the POST (urlencoded or multipart form) value.
 	if requestToken == "" {
-		requestToken = helpers.BytesToString(requestCtx.PostArgs().Peek(tokenFieldName))
-	}
-
-	// 3. Finally, fall back to the multipart form (if set).
-	if requestToken == "" {
-		if multipartForm, err := requestCtx.MultipartForm(); err == nil {
-			if vals, ok := multipartForm.Value[tokenFieldName]; ok {
-				if len(vals) > 0 {
-					requestToken = vals[0]
-				}
-			}
-		}
+		requestToken = c.PostFormValue(tokenFieldName)
 	}
 
 	// Decode the "issued" (pad + masked) token sent in the request. Return a
 	// nil byte slice on a decoding error (this will fail upstream).
 	decodedRequestToken, _ := base64.StdEncoding.DecodeString(requestToken)
 
-	realTokenCookie := requestCtx.Request.Header.Cookie(tokenCookieName)
-	if realTokenCookie == nil {
+	realToken := c.Cookie(tokenCookieName)
+	if realToken == "" {
 		return nil
 	}
-	realToken := string(realTokenCookie)
 	if !encryptedToken {
 		decodedRealToken, err := base64.StdEncoding.DecodeString(realToken)
 		if err != nil {
@@ -295,9 +237,9 @@ func GetFastHttpRequestCSRFToken(requestCtx *fasthttp.RequestCtx) *CSRFToken {
 		realToken = string(decodedRealToken)
 	}
 	if encryptedToken {
-		var e error
-		if realToken, e = decrypt([]byte(encryptionKey), realToken); e != nil {
-			clean.Error(e)
+		var err error
+		if realToken, err = decrypt([]byte(encryptionKey), realToken); err != nil {
+			clean.Error(err)
 			return nil
 		}
 	}
@@ -307,6 +249,14 @@ func GetFastHttpRequestCSRFToken(requestCtx *fasthttp.RequestCtx) *CSRFToken {
 	return csrfToken
 }
 
+func GetRequestCSRFToken(r *http.Request) *CSRFToken {
+	return GetContextCSRFToken(unified.NewHTTPContext(nil, r))
+}
+
+func GetFastHttpRequestCSRFToken(requestCtx *fasthttp.RequestCtx) *CSRFToken {
+	return GetContextCSRFToken(unified.NewFastHttpContext(requestCtx))
+}
+
 func contains(vals []string, s string) bool {
 	for _, v := range vals {
 		if v == s {
@@ -0,0 +1,223 @@
+// Package assets fingerprints the static files: a manifest, written at build time by
+// the pill-assets command, maps their names to names carrying a hash of their
+// content (js/app.js -> js/app.3f9c1a.js), which may be cached forever.
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+// ManifestName is the name of the manifest written by pill-assets, by default next to
+// the static files directory so it isn't served with them.
+const ManifestName = "assets-manifest.json"
+
+// HashLength is the number of hex digits of the hash put in the fingerprinted names.
+const HashLength = 6
+
+// CacheControl is the Cache-Control header of the fingerprinted files.
+const CacheControl = "public, max-age=31536000, immutable"
+
+// Manifest maps the slash-separated paths of the static files, relative to the
+// static files directory, to their fingerprinted paths.
+type Manifest map[string]string
+
+var (
+	mutex     sync.RWMutex
+	manifest  = Manifest{}
+	originals = map[string]string{}
+	urlPrefix = "/static"
+)
+
+// Init loads the manifest written by pill-assets, none when manifestPath is empty, and
+// sets the URL prefix of the paths returned by Asset. It's called by InitXServe and
+// SetXServeOptions with XServeOptions.AssetsManifest. The manifest is emptied when it
+// can't be loaded.
+func Init(manifestPath string, URLPrefix string) error {
+	m := Manifest{}
+	var err error
+	if manifestPath != "" {
+		var loaded Manifest
+		if loaded, err = Load(manifestPath); err == nil {
+			m = loaded
+		}
+	}
+	reversed := make(map[string]string, len(m))
+	for name, fingerprinted := range m {
+		reversed[fingerprinted] = name
+	}
+	mutex.Lock()
+	manifest, originals = m, reversed
+	if URLPrefix != "" {
+		urlPrefix = strings.TrimSuffix(URLPrefix, "/")
+	}
+	mutex.Unlock()
+	return err
+}
+
+// Asset returns the URL of a static file, fingerprinted when it's in the manifest.
+//
+// e.g. Asset("js/app.js") -> /static/js/app.3f9c1a.js
+func Asset(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if fingerprinted, ok := manifest[name]; ok {
+		name = fingerprinted
+	}
+	return urlPrefix + "/" + name
+}
+
+// Original returns the path of the static file whose fingerprinted path is requested,
+// and false when it isn't in the manifest.
+func Original(requested string) (string, bool) {
+	requested = strings.TrimPrefix(path.Clean("/"+requested), "/")
+	mutex.RLock()
+	defer mutex.RUnlock()
+	name, ok := originals[requested]
+	return name, ok
+}
+
+// Fingerprint inserts the hash of content in a file name, before its extension.
+func Fingerprint(name string, content io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(hash.Sum(nil))[:HashLength] + ext, nil
+}
+
+// Build fingerprints the files of the static files directory. The hidden files, the
+// precompressed siblings (app.js.br), the fingerprinted copies written by WriteCopies
+// and the manifests written there by the previous versions are left out.
+func Build(root string) (Manifest, error) {
+	m := Manifest{}
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && name != root {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Name() == ManifestName || isPrecompressed(name) || isFingerprintedCopy(name) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		rel = filepath.ToSlash(rel)
+		if m[rel], err = Fingerprint(rel, f); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WriteCopies copies the files of the manifest, and their precompressed siblings, to
+// their fingerprinted names, for the static files served from a cloud storage.
+func WriteCopies(root string, m Manifest) error {
+	for name, fingerprinted := range m {
+		src := filepath.Join(root, filepath.FromSlash(name))
+		dst := filepath.Join(root, filepath.FromSlash(fingerprinted))
+		if err := copyFile(src, dst); err != nil {
+			return err
+		}
+		for _, precompressed := range helpers.PrecompressedSuffixes {
+			if _, err := os.Stat(src + precompressed.Suffix); err == nil {
+				if err = copyFile(src+precompressed.Suffix, dst+precompressed.Suffix); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Save writes the manifest as JSON.
+func (this Manifest) Save(name string) error {
+	data, err := json.MarshalIndent(this, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, append(data, '\n'), 0644)
+}
+
+// Load reads a manifest written by Save.
+func Load(name string) (Manifest, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	m := Manifest{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func isPrecompressed(name string) bool {
+	for _, precompressed := range helpers.PrecompressedSuffixes {
+		if strings.HasSuffix(name, precompressed.Suffix) {
+			if _, err := os.Stat(strings.TrimSuffix(name, precompressed.Suffix)); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isFingerprintedCopy tells if name is app.<hash>.js and app.js exists.
+func isFingerprintedCopy(name string) bool {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	hash := filepath.Ext(base)
+	if len(hash) != HashLength+1 {
+		return false
+	}
+	if _, err := hex.DecodeString(hash[1:]); err != nil {
+		return false
+	}
+	_, err := os.Stat(strings.TrimSuffix(base, hash) + ext)
+	return err == nil
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
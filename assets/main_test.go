@@ -0,0 +1,104 @@
+package assets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	name, err := Fingerprint("js/app.js", strings.NewReader("app()"))
+	if err != nil || !strings.HasPrefix(name, "js/app.") || !strings.HasSuffix(name, ".js") || len(name) != len("js/app.js")+HashLength+1 {
+		t.Errorf("unexpected fingerprinted name %q, %v", name, err)
+	}
+	if other, _ := Fingerprint("js/app.js", strings.NewReader("app(1)")); other == name {
+		t.Errorf("the name should change with the content")
+	}
+	if again, _ := Fingerprint("js/app.js", strings.NewReader("app()")); again != name {
+		t.Errorf("the name should only depend on the content")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"css/app.css":     "body{}",
+		"js/app.js":       "app()",
+		"js/app.js.br":    "compressed app()",
+		"js/orphan.js.gz": "no original",
+		".env":            "SECRET=1",
+		".git/config":     "[core]",
+		ManifestName:      "{}",
+	})
+	m, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 3 || m["css/app.css"] == "" || m["js/app.js"] == "" || m["js/orphan.js.gz"] == "" {
+		t.Fatalf("unexpected manifest %v", m)
+	}
+	if err = WriteCopies(root, m); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{m["js/app.js"], m["js/app.js"] + ".br", m["css/app.css"]} {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Errorf("expected the copy %s: %v", name, err)
+		}
+	}
+	// the copies aren't fingerprinted again
+	rebuilt, err := Build(root)
+	if err != nil || len(rebuilt) != len(m) || rebuilt["js/app.js"] != m["js/app.js"] {
+		t.Errorf("expected the same manifest, got %v, %v", rebuilt, err)
+	}
+}
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, ManifestName)
+	if err := (Manifest{"js/app.js": "js/app.3f9c1a.js"}).Save(name); err != nil {
+		t.Fatal(err)
+	}
+	defer Init("", "/static")
+
+	if err := Init(name, "/assets/"); err != nil {
+		t.Fatal(err)
+	}
+	if url := Asset("js/app.js"); url != "/assets/js/app.3f9c1a.js" {
+		t.Errorf("expected the fingerprinted URL, got %q", url)
+	}
+	if url := Asset("/css/../css/app.css"); url != "/assets/css/app.css" {
+		t.Errorf("expected the URL of the file, got %q", url)
+	}
+	if original, ok := Original("/js/app.3f9c1a.js"); !ok || original != "js/app.js" {
+		t.Errorf("expected js/app.js, got %q", original)
+	}
+	if _, ok := Original("js/app.js"); ok {
+		t.Errorf("the original names aren't fingerprinted names")
+	}
+
+	if err := Init(filepath.Join(dir, "missing.json"), ""); err == nil {
+		t.Errorf("a missing manifest should be reported")
+	}
+	if url := Asset("js/app.js"); url != "/assets/js/app.js" {
+		t.Errorf("the manifest should be emptied, got %q", url)
+	}
+	if err := Init(name, ""); err != nil || Asset("js/app.js") != "/assets/js/app.3f9c1a.js" {
+		t.Errorf("the URL prefix should be kept")
+	}
+	if err := Init("", ""); err != nil || Asset("js/app.js") != "/assets/js/app.js" {
+		t.Errorf("no manifest should be used without a path")
+	}
+}
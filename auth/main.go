@@ -7,6 +7,7 @@ import (
 	"encoding/pem"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"path/filepath"
@@ -18,6 +19,9 @@ import (
 type JWTAuthentication struct {
 	privateKey *rsa.PrivateKey
 	publicKey  *rsa.PublicKey
+	// claims of the verified tokens, see verify
+	verified      map[string]*Claims
+	verifiedMutex sync.Mutex
 }
 
 var (
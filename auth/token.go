@@ -7,18 +7,14 @@ import (
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/nehmeroumani/pill.go/unified"
 	"github.com/valyala/fasthttp"
 )
 
 func IsAuthenticated(tokenString string, opts ...int64) (bool, int, string) {
 	if tokenString != "" && tokenString != "deleted" {
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-			return GetJWTAuth().publicKey, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, ok := GetJWTAuth().verify(tokenString)
+		if !ok {
 			return false, 0, ""
 		}
 		var lastPasswordUpdate int64
@@ -27,8 +23,7 @@ func IsAuthenticated(tokenString string, opts ...int64) (bool, int, string) {
 		}
 		if claims.IssuedAt > lastPasswordUpdate {
 			if getTokenRemainingValidity(claims.ExpiresAt) > 0 {
-				var id int
-				if id, err = strconv.Atoi(claims.Subject); err == nil {
+				if id, err := strconv.Atoi(claims.Subject); err == nil {
 					return true, id, claims.Role
 				}
 			}
@@ -37,53 +32,107 @@ func IsAuthenticated(tokenString string, opts ...int64) (bool, int, string) {
 	return false, 0, ""
 }
 
-func GetTokenFromRequest(w http.ResponseWriter, req *http.Request) string {
-	// Look for an Authorization header
-	if ah := req.Header.Get("Authorization"); ah != "" {
-		// Should be a bearer token
-		if len(ah) > 6 && strings.ToUpper(ah[0:7]) == "BEARER " {
-			return ah[7:]
-		}
+// maxVerifiedTokens bounds the cache of the verified tokens, which is emptied when full.
+const maxVerifiedTokens = 10000
+
+// verify returns the claims of a valid token signed with the key. Since the
+// middlewares (rate limiting, caching, idempotency) check the token of every request,
+// the verified tokens are cached until they expire instead of checking their RSA
+// signature each time.
+func (this *JWTAuthentication) verify(tokenString string) (*Claims, bool) {
+	this.verifiedMutex.Lock()
+	claims, ok := this.verified[tokenString]
+	this.verifiedMutex.Unlock()
+	if ok && claims.Valid() == nil {
+		return claims, true
 	}
-	tokenCookie, err := req.Cookie("access_token")
-	if err == nil {
-		if tokenCookie.Value != "" {
-			return tokenCookie.Value
-		}
+	claims = &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return this.publicKey, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, false
+	}
+	this.verifiedMutex.Lock()
+	if this.verified == nil || len(this.verified) >= maxVerifiedTokens {
+		this.verified = map[string]*Claims{}
+	}
+	this.verified[tokenString] = claims
+	this.verifiedMutex.Unlock()
+	return claims, true
+}
+
+// GetTokenFromContext looks for the access token in the Authorization header, then in
+// the access_token cookie and finally in the access_token param, which is then kept
+// in the cookie. The param is looked for in the query string and the form on
+// net/http, in the query string only on fasthttp.
+func GetTokenFromContext(c unified.Context) string {
+	tokStr, fromParam := lookupToken(c)
+	if fromParam {
+		c.SetCookie(newAuthCookie("access_token", tokStr))
+	}
+	return tokStr
+}
+
+func lookupToken(c unified.Context) (string, bool) {
+	if tokStr := headerToken(c); tokStr != "" {
+		return tokStr, false
 	}
 	// Look for "access_token" parameter
-	req.ParseMultipartForm(10e6)
-	if tokStr := req.Form.Get("access_token"); tokStr != "" {
-		SetAccessTokenCookie(w, tokStr)
-		return tokStr
+	tokStr := ""
+	if c.RequestCtx() != nil {
+		tokStr = c.Query("access_token")
+	} else {
+		tokStr = c.FormValue("access_token")
 	}
-	return ""
+	return tokStr, tokStr != ""
 }
 
-func GetTokenFromFastHttpRequest(requestCtx *fasthttp.RequestCtx) string {
+// headerToken looks for the access token in the Authorization header, then in the
+// access_token cookie.
+func headerToken(c unified.Context) string {
 	// Look for an Authorization header
-	if ah := helpers.BytesToString(requestCtx.Request.Header.Peek("Authorization")); ah != "" {
+	if ah := c.Header("Authorization"); ah != "" {
 		// Should be a bearer token
 		if len(ah) > 6 && strings.ToUpper(ah[0:7]) == "BEARER " {
 			return ah[7:]
 		}
 	}
-	tokenCookie := requestCtx.Request.Header.Cookie("access_token")
-	if tokenCookie != nil {
-		return string(tokenCookie)
+	return c.Cookie("access_token")
+}
+
+func GetTokenFromRequest(w http.ResponseWriter, req *http.Request) string {
+	return GetTokenFromContext(unified.NewHTTPContext(w, req))
+}
+
+func GetTokenFromFastHttpRequest(requestCtx *fasthttp.RequestCtx) string {
+	return GetTokenFromContext(unified.NewFastHttpContext(requestCtx))
+}
+
+// RequestToken returns the access token of the Authorization header, the cookie or
+// the query string, without storing a token found in the query string in the cookie.
+// It's meant for the middlewares telling the authenticated requests apart, so unlike
+// GetTokenFromRequest it never reads the body of the request.
+func RequestToken(req *http.Request) string {
+	if tokStr := headerToken(unified.NewHTTPContext(nil, req)); tokStr != "" {
+		return tokStr
 	}
-	// Look for "access_token" parameter
-	if tokStr := helpers.BytesToString(requestCtx.QueryArgs().Peek("access_token")); tokStr != "" {
-		SetAccessTokenFastHttpCookie(requestCtx, tokStr)
+	return req.URL.Query().Get("access_token")
+}
+
+func FastHttpRequestToken(requestCtx *fasthttp.RequestCtx) string {
+	if tokStr := headerToken(unified.NewFastHttpContext(requestCtx)); tokStr != "" {
 		return tokStr
 	}
-	return ""
+	return string(requestCtx.QueryArgs().Peek("access_token"))
 }
 
-func SetAccessTokenCookie(w http.ResponseWriter, tokenString string, opts ...bool) http.ResponseWriter {
-	cookie := http.Cookie{}
-	cookie.Name = "access_token"
-	cookie.Value = tokenString
+// newAuthCookie returns a cookie of the auth settings: http only, on the whole domain
+// and secure when the token is.
+func newAuthCookie(name string, value string) *http.Cookie {
+	cookie := &http.Cookie{}
+	cookie.Name = name
+	cookie.Value = value
 	cookie.HttpOnly = true
 	cookie.Path = "/"
 	if domainName != "" {
@@ -92,21 +141,16 @@ func SetAccessTokenCookie(w http.ResponseWriter, tokenString string, opts ...boo
 	if secureToken {
 		cookie.Secure = true
 	}
+	return cookie
+}
+
+func SetAccessTokenCookie(w http.ResponseWriter, tokenString string, opts ...bool) http.ResponseWriter {
+	cookie := newAuthCookie("access_token", tokenString)
 	if opts != nil && len(opts) > 0 {
 		if opts[0] {
 			cookie.Expires = time.Now().Add(time.Hour * tokenDuration)
-			rememberMeCookie := http.Cookie{}
-			rememberMeCookie.Value = "true"
-			rememberMeCookie.Name = "remember_me"
-			rememberMeCookie.HttpOnly = true
-			rememberMeCookie.Path = "/"
+			rememberMeCookie := newAuthCookie("remember_me", "true")
 			rememberMeCookie.Expires = time.Now().Add(time.Hour * tokenDuration)
-			if domainName != "" {
-				rememberMeCookie.Domain = domainName
-			}
-			if secureToken {
-				rememberMeCookie.Secure = true
-			}
 			w.Header().Add("Set-Cookie", rememberMeCookie.String())
 		}
 	}
@@ -0,0 +1,241 @@
+package bind
+
+import (
+	"encoding"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	fileHeaderType      = reflect.TypeOf(&multipart.FileHeader{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// timeLayouts are tried in order when a time field has no layout tag.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02"}
+
+func structValue(dst interface{}) (reflect.Value, error) {
+	value := reflect.ValueOf(dst)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, ErrInvalidTarget
+	}
+	if err := checkTags(value.Elem().Type()); err != nil {
+		return reflect.Value{}, err
+	}
+	return value.Elem(), nil
+}
+
+// bindValues fills the fields tagged with tag from values, e.g. `query:"page"`.
+func bindValues(dst interface{}, tag string, values map[string][]string, errs FieldErrors) error {
+	value, err := structValue(dst)
+	if err != nil {
+		return err
+	}
+	bindStruct(value, tag, values, nil, errs)
+	return nil
+}
+
+// bindForm fills the fields tagged with form from a multipart form, including the
+// *multipart.FileHeader ones.
+func bindForm(dst interface{}, form *multipart.Form, errs FieldErrors) error {
+	value, err := structValue(dst)
+	if err != nil {
+		return err
+	}
+	bindStruct(value, "form", form.Value, form.File, errs)
+	return nil
+}
+
+func bindStruct(value reflect.Value, tag string, values map[string][]string, files map[string][]*multipart.FileHeader, errs FieldErrors) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := value.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			bindStruct(fieldValue, tag, values, files, errs)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := tagName(field, tag)
+		if name == "" {
+			continue
+		}
+		if files != nil && isFileField(field.Type) {
+			if headers := files[name]; len(headers) > 0 {
+				if field.Type.Kind() == reflect.Slice {
+					fieldValue.Set(reflect.ValueOf(headers))
+				} else {
+					fieldValue.Set(reflect.ValueOf(headers[0]))
+				}
+			}
+			continue
+		}
+		fieldValues, ok := values[name]
+		if !ok || len(fieldValues) == 0 {
+			continue
+		}
+		if err := setValue(fieldValue, fieldValues, field.Tag.Get("layout")); err != nil {
+			errs.add(fieldName(field), err.Error())
+		}
+	}
+}
+
+func isFileField(t reflect.Type) bool {
+	return t == fileHeaderType || (t.Kind() == reflect.Slice && t.Elem() == fileHeaderType)
+}
+
+func tagName(field reflect.StructField, tag string) string {
+	name := field.Tag.Get(tag)
+	if i := strings.Index(name, ","); i >= 0 {
+		name = name[:i]
+	}
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// fieldName is the name a field is reported with in FieldErrors, its first name among
+// its json, form, query and param tags or its Go name.
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "query", "param"} {
+		if name := tagName(field, tag); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func setValue(value reflect.Value, values []string, layout string) error {
+	t := value.Type()
+	if t.Kind() == reflect.Ptr {
+		elem := reflect.New(t.Elem())
+		if err := setValue(elem.Elem(), values, layout); err != nil {
+			return err
+		}
+		value.Set(elem)
+		return nil
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 && !reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		if len(values) == 1 && strings.Contains(values[0], ",") && t.Elem().Kind() != reflect.String {
+			values = strings.Split(values[0], ",")
+		}
+		slice := reflect.MakeSlice(t, len(values), len(values))
+		for i, v := range values {
+			if err := setValue(slice.Index(i), []string{strings.TrimSpace(v)}, layout); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	}
+	return setString(value, values[0], layout)
+}
+
+func setString(value reflect.Value, s string, layout string) error {
+	t := value.Type()
+	switch {
+	case t == timeType:
+		tm, err := parseTime(s, layout)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(tm))
+		return nil
+	case t == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errInvalid("duration")
+		}
+		value.SetInt(int64(d))
+		return nil
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		if err := value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return errInvalid("value")
+		}
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Bool:
+		if s == "on" {
+			s = "true"
+		} else if s == "off" {
+			s = "false"
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errInvalid("boolean")
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return errInvalid("integer")
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return errInvalid("unsigned integer")
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return errInvalid("number")
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		// []byte
+		value.SetBytes([]byte(s))
+	default:
+		return errInvalid("value")
+	}
+	return nil
+}
+
+func parseTime(s string, layout string) (time.Time, error) {
+	if layout == "unix" {
+		seconds, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, errInvalid("timestamp")
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	if layout != "" {
+		tm, err := time.Parse(layout, s)
+		if err != nil {
+			return time.Time{}, errInvalid("time")
+		}
+		return tm, nil
+	}
+	for _, l := range timeLayouts {
+		if tm, err := time.Parse(l, s); err == nil {
+			return tm, nil
+		}
+	}
+	return time.Time{}, errInvalid("time")
+}
+
+type conversionError string
+
+func (this conversionError) Error() string {
+	return string(this)
+}
+
+func errInvalid(kind string) error {
+	article := "a"
+	if strings.IndexAny(kind[:1], "aeiou") == 0 {
+		article = "an"
+	}
+	return conversionError("must be " + article + " " + kind)
+}
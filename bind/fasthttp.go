@@ -0,0 +1,71 @@
+package bind
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/valyala/fasthttp"
+)
+
+var fastHttpParamsGetter func(*fasthttp.RequestCtx) map[string]string
+
+// SetFastHttpParamsGetter sets the function returning the route params of a request,
+// e.g. SetFastHttpParamsGetter(fastmux.Params).
+func SetFastHttpParamsGetter(getter func(*fasthttp.RequestCtx) map[string]string) {
+	fastHttpParamsGetter = getter
+}
+
+// FastHttpRequest is the fasthttp version of Request, the size of multipart bodies
+// kept in memory is decided by the server.
+func FastHttpRequest(requestCtx *fasthttp.RequestCtx, dst interface{}, opts ...Options) error {
+	if _, err := structValue(dst); err != nil {
+		return err
+	}
+	options := getOptions(opts)
+	errs := FieldErrors{}
+	bindValues(dst, "query", argsToValues(requestCtx.QueryArgs()), errs)
+	if int64(requestCtx.Request.Header.ContentLength()) > options.MaxBodySize {
+		return ErrBodyTooLarge
+	}
+	if body := requestCtx.PostBody(); len(body) > 0 || requestCtx.IsPost() {
+		if int64(len(body)) > options.MaxBodySize {
+			return ErrBodyTooLarge
+		}
+		if err := bindFastHttpBody(requestCtx, dst, errs); err != nil {
+			return err
+		}
+	}
+	if fastHttpParamsGetter != nil {
+		bindValues(dst, "param", toValues(fastHttpParamsGetter(requestCtx)), errs)
+	}
+	return finish(dst, errs)
+}
+
+func bindFastHttpBody(requestCtx *fasthttp.RequestCtx, dst interface{}, errs FieldErrors) error {
+	switch mediaType(helpers.BytesToString(requestCtx.Request.Header.ContentType())) {
+	case "application/json", "application/problem+json":
+		return decodeJSON(json.NewDecoder(bytes.NewReader(requestCtx.PostBody())), dst, errs)
+	case "application/x-www-form-urlencoded":
+		return bindValues(dst, "form", argsToValues(requestCtx.PostArgs()), errs)
+	case "multipart/form-data":
+		form, err := requestCtx.MultipartForm()
+		if err != nil {
+			return err
+		}
+		return bindForm(dst, form, errs)
+	case "":
+		if len(requestCtx.PostBody()) == 0 {
+			return nil
+		}
+	}
+	return ErrUnsupportedMediaType
+}
+
+func argsToValues(args *fasthttp.Args) map[string][]string {
+	values := map[string][]string{}
+	args.VisitAll(func(key, value []byte) {
+		values[string(key)] = append(values[string(key)], string(value))
+	})
+	return values
+}
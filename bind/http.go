@@ -0,0 +1,85 @@
+package bind
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+var paramsGetter func(*http.Request) map[string]string
+
+// SetParamsGetter sets the function returning the route params of a request, e.g.
+// SetParamsGetter(mux.Params); the `param` tags are ignored until it's set.
+func SetParamsGetter(getter func(*http.Request) map[string]string) {
+	paramsGetter = getter
+}
+
+// Request fills the struct pointed by dst from the query string (`query` tags), the
+// body (`json` tags for JSON, `form` tags for urlencoded and multipart forms) and the
+// route params (`param` tags, see SetParamsGetter), in this order, then sanitizes and validates it. Values
+// which can't be converted and broken rules are returned as FieldErrors.
+func Request(req *http.Request, dst interface{}, opts ...Options) error {
+	if _, err := structValue(dst); err != nil {
+		return err
+	}
+	options := getOptions(opts)
+	errs := FieldErrors{}
+	bindValues(dst, "query", req.URL.Query(), errs)
+	if req.Body != nil && req.Body != http.NoBody && req.ContentLength != 0 {
+		if req.ContentLength > options.MaxBodySize {
+			return ErrBodyTooLarge
+		}
+		req.Body = http.MaxBytesReader(nil, req.Body, options.MaxBodySize)
+		if err := bindBody(req, dst, options, errs); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return ErrBodyTooLarge
+			}
+			return err
+		}
+	}
+	if paramsGetter != nil {
+		bindValues(dst, "param", toValues(paramsGetter(req)), errs)
+	}
+	return finish(dst, errs)
+}
+
+func bindBody(req *http.Request, dst interface{}, options Options, errs FieldErrors) error {
+	switch mediaType(req.Header.Get("Content-Type")) {
+	case "application/json", "application/problem+json":
+		return decodeJSON(json.NewDecoder(req.Body), dst, errs)
+	case "application/x-www-form-urlencoded":
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+		return bindValues(dst, "form", req.PostForm, errs)
+	case "multipart/form-data":
+		if err := req.ParseMultipartForm(options.MaxMemory); err != nil {
+			return err
+		}
+		return bindForm(dst, req.MultipartForm, errs)
+	}
+	return ErrUnsupportedMediaType
+}
+
+// decodeJSON reports the fields of the wrong type as FieldErrors instead of failing.
+func decodeJSON(decoder *json.Decoder, dst interface{}, errs FieldErrors) error {
+	err := decoder.Decode(dst)
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok && typeErr.Field != "" {
+		errs.add(typeErr.Field, "must be of type "+typeErr.Type.String())
+		return nil
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func toValues(params map[string]string) map[string][]string {
+	values := make(map[string][]string, len(params))
+	for key, value := range params {
+		values[key] = []string{value}
+	}
+	return values
+}
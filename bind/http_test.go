@@ -0,0 +1,138 @@
+package bind
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/fastmux"
+	"github.com/nehmeroumani/pill.go/mux"
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRequestBodyTooLarge(t *testing.T) {
+	var dst struct {
+		Name string `json:"name"`
+	}
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name": "`+strings.Repeat("a", 100)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	// an unknown length is only limited while reading
+	req.ContentLength = -1
+	if err := Request(req, &dst, Options{MaxBodySize: 50}); err != ErrBodyTooLarge {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+}
+
+func TestInvalidTags(t *testing.T) {
+	type nested struct {
+		Code string `sanitize:"trim,shout"`
+	}
+	for name, dst := range map[string]interface{}{
+		"unknown rule": &struct {
+			Name string `validate:"required,shiny"`
+		}{Name: "x"},
+		"invalid argument": &struct {
+			Name string `validate:"min=three"`
+		}{},
+		"nested sanitizer": &struct {
+			Items []*nested
+		}{},
+	} {
+		req := httptest.NewRequest("GET", "/?name=x", nil)
+		if err := Request(req, dst); err == nil || !strings.HasPrefix(err.Error(), "bind: ") {
+			t.Errorf("%s: expected an error, got %v", name, err)
+		}
+		if err := Validate(dst); err == nil {
+			t.Errorf("%s: Validate should fail too", name)
+		}
+	}
+}
+
+type createPost struct {
+	Page      int                   `query:"page"`
+	BlogID    int                   `param:"blog"`
+	Title     string                `json:"title" form:"title" validate:"required,min=3" sanitize:"trim"`
+	Tags      []string              `json:"tags" form:"tag" validate:"max=2"`
+	Published bool                  `json:"published" form:"published" validate:"required"`
+	Draft     *bool                 `json:"draft" form:"draft"`
+	Cover     *multipart.FileHeader `form:"cover"`
+}
+
+func bindClients(t *testing.T) map[string]*pilltest.Client {
+	SetParamsGetter(mux.Params)
+	SetFastHttpParamsGetter(fastmux.Params)
+	t.Cleanup(func() {
+		SetParamsGetter(nil)
+		SetFastHttpParamsGetter(nil)
+	})
+	describe := func(dst createPost, err error) string {
+		if err != nil {
+			return err.Error()
+		}
+		cover := ""
+		if dst.Cover != nil {
+			cover = dst.Cover.Filename
+		}
+		return fmt.Sprintf("%d %d %q %v %v %v %s", dst.Page, dst.BlogID, dst.Title, dst.Tags, dst.Published, dst.Draft != nil, cover)
+	}
+	m := mux.New()
+	m.Post("/blogs/:blog/posts").ThenFunc(func(w http.ResponseWriter, req *http.Request) {
+		var dst createPost
+		err := Request(req, &dst)
+		w.Write([]byte(describe(dst, err)))
+	})
+	fm := fastmux.New()
+	fm.Post("/blogs/:blog/posts").ThenFunc(func(requestCtx *fasthttp.RequestCtx) {
+		var dst createPost
+		err := FastHttpRequest(requestCtx, &dst)
+		requestCtx.WriteString(describe(dst, err))
+	})
+	return map[string]*pilltest.Client{"net/http": pilltest.New(t, m), "fasthttp": pilltest.NewFastHttp(t, fm.ServeHTTP)}
+}
+
+func TestRequest(t *testing.T) {
+	for name, client := range bindClients(t) {
+		t.Run(name, func(t *testing.T) {
+			client.Post("/blogs/7/posts").Query("page", "2").JSON(map[string]interface{}{"title": " Hello ", "tags": []string{"a"}, "published": false}).Do().
+				ExpectBodyContains(`2 7 "Hello" [a] false false `)
+			client.Post("/blogs/7/posts").Form(url.Values{"title": {"Hello"}, "tag": {"a", "b"}, "published": {"true"}, "draft": {"false"}}).Do().
+				ExpectBodyContains(`0 7 "Hello" [a b] true true `)
+			client.Post("/blogs/7/posts").Field("title", "Hello").File("cover", "cover.png", []byte("png")).Do().
+				ExpectBodyContains(`0 7 "Hello" [] false false cover.png`)
+			client.Post("/blogs/7/posts").Query("page", "two").JSON(map[string]interface{}{"title": "Hi", "tags": []string{"a", "b", "c"}}).Do().
+				ExpectBodyContains("bind: page must be an integer, tags must be at most 2 items, title must be at least 3 characters")
+			client.Post("/blogs/7/posts").JSON(map[string]interface{}{"title": 5}).Do().
+				ExpectBodyContains("bind: title must be of type string")
+			client.Post("/blogs/7/posts").Body("text/plain", []byte("hello")).Do().
+				ExpectBodyContains(ErrUnsupportedMediaType.Error())
+		})
+	}
+}
+
+func TestRequestWithoutParamsGetter(t *testing.T) {
+	var dst createPost
+	req := httptest.NewRequest("POST", "/blogs/7/posts", strings.NewReader(`{"title": "Hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if err := Request(req, &dst); err != nil || dst.BlogID != 0 || dst.Title != "Hello" {
+		t.Errorf("the params should be skipped without a getter, got %+v and %v", dst, err)
+	}
+}
+
+func TestValidateRequiredBool(t *testing.T) {
+	if err := Validate(&struct {
+		Accepted bool `validate:"required"`
+	}{}); err != nil {
+		t.Errorf("false should satisfy required, got %v", err)
+	}
+	err := Validate(&struct {
+		Accepted *bool `validate:"required"`
+	}{})
+	if errs, ok := err.(FieldErrors); !ok || errs["Accepted"] != "is required" {
+		t.Errorf("a nil *bool shouldn't satisfy required, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package bind
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidTarget        = errors.New("bind: the target must be a non-nil pointer to a struct")
+	ErrBodyTooLarge         = errors.New("bind: request body too large")
+	ErrUnsupportedMediaType = errors.New("bind: unsupported content type")
+)
+
+type Options struct {
+	// MaxBodySize is the largest body accepted, it defaults to 10MB.
+	MaxBodySize int64
+	// MaxMemory is the part of a multipart body kept in memory, the rest of the
+	// files is stored on disk; it defaults to 10MB.
+	MaxMemory int64
+}
+
+var defaultOptions = Options{MaxBodySize: 10 << 20, MaxMemory: 10 << 20}
+
+func getOptions(opts []Options) Options {
+	options := defaultOptions
+	if len(opts) > 0 {
+		if opts[0].MaxBodySize > 0 {
+			options.MaxBodySize = opts[0].MaxBodySize
+		}
+		if opts[0].MaxMemory > 0 {
+			options.MaxMemory = opts[0].MaxMemory
+		}
+	}
+	return options
+}
+
+// FieldErrors maps the names of the fields that couldn't be converted or validated
+// to their error messages.
+type FieldErrors map[string]string
+
+func (this FieldErrors) Error() string {
+	fields := make([]string, 0, len(this))
+	for field := range this {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field + " " + this[field]
+	}
+	return "bind: " + strings.Join(messages, ", ")
+}
+
+func (this FieldErrors) add(field string, message string) {
+	if _, ok := this[field]; !ok {
+		this[field] = message
+	}
+}
+
+// mediaType returns the media type of a Content-Type header, lowercased and without
+// its parameters.
+func mediaType(contentType string) string {
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// finish sanitizes and validates dst once it's filled, joining the conversion errors
+// to the validation ones.
+func finish(dst interface{}, errs FieldErrors) error {
+	Sanitize(dst)
+	if err := Validate(dst); err != nil {
+		if validationErrs, ok := err.(FieldErrors); ok {
+			for field, message := range validationErrs {
+				errs.add(field, message)
+			}
+		} else {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
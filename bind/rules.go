@@ -0,0 +1,337 @@
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/nehmeroumani/pill.go/sanitize"
+	"github.com/nehmeroumani/pill.go/validate"
+)
+
+// Validate checks the fields of the struct pointed by dst against the rules of their
+// validate tags, e.g. `validate:"required,email"`, and returns the failures as
+// FieldErrors. Empty fields which aren't required are skipped; since a bool can't
+// tell false from missing, required only rejects the nil *bool.
+//
+// Rules: required, email, url, phone, uuid, min=N, max=N, len=N (string length,
+// number of elements or numeric value) and oneof=a b c.
+func Validate(dst interface{}) error {
+	value, err := structValue(dst)
+	if err != nil {
+		return err
+	}
+	errs := FieldErrors{}
+	validateStruct(value, "", errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(value reflect.Value, prefix string, errs FieldErrors) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := value.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			validateStruct(fieldValue, prefix, errs)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := prefix + fieldName(field)
+		if rules := field.Tag.Get("validate"); rules != "" && rules != "-" {
+			if message := checkRules(fieldValue, rules); message != "" {
+				errs.add(name, message)
+				continue
+			}
+		}
+		// nested structs are validated too
+		elem := fieldValue
+		for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+			elem = elem.Elem()
+		}
+		if elem.Kind() == reflect.Struct && elem.Type() != timeType {
+			validateStruct(elem, name+".", errs)
+		} else if elem.Kind() == reflect.Slice && isStruct(elem.Type().Elem()) {
+			for j := 0; j < elem.Len(); j++ {
+				item := elem.Index(j)
+				for item.Kind() == reflect.Ptr && !item.IsNil() {
+					item = item.Elem()
+				}
+				if item.Kind() == reflect.Struct {
+					validateStruct(item, name+"["+strconv.Itoa(j)+"].", errs)
+				}
+			}
+		}
+	}
+}
+
+func isStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// checkRules returns the message of the first rule value breaks, or "".
+func checkRules(value reflect.Value, rules string) string {
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		if strings.TrimSpace(rule) == "required" {
+			required = true
+		}
+	}
+	if isEmpty(value) && (value.Kind() != reflect.Bool || !required) {
+		if required {
+			return "is required"
+		}
+		return ""
+	}
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		name, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+		if message := checkRule(value, name, arg); message != "" {
+			return message
+		}
+	}
+	return ""
+}
+
+func checkRule(value reflect.Value, name string, arg string) string {
+	switch name {
+	case "", "required":
+	case "email":
+		if !validate.Email(value.String()) {
+			return "must be a valid email address"
+		}
+	case "url":
+		if !validate.URL(value.String()) {
+			return "must be a valid URL"
+		}
+	case "phone":
+		if !validate.PhoneNumber(value.String()) {
+			return "must be a valid phone number"
+		}
+	case "uuid":
+		if _, err := helpers.ParseUUIDParam("", value.String()); err != nil {
+			return "must be a valid UUID"
+		}
+	case "min", "max", "len":
+		// the argument is checked by checkTags
+		limit, _ := strconv.ParseFloat(arg, 64)
+		size, unit := measure(value)
+		switch {
+		case name == "min" && size < limit:
+			return "must be at least " + arg + unit
+		case name == "max" && size > limit:
+			return "must be at most " + arg + unit
+		case name == "len" && size != limit:
+			return "must be exactly " + arg + unit
+		}
+	case "oneof":
+		s := fmt.Sprint(value.Interface())
+		for _, option := range strings.Fields(arg) {
+			if s == option {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(strings.Fields(arg), ", ")
+	}
+	return ""
+}
+
+// checkRuleTag returns an error if a rule of a validate tag is unknown or has an
+// invalid argument.
+func checkRuleTag(name string, arg string) error {
+	switch name {
+	case "", "required", "email", "url", "phone", "uuid", "oneof":
+	case "min", "max", "len":
+		if _, err := strconv.ParseFloat(arg, 64); err != nil {
+			return errors.New("invalid argument for the rule '" + name + "': " + arg)
+		}
+	default:
+		return errors.New("unknown validation rule '" + name + "'")
+	}
+	return nil
+}
+
+// measure returns what min, max and len compare: the length of strings and
+// collections or the value of numbers.
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+	return 0, ""
+}
+
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// Sanitize rewrites the string fields of the struct pointed by dst with the sanitizers
+// of their sanitize tags, e.g. `sanitize:"trim,lower"`.
+//
+// Sanitizers: trim, lower, upper, striptags, html (the tags allowed by
+// sanitize.HTMLAllowing), name, username, phone and accents.
+func Sanitize(dst interface{}) {
+	if value, err := structValue(dst); err == nil {
+		sanitizeStruct(value)
+	}
+}
+
+func sanitizeStruct(value reflect.Value) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := value.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		for fieldValue.Kind() == reflect.Ptr && !fieldValue.IsNil() {
+			fieldValue = fieldValue.Elem()
+		}
+		sanitizers := field.Tag.Get("sanitize")
+		switch {
+		case fieldValue.Kind() == reflect.String && sanitizers != "":
+			fieldValue.SetString(sanitizeString(fieldValue.String(), sanitizers))
+		case fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() == reflect.String && sanitizers != "":
+			for j := 0; j < fieldValue.Len(); j++ {
+				fieldValue.Index(j).SetString(sanitizeString(fieldValue.Index(j).String(), sanitizers))
+			}
+		case fieldValue.Kind() == reflect.Struct && fieldValue.Type() != timeType:
+			sanitizeStruct(fieldValue)
+		case fieldValue.Kind() == reflect.Slice && isStruct(fieldValue.Type().Elem()):
+			for j := 0; j < fieldValue.Len(); j++ {
+				item := fieldValue.Index(j)
+				for item.Kind() == reflect.Ptr && !item.IsNil() {
+					item = item.Elem()
+				}
+				if item.Kind() == reflect.Struct {
+					sanitizeStruct(item)
+				}
+			}
+		}
+	}
+}
+
+func sanitizeString(s string, sanitizers string) string {
+	for _, sanitizer := range strings.Split(sanitizers, ",") {
+		if s == "" {
+			return s
+		}
+		switch strings.TrimSpace(sanitizer) {
+		case "trim":
+			s = strings.TrimSpace(s)
+		case "lower":
+			s = strings.ToLower(s)
+		case "upper":
+			s = strings.ToUpper(s)
+		case "striptags":
+			s = sanitize.StripTags(s)
+		case "html":
+			if html, err := sanitize.HTMLAllowing(s); err == nil {
+				s = html
+			} else {
+				s = sanitize.StripTags(s)
+			}
+		case "name":
+			s = sanitize.Name(s)
+		case "username":
+			s = sanitize.Username(s)
+		case "phone":
+			s = sanitize.PhoneNumber(s)
+		case "accents":
+			s = sanitize.Accents(s)
+		}
+	}
+	return s
+}
+
+var sanitizers = map[string]bool{"": true, "trim": true, "lower": true, "upper": true, "striptags": true, "html": true, "name": true, "username": true, "phone": true, "accents": true}
+
+// checkedTypes caches the result of checkTags by struct type.
+var checkedTypes sync.Map
+
+// checkTags checks the validate and sanitize tags of a struct type and of the structs
+// it contains, once per type, so a typo fails every binding with an error instead of
+// the requests reaching the broken rule.
+func checkTags(t reflect.Type) error {
+	if result, ok := checkedTypes.Load(t); ok {
+		err, _ := result.(error)
+		return err
+	}
+	err := checkStructTags(t, map[reflect.Type]bool{})
+	checkedTypes.Store(t, err)
+	return err
+}
+
+func checkStructTags(t reflect.Type, seen map[reflect.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		if rules := field.Tag.Get("validate"); rules != "" && rules != "-" {
+			for _, rule := range strings.Split(rules, ",") {
+				rule = strings.TrimSpace(rule)
+				name, arg := rule, ""
+				if i := strings.Index(rule, "="); i >= 0 {
+					name, arg = rule[:i], rule[i+1:]
+				}
+				if err := checkRuleTag(name, arg); err != nil {
+					return errors.New("bind: " + t.String() + "." + field.Name + ": " + err.Error())
+				}
+			}
+		}
+		for _, sanitizer := range strings.Split(field.Tag.Get("sanitize"), ",") {
+			if !sanitizers[strings.TrimSpace(sanitizer)] {
+				return errors.New("bind: " + t.String() + "." + field.Name + ": unknown sanitizer '" + sanitizer + "'")
+			}
+		}
+		elem := field.Type
+		for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Slice {
+			elem = elem.Elem()
+		}
+		if elem.Kind() == reflect.Struct && elem != timeType {
+			if err := checkStructTags(elem, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
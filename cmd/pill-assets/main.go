@@ -0,0 +1,71 @@
+// pill-assets fingerprints the static files of an app and writes their manifest, used
+// by the Asset template function and the static files handlers once its path is set
+// in XServeOptions.AssetsManifest, e.g.
+//
+//	pill-assets -dir public/static -out public/assets-manifest.json
+//
+// The manifest is written next to the static files directory by default, it's refused
+// inside of it where it would be served. It must be run on every build, after the
+// static files are generated; with -copy, the files are also copied to their
+// fingerprinted names, to be uploaded to a cloud storage.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nehmeroumani/pill.go/assets"
+)
+
+func main() {
+	dir := flag.String("dir", "", "static files directory")
+	out := flag.String("out", "", "manifest file, "+assets.ManifestName+" next to the static files directory by default")
+	copies := flag.Bool("copy", false, "copy the files to their fingerprinted names")
+	flag.Parse()
+	if *dir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	root, err := filepath.Abs(*dir)
+	if err != nil {
+		fail(err)
+	}
+	if *out == "" {
+		*out = filepath.Join(filepath.Dir(root), assets.ManifestName)
+	}
+	if inside(root, *out) {
+		fail(errors.New("the manifest would be served with the static files, write it outside of " + *dir))
+	}
+	manifest, err := assets.Build(root)
+	if err != nil {
+		fail(err)
+	}
+	if *copies {
+		if err = assets.WriteCopies(root, manifest); err != nil {
+			fail(err)
+		}
+	}
+	if err = manifest.Save(*out); err != nil {
+		fail(err)
+	}
+	fmt.Printf("pill-assets: %d files fingerprinted in %s\n", len(manifest), *out)
+}
+
+// inside tells if name is in the directory root.
+func inside(root string, name string) bool {
+	name, err := filepath.Abs(name)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, name)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "pill-assets:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,83 @@
+// pill-routes prints the route table of an app registering its routes in an exported
+// function taking a *mux.Mux (or a *fastmux.Mux with -fast), e.g.
+//
+//	pill-routes -pkg github.com/me/app/routes -func Register
+//
+// It must be run from the app module, since it builds a small program importing the
+// package.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/template"
+)
+
+var program = template.Must(template.New("main").Parse(`package main
+
+import (
+{{if .JSON}}	"encoding/json"
+{{end}}	"os"
+
+	app "{{.Pkg}}"
+	"github.com/nehmeroumani/pill.go/{{.Mux}}"
+)
+
+func main() {
+	m := {{.Mux}}.New()
+	app.{{.Func}}(m)
+	{{if .JSON}}enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m.Routes()); err != nil {
+		panic(err)
+	}{{else}}if err := m.PrintRoutes(os.Stdout); err != nil {
+		panic(err)
+	}{{end}}
+}
+`))
+
+func main() {
+	pkg := flag.String("pkg", "", "import path of the package registering the routes")
+	fn := flag.String("func", "Routes", "exported function of the package registering the routes")
+	fast := flag.Bool("fast", false, "the function takes a *fastmux.Mux")
+	asJSON := flag.Bool("json", false, "print the routes as JSON")
+	flag.Parse()
+	if *pkg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	muxPkg := "mux"
+	if *fast {
+		muxPkg = "fastmux"
+	}
+	dir, err := ioutil.TempDir(".", ".pill-routes-")
+	if err != nil {
+		fail(err)
+	}
+	defer os.RemoveAll(dir)
+	f, err := os.Create(filepath.Join(dir, "main.go"))
+	if err != nil {
+		fail(err)
+	}
+	err = program.Execute(f, map[string]interface{}{"Pkg": *pkg, "Func": *fn, "Mux": muxPkg, "JSON": *asJSON})
+	f.Close()
+	if err != nil {
+		fail(err)
+	}
+	cmd := exec.Command("go", "run", "./"+filepath.ToSlash(dir))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		os.RemoveAll(dir)
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "pill-routes:", err)
+	os.Exit(1)
+}
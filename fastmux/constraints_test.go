@@ -0,0 +1,32 @@
+package fastmux
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestConstraints(t *testing.T) {
+	m := New()
+	m.Get("/posts/:id").Where("id", Int).ThenFunc(pattern)
+	m.Get("/posts/:slug/:order").Where("slug", Slug).Where("order", Enum("asc", "desc")).ThenFunc(pattern)
+	m.Get("/codes/:code").Where("code", Regex("[A-Z]{3}")).ThenFunc(pattern)
+	client := pilltest.NewFastHttp(t, m.ServeHTTP)
+	for path, status := range map[string]int{
+		"/posts/42":        http.StatusOK,
+		"/posts/4x":        http.StatusNotFound,
+		"/posts/hello/asc": http.StatusOK,
+		"/posts/hello/up":  http.StatusNotFound,
+		"/codes/ABC":       http.StatusOK,
+		"/codes/ABCD":      http.StatusNotFound,
+	} {
+		client.Get(path).Do().ExpectStatus(status)
+	}
+
+	m.ConstraintFailedHandler(func(requestCtx *fasthttp.RequestCtx) {
+		requestCtx.Error("invalid "+RoutePattern(requestCtx), fasthttp.StatusBadRequest)
+	})
+	client.Get("/posts/4x").Do().ExpectStatus(http.StatusBadRequest).ExpectBodyContains("invalid /posts/:id")
+}
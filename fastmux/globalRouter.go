@@ -0,0 +1,112 @@
+package fastmux
+
+import (
+	"strings"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/valyala/fasthttp"
+)
+
+// GlobalRouter dispatches requests to the first of its routes matching their host
+// and path, or to its fallback.
+type GlobalRouter struct {
+	routes   []globalRoute
+	fallback *Mux
+}
+
+type globalRoute struct {
+	host   *helpers.HostPattern
+	prefix string
+	// stringPrefix matches prefix as a plain string, see NewGlobalRouter
+	stringPrefix bool
+	router       *Mux
+}
+
+func NewRoutingTable(fallback *Mux) *GlobalRouter {
+	return &GlobalRouter{fallback: fallback}
+}
+
+// NewGlobalRouter sends the requests for APIDomainName, or whose path starts with
+// APIPath when no domain is given, to APIRouter and the others to WebRouter. APIPath is
+// a plain string prefix, "/api" matches "/apiv2" too; use Prefix to match whole path
+// segments.
+func NewGlobalRouter(WebRouter *Mux, APIRouter *Mux, APIDomainName string, APIPath string) *GlobalRouter {
+	this := NewRoutingTable(WebRouter)
+	if APIDomainName != "" {
+		this.Host(APIDomainName, APIRouter)
+	} else {
+		this.routes = append(this.routes, globalRoute{prefix: strings.ToLower(strings.TrimSpace(APIPath)), stringPrefix: true, router: APIRouter})
+	}
+	return this
+}
+
+// Host routes the requests for a host pattern, e.g. "admin.example.com" or
+// "*.example.com" whose subdomain is then returned by Subdomain.
+func (this *GlobalRouter) Host(pattern string, router *Mux) *GlobalRouter {
+	return this.Route(pattern, "", router)
+}
+
+// Prefix routes the requests whose path is under prefix, e.g. "/api".
+//
+// Prefixes match whole path segments: "/api" matches "/api" and "/api/users" but not
+// "/apiv2".
+func (this *GlobalRouter) Prefix(prefix string, router *Mux) *GlobalRouter {
+	return this.Route("", prefix, router)
+}
+
+// Route routes the requests matching both a host pattern and a path prefix, an empty
+// one matches everything. Routes are tried in the order they're added.
+func (this *GlobalRouter) Route(hostPattern string, prefix string, router *Mux) *GlobalRouter {
+	route := globalRoute{prefix: prefix, router: router}
+	if hostPattern != "" {
+		host := helpers.ParseHostPattern(hostPattern)
+		route.host = &host
+	}
+	this.routes = append(this.routes, route)
+	return this
+}
+
+func (this *GlobalRouter) Fallback(router *Mux) *GlobalRouter {
+	this.fallback = router
+	return this
+}
+
+// ServeHTTP has a value receiver so that a GlobalRouter value is a handler too.
+func (this GlobalRouter) ServeHTTP(requestCtx *fasthttp.RequestCtx) {
+	for _, route := range this.routes {
+		subdomain := ""
+		if route.host != nil {
+			var ok bool
+			if subdomain, ok = route.host.Match(helpers.BytesToString(requestCtx.Host())); !ok {
+				continue
+			}
+		}
+		if !route.matchPath(helpers.BytesToString(requestCtx.Path())) {
+			continue
+		}
+		if subdomain != "" {
+			requestCtx.SetUserValue("subdomain", subdomain)
+		}
+		route.router.ServeHTTP(requestCtx)
+		return
+	}
+	if this.fallback != nil {
+		this.fallback.ServeHTTP(requestCtx)
+		return
+	}
+	requestCtx.NotFound()
+}
+
+func (this globalRoute) matchPath(path string) bool {
+	if this.stringPrefix {
+		return strings.HasPrefix(strings.ToLower(path), this.prefix)
+	}
+	return helpers.MatchPathPrefix(path, this.prefix)
+}
+
+// Subdomain returns the subdomain captured by the wildcard host pattern the request
+// was routed with.
+func Subdomain(requestCtx *fasthttp.RequestCtx) string {
+	subdomain, _ := requestCtx.UserValue("subdomain").(string)
+	return subdomain
+}
@@ -0,0 +1,53 @@
+package fastmux
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+// namedMux answers every path with its name and the subdomain of the request.
+func namedMux(name string) *Mux {
+	m := New()
+	m.Get("/*path").ThenFunc(func(requestCtx *fasthttp.RequestCtx) {
+		requestCtx.WriteString(name + " " + Subdomain(requestCtx))
+	})
+	return m
+}
+
+func TestNewGlobalRouter(t *testing.T) {
+	web, api := namedMux("web"), namedMux("api")
+	// a value is a handler too
+	client := pilltest.NewFastHttp(t, (*NewGlobalRouter(web, api, "", "/API")).ServeHTTP)
+	for path, expected := range map[string]string{"/api/users": "api", "/Api": "api", "/apiv2": "api", "/web": "web", "/users/api": "web"} {
+		client.Get(path).Do().ExpectStatus(http.StatusOK).ExpectBodyContains(expected + " ")
+	}
+	client = pilltest.NewFastHttp(t, NewGlobalRouter(web, api, "API.example.com", "/api").ServeHTTP)
+	client.Get("/users").Host("api.example.com").Do().ExpectBodyContains("api ")
+	client.Get("/api/users").Host("example.com").Do().ExpectBodyContains("web ")
+}
+
+func TestRoutingTable(t *testing.T) {
+	router := NewRoutingTable(nil).
+		Route("admin.example.com", "/api", namedMux("admin-api")).
+		Host("admin.example.com", namedMux("admin")).
+		Host("*.example.com", namedMux("tenant")).
+		Prefix("/api/", namedMux("api"))
+	client := pilltest.NewFastHttp(t, router.ServeHTTP)
+	for _, test := range []struct{ host, path, expected string }{
+		{"admin.example.com", "/api/users", "admin-api "},
+		{"admin.example.com", "/apiv2", "admin "},
+		{"acme.example.com", "/api", "tenant acme"},
+		{"example.com", "/api", "api "},
+		{"example.com", "/api/users", "api "},
+	} {
+		client.Get(test.path).Host(test.host).Do().ExpectStatus(http.StatusOK).ExpectBodyContains(test.expected)
+	}
+	client.Get("/apiv2").Host("example.com").Do().ExpectStatus(http.StatusNotFound)
+	router.Fallback(namedMux("web"))
+	// the method value holds a copy of the router
+	client = pilltest.NewFastHttp(t, router.ServeHTTP)
+	client.Get("/apiv2").Host("example.com").Do().ExpectStatus(http.StatusOK).ExpectBodyContains("web ")
+}
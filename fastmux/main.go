@@ -1,10 +1,11 @@
 package fastmux
 
 import (
+	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/nehmeroumani/fastchain"
-	"github.com/nehmeroumani/fasthttpcontext"
 	"github.com/nehmeroumani/fasthttptreemux"
 	"github.com/nehmeroumani/pill.go/helpers"
 	"github.com/valyala/fasthttp"
@@ -17,20 +18,46 @@ func New(opts ...string) *Mux {
 			basePath = opts[0]
 		}
 	}
-	return &Mux{Router: fasthttptreemux.New(), basePath: basePath}
+	m := &Mux{Router: fasthttptreemux.New(), basePath: basePath, shared: &muxShared{}}
+	m.Router.MethodNotAllowedHandler = m.methodNotAllowed
+	return m
 }
 
-func wrapHandler(h fasthttp.RequestHandler) fasthttptreemux.HandlerFunc {
+func wrapHandler(pattern string, h fasthttp.RequestHandler) fasthttptreemux.HandlerFunc {
 	return func(requestCtx *fasthttp.RequestCtx, params map[string]string) {
-		fasthttpcontext.Set(requestCtx, "params", params)
-		fasthttpcontext.ClearHandler(h)(requestCtx)
+		requestCtx.SetUserValue("params", params)
+		requestCtx.SetUserValue("pattern", pattern)
+		if requestCtx.IsHead() {
+			requestCtx.Response.SkipBody = true
+		}
+		h(requestCtx)
 	}
 }
 
 type Mux struct {
-	Router   *fasthttptreemux.TreeMux
-	Chain    fastchain.Chain
-	basePath string
+	Router      *fasthttptreemux.TreeMux
+	Chain       fastchain.Chain
+	basePath    string
+	shared      *muxShared
+	middlewares []string
+}
+
+// muxShared holds the state shared by a mux and all of its groups.
+type muxShared struct {
+	constraintFailedHandler fasthttp.RequestHandler
+	methodNotAllowedHandler fasthttp.RequestHandler
+	optionsHandler          fasthttp.RequestHandler
+	routes                  []RouteInfo
+	// namedRoutes maps the route names to their patterns, they're unique per mux
+	namedRoutes map[string]string
+	routesMutex sync.RWMutex
+}
+
+func (this *Mux) getShared() *muxShared {
+	if this.shared == nil {
+		this.shared = &muxShared{}
+	}
+	return this.shared
 }
 
 func (this *Mux) SetBasePath(bp string) {
@@ -40,13 +67,24 @@ func (this *Mux) SetBasePath(bp string) {
 	}
 }
 
-func (this *Mux) Path(bp string, h func(r *Mux)) {
-	r := &Mux{Router: this.Router}
-	bp = strings.TrimSpace(bp)
-	if bp != "/" {
-		r.basePath = bp
+// Group returns a sub-mux sharing the same router, with bp appended to the current
+// base path and a copy of the current middleware chain, so middlewares added to the
+// group don't affect its parent.
+func (this *Mux) Group(bp string) *Mux {
+	r := &Mux{Router: this.Router, basePath: this.basePath, shared: this.getShared(), middlewares: copyStrings(this.middlewares)}
+	bp = strings.TrimSuffix(strings.TrimSpace(bp), "/")
+	if bp != "" {
+		if !strings.HasPrefix(bp, "/") {
+			bp = "/" + bp
+		}
+		r.basePath += bp
 	}
 	r.Chain = this.Chain.Clone()
+	return r
+}
+
+func (this *Mux) Path(bp string, h func(r *Mux)) {
+	r := this.Group(bp)
 	if h != nil {
 		h(r)
 	}
@@ -54,27 +92,45 @@ func (this *Mux) Path(bp string, h func(r *Mux)) {
 
 func (this *Mux) Use(middlewares ...fastchain.Constructor) {
 	this.Chain = this.Chain.Append(middlewares...)
+	for _, middleware := range middlewares {
+		this.middlewares = append(this.middlewares, helpers.FuncName(middleware))
+	}
+}
+
+// Routes returns the routes registered in the mux and all of its groups, in
+// registration order.
+func (this *Mux) Routes() []RouteInfo {
+	shared := this.getShared()
+	shared.routesMutex.RLock()
+	defer shared.routesMutex.RUnlock()
+	routes := make([]RouteInfo, len(shared.routes))
+	copy(routes, shared.routes)
+	return routes
+}
+
+func (this *Mux) newRoute(method string, p string) *route {
+	return &route{mux: this, pattern: this.basePath + p, method: method, chain: this.Chain, middlewares: copyStrings(this.middlewares)}
 }
 func (this *Mux) Get(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "GET", chain: this.Chain}
+	return this.newRoute("GET", p)
 }
 func (this *Mux) Post(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "POST", chain: this.Chain}
+	return this.newRoute("POST", p)
 }
 func (this *Mux) Put(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "PUT", chain: this.Chain}
+	return this.newRoute("PUT", p)
 }
 func (this *Mux) Patch(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "PATCH", chain: this.Chain}
+	return this.newRoute("PATCH", p)
 }
 func (this *Mux) Delete(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "DELETE", chain: this.Chain}
+	return this.newRoute("DELETE", p)
 }
 func (this *Mux) Head(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "HEAD", chain: this.Chain}
+	return this.newRoute("HEAD", p)
 }
 func (this *Mux) Options(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "OPTIONS", chain: this.Chain}
+	return this.newRoute("OPTIONS", p)
 }
 func (this *Mux) ServeHTTP(requestCtx *fasthttp.RequestCtx) {
 	this.Router.ServeHTTP(requestCtx)
@@ -83,29 +139,224 @@ func (this *Mux) NotFoundHandler(h func(requestCtx *fasthttp.RequestCtx)) {
 	this.Router.NotFoundHandler = h
 }
 
+// ConstraintFailedHandler sets the handler called when a route matches but one of its
+// params doesn't satisfy the route constraints. It defaults to the not found handler.
+func (this *Mux) ConstraintFailedHandler(h func(requestCtx *fasthttp.RequestCtx)) {
+	this.getShared().constraintFailedHandler = h
+}
+
+// MethodNotAllowedHandler sets the handler called when a path matches but has no route
+// for the request method. The Allow header is already set when it's called, and
+// requestCtx.Error would reset it.
+func (this *Mux) MethodNotAllowedHandler(h func(requestCtx *fasthttp.RequestCtx)) {
+	this.getShared().methodNotAllowedHandler = h
+}
+
+// OptionsHandler sets the handler answering the OPTIONS requests of the paths without
+// an OPTIONS route. The Allow header is already set when it's called.
+func (this *Mux) OptionsHandler(h func(requestCtx *fasthttp.RequestCtx)) {
+	this.getShared().optionsHandler = h
+}
+
+func (this *Mux) methodNotAllowed(requestCtx *fasthttp.RequestCtx, methods map[string]fasthttptreemux.HandlerFunc) {
+	list := make([]string, 0, len(methods))
+	for method := range methods {
+		list = append(list, method)
+	}
+	requestCtx.Response.Header.Set("Allow", helpers.AllowHeader(list))
+	shared := this.getShared()
+	if requestCtx.IsOptions() {
+		// a CORS preflight is answered by the CORS middleware of the route it's about
+		if method := helpers.BytesToString(requestCtx.Request.Header.Peek("Access-Control-Request-Method")); method != "" && len(requestCtx.Request.Header.Peek("Origin")) > 0 {
+			h, ok := methods[method]
+			if !ok && method == "HEAD" {
+				h, ok = methods["GET"]
+			}
+			if ok {
+				requestCtx.SetUserValue("preflight", true)
+				h(requestCtx, nil)
+				return
+			}
+		}
+		this.options(requestCtx)
+	} else if shared.methodNotAllowedHandler != nil {
+		shared.methodNotAllowedHandler(requestCtx)
+	} else {
+		// requestCtx.Error would reset the Allow header
+		requestCtx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		requestCtx.SetContentType("text/plain; charset=utf-8")
+		requestCtx.SetBodyString(fasthttp.StatusMessage(fasthttp.StatusMethodNotAllowed))
+	}
+}
+
+func (this *Mux) options(requestCtx *fasthttp.RequestCtx) {
+	if h := this.getShared().optionsHandler; h != nil {
+		h(requestCtx)
+	} else {
+		requestCtx.SetStatusCode(fasthttp.StatusNoContent)
+	}
+}
+
+func isPreflight(requestCtx *fasthttp.RequestCtx) bool {
+	preflight, _ := requestCtx.UserValue("preflight").(bool)
+	return preflight
+}
+
+func (this *Mux) constraintFailed(requestCtx *fasthttp.RequestCtx) {
+	if h := this.getShared().constraintFailedHandler; h != nil {
+		h(requestCtx)
+	} else if this.Router.NotFoundHandler != nil {
+		this.Router.NotFoundHandler(requestCtx)
+	} else {
+		requestCtx.NotFound()
+	}
+}
+
 type route struct {
-	mux     *Mux
-	chain   fastchain.Chain
-	pattern string
-	method  string
+	mux         *Mux
+	chain       fastchain.Chain
+	pattern     string
+	method      string
+	name        string
+	constraints map[string]Constraint
+	middlewares []string
+	hidden      bool
+	doc         RouteInfo
+}
+
+// Name registers the route under name, to be used with URL to build its path.
+func (this *route) Name(name string) *route {
+	this.name = name
+	return this
+}
+
+// Where constrains the values accepted by the param key of the route, e.g.
+// Get("/posts/:id").Where("id", Int). It panics if the pattern has no such param.
+func (this *route) Where(key string, constraint Constraint) *route {
+	found := false
+	for _, name := range helpers.PatternParams(this.pattern) {
+		if name == key {
+			found = true
+			break
+		}
+	}
+	if !found {
+		panic("route " + this.pattern + " has no param '" + key + "'")
+	}
+	if this.constraints == nil {
+		this.constraints = map[string]Constraint{}
+	}
+	this.constraints[key] = constraint
+	return this
+}
+
+func (this *route) Constraints() map[string]Constraint {
+	return this.constraints
+}
+
+func (this *route) Summary(summary string) *route {
+	this.doc.Summary = summary
+	return this
+}
+
+func (this *route) Description(description string) *route {
+	this.doc.Description = description
+	return this
+}
+
+func (this *route) Tags(tags ...string) *route {
+	this.doc.Tags = append(this.doc.Tags, tags...)
+	return this
+}
+
+// Request documents the body (or the query string for GET routes) expected by the
+// route with a value of its Go type, e.g. Request(CreateUserRequest{}).
+func (this *route) Request(v interface{}) *route {
+	this.doc.Request = reflect.TypeOf(v)
+	return this
+}
+
+// Response documents a response of the route with a value of its Go type, or nil
+// for a response without body.
+func (this *route) Response(statusCode int, v interface{}) *route {
+	if this.doc.Responses == nil {
+		this.doc.Responses = map[int]reflect.Type{}
+	}
+	this.doc.Responses[statusCode] = reflect.TypeOf(v)
+	return this
+}
+
+func (this *route) register(h interface{}) {
+	if this.name != "" {
+		this.mux.getShared().addNamedRoute(this.name, this.pattern)
+	}
+	if this.hidden {
+		return
+	}
+	info := this.doc
+	info.Method = this.method
+	info.Pattern = this.pattern
+	info.Name = this.name
+	info.Middlewares = this.middlewares
+	info.Handler = helpers.FuncName(h)
+	info.Constraints = this.constraints
+	shared := this.mux.getShared()
+	shared.routesMutex.Lock()
+	shared.routes = append(shared.routes, info)
+	shared.routesMutex.Unlock()
 }
 
 func (this *route) Use(middlewares ...fastchain.Constructor) *route {
 	this.chain = this.chain.Append(middlewares...)
+	for _, middleware := range middlewares {
+		this.middlewares = append(this.middlewares, helpers.FuncName(middleware))
+	}
 	return this
 }
 
 func (this *route) ThenFunc(h fasthttp.RequestHandler) {
-	this.mux.Router.Handle(this.method, this.pattern, wrapHandler(this.chain.ThenFunc(h)))
+	this.register(h)
+	mux := this.mux
+	// the preflights dispatched to the route must be answered by its middlewares
+	guarded := func(requestCtx *fasthttp.RequestCtx) {
+		if isPreflight(requestCtx) {
+			mux.options(requestCtx)
+			return
+		}
+		h(requestCtx)
+	}
+	this.mux.Router.Handle(this.method, this.pattern, wrapHandler(this.pattern, this.checkConstraints(this.chain.ThenFunc(guarded))))
+}
+
+func (this *route) checkConstraints(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	if len(this.constraints) == 0 {
+		return h
+	}
+	mux, constraints := this.mux, this.constraints
+	return func(requestCtx *fasthttp.RequestCtx) {
+		if !isPreflight(requestCtx) && !helpers.MatchConstraints(Params(requestCtx), constraints) {
+			mux.constraintFailed(requestCtx)
+			return
+		}
+		h(requestCtx)
+	}
 }
 
-// Params(r *http.Request) is a function to get URL params from the request fasthttpcontext
+// Params(requestCtx *fasthttp.RequestCtx) is a function to get URL params from the request user values
 func Params(requestCtx *fasthttp.RequestCtx) map[string]string {
-	if params, ok := fasthttpcontext.GetOk(requestCtx, "params"); ok {
-		return params.(map[string]string)
+	if params, ok := requestCtx.UserValue("params").(map[string]string); ok {
+		return params
 	}
 	return nil
 }
+
+// RoutePattern returns the pattern of the route which matched the request, e.g.
+// "/users/:id", or "" outside of a route.
+func RoutePattern(requestCtx *fasthttp.RequestCtx) string {
+	pattern, _ := requestCtx.UserValue("pattern").(string)
+	return pattern
+}
+
 func GetParam(requestCtx *fasthttp.RequestCtx, key string) string {
 	if params := Params(requestCtx); params != nil {
 		if value, ok := params[key]; ok {
@@ -115,29 +366,6 @@ func GetParam(requestCtx *fasthttp.RequestCtx, key string) string {
 	return ""
 }
 
-type GlobalRouter struct {
-	webRouter     *Mux
-	apiRouter     *Mux
-	apiDomainName string
-	apiPath       string
-}
-
-func NewGlobalRouter(WebRouter *Mux, APIRouter *Mux, APIDomainName string, APIPath string) *GlobalRouter {
-	return &GlobalRouter{webRouter: WebRouter, apiRouter: APIRouter, apiDomainName: strings.ToLower(strings.TrimSpace(APIDomainName)), apiPath: strings.ToLower(strings.TrimSpace(APIPath))}
-}
-
-func (this GlobalRouter) ServeHTTP(requestCtx *fasthttp.RequestCtx) {
-	if this.apiDomainName != "" {
-		if strings.ToLower(helpers.BytesToString(requestCtx.Host())) == this.apiDomainName {
-			this.apiRouter.ServeHTTP(requestCtx)
-		} else {
-			this.webRouter.ServeHTTP(requestCtx)
-		}
-	} else {
-		if strings.HasPrefix(strings.ToLower(helpers.BytesToString(requestCtx.Path())), this.apiPath) {
-			this.apiRouter.ServeHTTP(requestCtx)
-		} else {
-			this.webRouter.ServeHTTP(requestCtx)
-		}
-	}
+func copyStrings(s []string) []string {
+	return append([]string(nil), s...)
 }
@@ -0,0 +1,54 @@
+package fastmux
+
+import (
+	"sync"
+
+	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/nehmeroumani/pill.go/openapi"
+	"github.com/valyala/fasthttp"
+)
+
+// ServeOpenAPI serves the OpenAPI document of the routes registered in the mux and all
+// of its groups. The document is generated on the first request, so routes registered
+// after ServeOpenAPI are included; the documentation routes themselves are not.
+func (this *Mux) ServeOpenAPI(config openapi.Config) {
+	var once sync.Once
+	var doc *openapi.Document
+	getDoc := func() *openapi.Document {
+		once.Do(func() {
+			doc = openapi.Generate(config.Info, this.Routes(), config.Servers...)
+		})
+		return doc
+	}
+	serve := func(contentType string, encode func(*openapi.Document) ([]byte, error)) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			data, err := encode(getDoc())
+			if err != nil {
+				clean.Error(err)
+				requestCtx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+				return
+			}
+			requestCtx.SetContentType(contentType)
+			requestCtx.Write(data)
+		}
+	}
+	if config.Path != "" {
+		r := this.Get(config.Path)
+		r.hidden = true
+		r.ThenFunc(serve("application/json", (*openapi.Document).JSON))
+	}
+	if config.YAMLPath != "" {
+		r := this.Get(config.YAMLPath)
+		r.hidden = true
+		r.ThenFunc(serve("application/yaml", (*openapi.Document).YAML))
+	}
+	if config.DocsPath != "" && config.Path != "" {
+		page := openapi.DocsPage(config, this.basePath+config.Path)
+		r := this.Get(config.DocsPath)
+		r.hidden = true
+		r.ThenFunc(func(requestCtx *fasthttp.RequestCtx) {
+			requestCtx.SetContentType("text/html; charset=utf-8")
+			requestCtx.Write(page)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package fastmux
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/openapi"
+	"github.com/nehmeroumani/pill.go/pilltest"
+)
+
+func TestServeOpenAPI(t *testing.T) {
+	m := New()
+	api := m.Group("/api")
+	api.ServeOpenAPI(openapi.Config{Info: openapi.Info{Title: "API", Version: "1"}, Path: "/openapi.json", YAMLPath: "/openapi.yaml", DocsPath: "/docs"})
+	api.Get("/users/:id").ThenFunc(pattern)
+	c := pilltest.NewFastHttp(t, m.ServeHTTP)
+
+	var doc openapi.Document
+	c.Get("/api/openapi.json").Do().ExpectStatus(http.StatusOK).ExpectHeader("Content-Type", "application/json").DecodeJSON(&doc)
+	if _, ok := doc.Paths["/api/users/{id}"]["get"]; !ok || len(doc.Paths) != 1 {
+		t.Errorf("the document should describe the routes registered after it and not the documentation routes, got %v", doc.Paths)
+	}
+	c.Get("/api/openapi.yaml").Do().ExpectStatus(http.StatusOK).ExpectHeader("Content-Type", "application/yaml").ExpectBodyContains("/api/users/{id}")
+	c.Get("/api/docs").Do().ExpectStatus(http.StatusOK).ExpectBodyContains(`data-url="/api/openapi.json"`)
+}
@@ -0,0 +1,33 @@
+package fastmux
+
+import (
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/valyala/fasthttp"
+)
+
+// ParamOr returns the URL param key, or def if it's missing or empty.
+func ParamOr(requestCtx *fasthttp.RequestCtx, key string, def string) string {
+	if value := GetParam(requestCtx, key); value != "" {
+		return value
+	}
+	return def
+}
+
+func ParamInt(requestCtx *fasthttp.RequestCtx, key string) (int, error) {
+	i, err := helpers.ParseIntParam(key, GetParam(requestCtx, key), 0)
+	return int(i), err
+}
+
+func ParamInt32(requestCtx *fasthttp.RequestCtx, key string) (int32, error) {
+	i, err := helpers.ParseIntParam(key, GetParam(requestCtx, key), 32)
+	return int32(i), err
+}
+
+func ParamInt64(requestCtx *fasthttp.RequestCtx, key string) (int64, error) {
+	return helpers.ParseIntParam(key, GetParam(requestCtx, key), 64)
+}
+
+// ParamUUID returns the URL param key lowercased, or an error if it isn't a valid UUID.
+func ParamUUID(requestCtx *fasthttp.RequestCtx, key string) (string, error) {
+	return helpers.ParseUUIDParam(key, GetParam(requestCtx, key))
+}
@@ -0,0 +1,47 @@
+package fastmux
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestParams(t *testing.T) {
+	m := New()
+	m.Get("/users/:id/posts/:slug").ThenFunc(func(requestCtx *fasthttp.RequestCtx) {
+		id, err := ParamInt(requestCtx, "id")
+		if err != nil {
+			requestCtx.Error(err.Error(), fasthttp.StatusBadRequest)
+			return
+		}
+		requestCtx.WriteString(strconv.Itoa(id) + " " + GetParam(requestCtx, "slug") + " " + ParamOr(requestCtx, "page", "1") + " " + strconv.Itoa(len(Params(requestCtx))))
+	})
+	client := pilltest.NewFastHttp(t, m.ServeHTTP)
+	client.Get("/users/42/posts/hello").Do().ExpectStatus(http.StatusOK).ExpectBodyContains("42 hello 1 2")
+	client.Get("/users/x/posts/hello").Do().ExpectStatus(http.StatusBadRequest).ExpectBodyContains(`param 'id' ("x"): invalid syntax`)
+	if params := Params(&fasthttp.RequestCtx{}); params != nil {
+		t.Errorf("a request outside of a route has no params, got %v", params)
+	}
+}
+
+func TestTypedParams(t *testing.T) {
+	requestCtx := &fasthttp.RequestCtx{}
+	wrapHandler("/", func(*fasthttp.RequestCtx) {})(requestCtx, map[string]string{"big": "2147483648", "uuid": "3F2504E0-4F89-11D3-9A0C-0305E82C3301"})
+	if _, err := ParamInt32(requestCtx, "big"); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("expected a range error, got %v", err)
+	}
+	if i, err := ParamInt64(requestCtx, "big"); err != nil || i != 2147483648 {
+		t.Errorf("expected 2147483648, got %d, %v", i, err)
+	}
+	if _, err := ParamInt(requestCtx, "missing"); !errors.Is(err, helpers.ErrMissingParam) {
+		t.Errorf("expected a missing param error, got %v", err)
+	}
+	if u, err := ParamUUID(requestCtx, "uuid"); err != nil || u != "3f2504e0-4f89-11d3-9a0c-0305e82c3301" {
+		t.Errorf("expected the lowercased UUID, got %s, %v", u, err)
+	}
+}
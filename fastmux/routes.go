@@ -0,0 +1,14 @@
+package fastmux
+
+import (
+	"io"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+type RouteInfo = helpers.RouteInfo
+
+// PrintRoutes writes the routes registered in the mux as a table.
+func (this *Mux) PrintRoutes(w io.Writer) error {
+	return helpers.WriteRoutes(w, this.Routes())
+}
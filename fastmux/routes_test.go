@@ -0,0 +1,30 @@
+package fastmux
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func showUser(requestCtx *fasthttp.RequestCtx) {}
+
+func TestRoutes(t *testing.T) {
+	m := New()
+	m.Use(tag("root"))
+	api := m.Group("/api")
+	api.Get("/users/:id").Name("users.show").Where("id", Int).ThenFunc(showUser)
+	api.Post("/users").ThenFunc(showUser)
+	routes := api.Routes()
+	if len(routes) != 2 || routes[0].Pattern != "/api/users/:id" || routes[0].Handler != "fastmux.showUser" || len(routes[0].Middlewares) != 1 || routes[1].Method != "POST" {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+	var table bytes.Buffer
+	if err := m.PrintRoutes(&table); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(table.String()), "\n"); len(lines) != 3 || !strings.Contains(lines[1], "id=int") {
+		t.Errorf("unexpected table:\n%s", table.String())
+	}
+}
@@ -0,0 +1,25 @@
+package fastmux
+
+import (
+	"github.com/nehmeroumani/fastchain"
+	"github.com/nehmeroumani/pill.go/unified"
+	"github.com/valyala/fasthttp"
+)
+
+// Unified runs h as a fasthttp handler, e.g. m.Get("/users/:id").ThenFunc(fastmux.Unified(h)).
+func Unified(h unified.HandlerFunc) fasthttp.RequestHandler {
+	return func(requestCtx *fasthttp.RequestCtx) {
+		h(unified.NewFastHttpContext(requestCtx, Params(requestCtx)))
+	}
+}
+
+// UnifiedMiddleware runs middleware in a fastchain chain.
+func UnifiedMiddleware(middleware unified.Middleware) fastchain.Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			middleware(func(c unified.Context) {
+				next(c.RequestCtx())
+			})(unified.NewFastHttpContext(requestCtx, Params(requestCtx)))
+		}
+	}
+}
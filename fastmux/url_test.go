@@ -0,0 +1,61 @@
+package fastmux
+
+import (
+	"github.com/valyala/fasthttp"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+func TestURL(t *testing.T) {
+	noop := func(requestCtx *fasthttp.RequestCtx) {}
+	m := New()
+	m.Get("/users/:id").Name("users.show").ThenFunc(noop)
+	m.Group("/files").Get("/*path").Name("files.show").ThenFunc(noop)
+	for _, c := range []struct {
+		name     string
+		params   []interface{}
+		expected string
+	}{
+		{"users.show", []interface{}{"id", 5}, "/users/5"},
+		{"users.show", []interface{}{"id", "a b", "tab", "posts"}, "/users/a%20b?tab=posts"},
+		{"files.show", []interface{}{"path", "css/app.css"}, "/files/css/app.css"},
+	} {
+		if u, err := m.URL(c.name, c.params...); err != nil || u != c.expected {
+			t.Errorf("%s %v: expected %s, got %s, %v", c.name, c.params, c.expected, u, err)
+		}
+	}
+	if _, err := m.URL("users.show"); err == nil {
+		t.Error("a missing param should be an error")
+	}
+	if _, err := m.URL("posts.show", "id", 1); err != helpers.UnknownRouteError("posts.show") {
+		t.Errorf("expected an unknown route error, got %v", err)
+	}
+}
+
+func TestURLPerMux(t *testing.T) {
+	noop := func(requestCtx *fasthttp.RequestCtx) {}
+	web, api := New(), New()
+	web.Get("/").Name("home").ThenFunc(noop)
+	// the muxes have their own names
+	api.Get("/api").Name("home").ThenFunc(noop)
+	api.Group("/api/v1").Get("/status").Name("status").ThenFunc(noop)
+	if u, _ := web.URL("home"); u != "/" {
+		t.Errorf("expected /, got %s", u)
+	}
+	if u, _ := api.URL("home"); u != "/api" {
+		t.Errorf("expected /api, got %s", u)
+	}
+	if u, _ := api.URL("status"); u != "/api/v1/status" {
+		t.Errorf("the names of the groups should be shared with their mux, got %s", u)
+	}
+	if _, err := web.URL("status"); err == nil {
+		t.Error("the names of another mux shouldn't be known")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("a name reused for another pattern in the same mux should panic")
+		}
+	}()
+	web.Get("/home").Name("home").ThenFunc(noop)
+}
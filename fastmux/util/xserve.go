@@ -1,13 +1,16 @@
 package util
 
 import (
+	"io"
+	"net/http"
 	"net/url"
 	"os"
-	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/nehmeroumani/pill.go/assets"
+	"github.com/nehmeroumani/pill.go/clean"
 	"github.com/nehmeroumani/pill.go/helpers"
 	"github.com/valyala/fasthttp"
 )
@@ -17,9 +20,26 @@ var (
 	staticFilesURLPath, uploadsURLPath       string
 	staticFilesFromCloud, uploadsFromCloud   bool
 	cacheTTL                                 int64 = 60 * 60 * 24 * 30 * 6
-	fsUploadsHandler, fsStaticFilesHandler   fasthttp.RequestHandler
+	generateIndexPages                       bool
+	fsIndexPagesHandler                      fasthttp.RequestHandler
+	xServeOptions                            XServeOptions
+	assetsURLPrefix                          = "/static"
 )
 
+type XServeOptions struct {
+	// SPAFallback is the file, relative to the static files path, served to the page
+	// requests matching no static file, e.g. "index.html" for a single page app. The
+	// missing files are answered with a 404 when it's empty.
+	SPAFallback string
+	// AssetsManifest is the manifest written by pill-assets, outside of the static
+	// files path; the fingerprinted names of its files are served and returned by
+	// the Asset template function.
+	AssetsManifest string
+}
+
+// indexPageKey holds the confined path of the directory listed by fsIndexPagesHandler.
+const indexPageKey = "xserve.indexPage"
+
 func InitXServe(StaticFilesPath string, StaticFilesURLPath string, StaticFilesFromCloud bool, UploadsPath string, UploadsURLPath string, UploadsFromCloud bool, AppVersion string, opts ...bool) {
 	staticFilesPath = StaticFilesPath
 	staticFilesURLPath = StaticFilesURLPath
@@ -28,45 +48,37 @@ func InitXServe(StaticFilesPath string, StaticFilesURLPath string, StaticFilesFr
 	uploadsURLPath = UploadsURLPath
 	uploadsFromCloud = UploadsFromCloud
 	appVersion = AppVersion
-	generateIndexPages := false
+	assetsURLPrefix = "/static"
+	if StaticFilesFromCloud {
+		assetsURLPrefix = StaticFilesURLPath
+	}
+	initAssets()
+	generateIndexPages = false
 	if opts != nil && len(opts) > 0 {
 		generateIndexPages = opts[0]
 	}
-	fsUploads := &fasthttp.FS{
-		Root:                 "/",
-		GenerateIndexPages:   generateIndexPages,
-		IndexNames:           []string{"index.html"},
-		Compress:             true,
-		AcceptByteRange:      true,
-		CompressedFileSuffix: ".gz",
-		PathRewrite: fasthttp.PathRewriteFunc(func(requestCtx *fasthttp.RequestCtx) []byte {
-			requestedFile := helpers.BytesToString(requestCtx.Path())
-			if strings.HasPrefix(requestedFile, "/public") {
-				requestedFile = requestedFile[len("/public"):]
-			}
-			requestedFile = requestedFile[len("/uploads"):]
-			return []byte(path.Join(uploadsPath, requestedFile))
-		}),
-	}
-	fsUploadsHandler = fsUploads.NewRequestHandler()
-	fsStaticFiles := &fasthttp.FS{
-		Root:                 "/",
-		IndexNames:           []string{"index.html"},
-		GenerateIndexPages:   generateIndexPages,
-		Compress:             true,
-		AcceptByteRange:      true,
-		CompressedFileSuffix: ".gz",
-		PathRewrite: fasthttp.PathRewriteFunc(func(requestCtx *fasthttp.RequestCtx) []byte {
-			requestedFile := helpers.BytesToString(requestCtx.Path())
-			if strings.HasPrefix(requestedFile, "/public") {
-				requestedFile = requestedFile[len("/public"):]
-			}
-			requestedFile = requestedFile[len("/static"):]
-			return []byte(path.Join(staticFilesPath, requestedFile))
-		}),
+	if generateIndexPages {
+		fsIndexPages := &fasthttp.FS{
+			Root:               "/",
+			GenerateIndexPages: true,
+			PathRewrite: fasthttp.PathRewriteFunc(func(requestCtx *fasthttp.RequestCtx) []byte {
+				dir, _ := requestCtx.UserValue(indexPageKey).(string)
+				return []byte(filepath.ToSlash(dir))
+			}),
+		}
+		fsIndexPagesHandler = fsIndexPages.NewRequestHandler()
 	}
-	fsStaticFilesHandler = fsStaticFiles.NewRequestHandler()
+}
 
+func SetXServeOptions(options XServeOptions) {
+	xServeOptions = options
+	initAssets()
+}
+
+func initAssets() {
+	if err := assets.Init(xServeOptions.AssetsManifest, assetsURLPrefix); err != nil {
+		clean.Error(err)
+	}
 }
 
 func StaticFilesServe(requestCtx *fasthttp.RequestCtx) {
@@ -83,9 +95,9 @@ func xServe(requestCtx *fasthttp.RequestCtx, filesPath string, filesURLPath stri
 		requestedFile = requestedFile[len("/public"):]
 	}
 	if isStatic {
-		requestedFile = requestedFile[len("/static"):]
+		requestedFile = strings.TrimPrefix(requestedFile, "/static")
 	} else {
-		requestedFile = requestedFile[len("/uploads"):]
+		requestedFile = strings.TrimPrefix(requestedFile, "/uploads")
 	}
 	if fromCloud {
 		queryString := helpers.BytesToString(requestCtx.URI().QueryString())
@@ -95,84 +107,143 @@ func xServe(requestCtx *fasthttp.RequestCtx, filesPath string, filesURLPath stri
 		}
 		requestedFile += "app_version=" + url.QueryEscape(appVersion)
 		requestCtx.Redirect(filesURLPath+requestedFile, 307)
-	} else if f, err := os.Open(filesPath + filepath.FromSlash(requestedFile)); err == nil {
-		defer f.Close()
-		fileInfo, infoErr := f.Stat()
-		if infoErr != nil {
-			requestCtx.Redirect("/404", 307)
-		} else {
-			requestCtx.Response.Header.Set("Cache-Control", "public, max-age="+strconv.FormatInt(cacheTTL, 10))
-			if requestCtx.IsHead() && !fileInfo.IsDir() {
-				fileExtension := strings.ToLower(filepath.Ext(requestedFile))
-				if err == nil {
-					var contentType string
-					switch fileExtension {
-					case ".js":
-						contentType = "text/javascript"
-					case ".css":
-						contentType = "text/css"
-					case ".jpg":
-						contentType = "image/jpg"
-					case ".png":
-						contentType = "image/png"
-					case ".jpeg":
-						contentType = "image/jpeg"
-					case ".gif":
-						contentType = "image/gif"
-					case ".mp3":
-						contentType = "audio/mpeg"
-					case ".ogg":
-						contentType = "audio/ogg"
-					case ".mp4":
-						contentType = "video/mp4"
-					case ".avi":
-						contentType = "video/x-msvideo"
-					case ".wmv":
-						contentType = "video/x-ms-wmv"
-					case ".3gp":
-						contentType = "video/3gpp"
-					case ".mov":
-						contentType = "video/quicktime"
-					case ".mkv":
-						contentType = "video/x-matroska"
-					case ".woff":
-						contentType = "application/x-font-woff"
-					case ".ttf":
-						contentType = "application/font-sfnt"
-					case ".svg":
-						contentType = "image/svg+xml"
-					case ".eot":
-						contentType = "application/vnd.ms-fontobject"
-					case ".pdf":
-						contentType = "application/pdf"
-					case ".ipa":
-						contentType = "application/octet-stream"
-					case ".html":
-						contentType = "text/html"
-					case ".plist":
-						contentType = "application/x-plist"
-					case ".apk":
-						contentType = "application/vnd.android.package-archive"
-					default:
-						contentType = "text/plain"
-					}
-					requestCtx.SetContentType(contentType)
-					fileSize := fileInfo.Size()
-					requestCtx.Response.Header.Set("Content-Length", strconv.FormatInt(fileSize, 10))
-					requestCtx.Response.Header.Set("Accept-Ranges", "bytes")
-					requestCtx.SetStatusCode(304)
-					requestCtx.Write([]byte{})
-				}
-			} else {
-				requestCtx.Request.SetRequestURIBytes(requestCtx.Path())
-				if isStatic {
-					fsStaticFilesHandler(requestCtx)
-				} else {
-					fsUploadsHandler(requestCtx)
+		return
+	}
+	// the fingerprinted names are served from the original files
+	immutable := false
+	if isStatic {
+		if original, ok := assets.Original(requestedFile); ok {
+			requestedFile, immutable = "/"+original, true
+		}
+	}
+	name, info, err := helpers.ResolveStaticFile(filesPath, requestedFile)
+	if err != nil {
+		if generateIndexPages {
+			if dir, dirErr := helpers.ConfinePath(filesPath, requestedFile); dirErr == nil {
+				if dirInfo, statErr := os.Stat(dir); statErr == nil && dirInfo.IsDir() {
+					requestCtx.SetUserValue(indexPageKey, dir)
+					fsIndexPagesHandler(requestCtx)
+					return
 				}
 			}
 		}
+		if isStatic && xServeOptions.SPAFallback != "" && helpers.IsSPARoute(string(requestCtx.Method()), requestedFile, string(requestCtx.Request.Header.Peek("Accept"))) {
+			if name, info, err = helpers.ResolveStaticFile(filesPath, xServeOptions.SPAFallback); err == nil {
+				// the index changes with every release
+				requestCtx.Response.Header.Set("Cache-Control", "no-cache")
+				serveFile(requestCtx, name, info)
+				return
+			}
+		}
+		requestCtx.Error(fasthttp.StatusMessage(fasthttp.StatusNotFound), fasthttp.StatusNotFound)
+		return
+	}
+	if immutable {
+		requestCtx.Response.Header.Set("Cache-Control", assets.CacheControl)
 	} else {
-		requestCtx.Redirect("/404", 307)
+		requestCtx.Response.Header.Set("Cache-Control", "public, max-age="+strconv.FormatInt(cacheTTL, 10))
+	}
+	serveFile(requestCtx, name, info)
+}
+
+// serveFile serves a file, or its precompressed sibling preferred by the client, with
+// the semantics of http.ServeContent: single byte ranges and conditional requests on
+// its ETag and modification time.
+func serveFile(requestCtx *fasthttp.RequestCtx, name string, info os.FileInfo) {
+	requestHeader := func(name string) string {
+		return string(requestCtx.Request.Header.Peek(name))
+	}
+	contentType := helpers.StaticContentType(name)
+	served, servedInfo, encoding := name, info, ""
+	variant, variantInfo, variantEncoding, vary := helpers.PrecompressedVariant(name, info, requestHeader("Accept-Encoding"))
+	if variant != "" {
+		served, servedInfo, encoding = variant, variantInfo, variantEncoding
+	}
+	if contentType == "" {
+		contentType = sniffContentType(name)
+	}
+	etag := helpers.StaticETag(servedInfo, encoding)
+	lastModified := servedInfo.ModTime().UTC().Format(http.TimeFormat)
+	header := &requestCtx.Response.Header
+	if vary {
+		header.Add("Vary", "Accept-Encoding")
+	}
+	header.Set("ETag", etag)
+	header.Set("Last-Modified", lastModified)
+	header.Set("Accept-Ranges", "bytes")
+	header.SetContentType(contentType)
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+	}
+
+	switch helpers.ConditionalStatus(string(requestCtx.Method()), requestHeader, etag, lastModified) {
+	case http.StatusNotModified:
+		header.Del("Content-Encoding")
+		requestCtx.SetStatusCode(fasthttp.StatusNotModified)
+		return
+	case http.StatusPreconditionFailed:
+		requestCtx.Error(fasthttp.StatusMessage(fasthttp.StatusPreconditionFailed), fasthttp.StatusPreconditionFailed)
+		return
+	}
+
+	size := servedInfo.Size()
+	start, length := int64(0), size
+	statusCode := fasthttp.StatusOK
+	// multiple ranges are answered with the whole file
+	if byteRange := requestHeader("Range"); byteRange != "" && !strings.Contains(byteRange, ",") && ifRange(requestHeader("If-Range"), etag, lastModified) {
+		rangeStart, rangeEnd, err := fasthttp.ParseByteRange([]byte(byteRange), int(size))
+		if err != nil {
+			// requestCtx.Error would reset the Content-Range header
+			header.Del("Content-Encoding")
+			header.Set("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+			header.SetContentType("text/plain; charset=utf-8")
+			requestCtx.SetStatusCode(fasthttp.StatusRequestedRangeNotSatisfiable)
+			requestCtx.SetBodyString(fasthttp.StatusMessage(fasthttp.StatusRequestedRangeNotSatisfiable))
+			return
+		}
+		start, length = int64(rangeStart), int64(rangeEnd-rangeStart+1)
+		header.Set("Content-Range", "bytes "+strconv.Itoa(rangeStart)+"-"+strconv.Itoa(rangeEnd)+"/"+strconv.FormatInt(size, 10))
+		statusCode = fasthttp.StatusPartialContent
+	}
+
+	f, err := os.Open(served)
+	if err != nil {
+		requestCtx.Error(fasthttp.StatusMessage(fasthttp.StatusNotFound), fasthttp.StatusNotFound)
+		return
+	}
+	requestCtx.SetStatusCode(statusCode)
+	// the stream is closed by fasthttp once sent
+	requestCtx.SetBodyStream(&fileSection{io.NewSectionReader(f, start, length), f}, int(length))
+}
+
+// ifRange tells if the Range header applies, i.e. the If-Range header is absent or
+// matches the current ETag or modification date.
+func ifRange(header string, etag string, lastModified string) bool {
+	if header == "" {
+		return true
+	}
+	if strings.HasPrefix(header, `"`) || strings.HasPrefix(header, "W/") {
+		return helpers.MatchETag(header, etag, false)
+	}
+	return header == lastModified
+}
+
+type fileSection struct {
+	*io.SectionReader
+	file *os.File
+}
+
+func (this *fileSection) Close() error {
+	return this.file.Close()
+}
+
+func sniffContentType(name string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		return "application/octet-stream"
 	}
+	defer f.Close()
+	buffer := make([]byte, 512)
+	n, _ := io.ReadFull(f, buffer)
+	return http.DetectContentType(buffer[:n])
 }
@@ -0,0 +1,40 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+)
+
+// TCPCheck checks that a connection to addr can be opened.
+func TCPCheck(addr string) Check {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// WritableDirCheck checks that a file can be created in dir.
+func WritableDirCheck(dir string) Check {
+	return func(ctx context.Context) error {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return errors.New(dir + " is not a directory")
+		}
+		f, err := ioutil.TempFile(dir, ".health-")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		return os.Remove(f.Name())
+	}
+}
@@ -0,0 +1,72 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/valyala/fasthttp"
+)
+
+// LivenessHandler serves the liveness report of a registry, DefaultRegistry by
+// default, e.g. m.Get("/healthz").Then(health.LivenessHandler()). It answers 503 when
+// the report is down.
+func LivenessHandler(registry ...*Registry) http.Handler {
+	r := getRegistry(registry)
+	return reportHandler(r.Liveness)
+}
+
+// ReadinessHandler serves the readiness report, e.g.
+// m.Get("/readyz").Then(health.ReadinessHandler()).
+func ReadinessHandler(registry ...*Registry) http.Handler {
+	r := getRegistry(registry)
+	return reportHandler(r.Readiness)
+}
+
+func reportHandler(report func(context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, statusCode := encodeReport(report(req.Context()))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(statusCode)
+		w.Write(body)
+	})
+}
+
+// FastHttpLivenessHandler is the fasthttp version of LivenessHandler.
+func FastHttpLivenessHandler(registry ...*Registry) fasthttp.RequestHandler {
+	r := getRegistry(registry)
+	return fastHttpReportHandler(r.Liveness)
+}
+
+// FastHttpReadinessHandler is the fasthttp version of ReadinessHandler.
+func FastHttpReadinessHandler(registry ...*Registry) fasthttp.RequestHandler {
+	r := getRegistry(registry)
+	return fastHttpReportHandler(r.Readiness)
+}
+
+func fastHttpReportHandler(report func(context.Context) Report) fasthttp.RequestHandler {
+	return func(requestCtx *fasthttp.RequestCtx) {
+		body, statusCode := encodeReport(report(requestCtx))
+		requestCtx.SetContentType("application/json; charset=utf-8")
+		requestCtx.Response.Header.Set("Cache-Control", "no-store")
+		requestCtx.SetStatusCode(statusCode)
+		requestCtx.SetBody(body)
+	}
+}
+
+func encodeReport(report Report) ([]byte, int) {
+	statusCode := http.StatusOK
+	if report.Status != StatusUp {
+		statusCode = http.StatusServiceUnavailable
+	}
+	body, _ := json.Marshal(report)
+	return body, statusCode
+}
+
+func getRegistry(registry []*Registry) *Registry {
+	if len(registry) > 0 && registry[0] != nil {
+		return registry[0]
+	}
+	return DefaultRegistry
+}
@@ -0,0 +1,213 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports a subsystem as unhealthy by returning an error, it should give up
+// once ctx is done.
+type Check func(ctx context.Context) error
+
+type CheckOptions struct {
+	// Timeout of a run of the check, 2 seconds by default.
+	Timeout time.Duration
+	// CacheTTL is how long the result of a run is reused, 5 seconds by default, so
+	// frequent probes don't hammer the checked subsystems.
+	CacheTTL time.Duration
+	// Liveness adds the check to /healthz, it should only be set on checks whose
+	// failure can't be fixed without a restart. All the checks are part of /readyz.
+	Liveness bool
+	// Optional checks are reported but don't fail the probes.
+	Optional bool
+}
+
+var defaultCheckOptions = CheckOptions{Timeout: 2 * time.Second, CacheTTL: 5 * time.Second}
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+type CheckResult struct {
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Optional  bool      `json:"optional,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the JSON body of the /healthz and /readyz responses.
+type Report struct {
+	Status       Status                 `json:"status"`
+	ShuttingDown bool                   `json:"shutting_down,omitempty"`
+	Checks       map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type Registry struct {
+	mutex        sync.RWMutex
+	checks       map[string]*check
+	shuttingDown int32
+}
+
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{checks: map[string]*check{}}
+}
+
+// Register adds a check to the registry, replacing the check registered under the
+// same name if any.
+func (this *Registry) Register(name string, fn Check, opts ...CheckOptions) {
+	options := defaultCheckOptions
+	if len(opts) > 0 {
+		options = opts[0]
+		if options.Timeout <= 0 {
+			options.Timeout = defaultCheckOptions.Timeout
+		}
+		if options.CacheTTL <= 0 {
+			options.CacheTTL = defaultCheckOptions.CacheTTL
+		}
+	}
+	this.mutex.Lock()
+	this.checks[name] = &check{fn: fn, options: options}
+	this.mutex.Unlock()
+}
+
+func (this *Registry) Unregister(name string) {
+	this.mutex.Lock()
+	delete(this.checks, name)
+	this.mutex.Unlock()
+}
+
+// SetShuttingDown turns readiness off without running the checks, it's called by
+// server.Server as soon as its graceful shutdown begins.
+func (this *Registry) SetShuttingDown(shuttingDown bool) {
+	if shuttingDown {
+		atomic.StoreInt32(&this.shuttingDown, 1)
+	} else {
+		atomic.StoreInt32(&this.shuttingDown, 0)
+	}
+}
+
+func (this *Registry) ShuttingDown() bool {
+	return atomic.LoadInt32(&this.shuttingDown) == 1
+}
+
+// Liveness runs the liveness checks, the process is alive if it can answer and none
+// of them failed. The checks run on their own context, ctx being the probe's.
+func (this *Registry) Liveness(ctx context.Context) Report {
+	return this.run(true)
+}
+
+// Readiness runs all the checks, it's down while shutting down.
+func (this *Registry) Readiness(ctx context.Context) Report {
+	if this.ShuttingDown() {
+		return Report{Status: StatusDown, ShuttingDown: true}
+	}
+	return this.run(false)
+}
+
+func (this *Registry) run(liveness bool) Report {
+	this.mutex.RLock()
+	names := make([]string, 0, len(this.checks))
+	for name, c := range this.checks {
+		if !liveness || c.options.Liveness {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	checks := make([]*check, len(names))
+	for i, name := range names {
+		checks[i] = this.checks[name]
+	}
+	this.mutex.RUnlock()
+
+	report := Report{Status: StatusUp, ShuttingDown: this.ShuttingDown()}
+	if len(checks) == 0 {
+		return report
+	}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.result()
+		}(i, c)
+	}
+	wg.Wait()
+	report.Checks = make(map[string]CheckResult, len(checks))
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status == StatusDown && !results[i].Optional {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+type check struct {
+	fn      Check
+	options CheckOptions
+	// mutex is held during a run, concurrent probes wait for its result
+	mutex   sync.Mutex
+	last    CheckResult
+	expires time.Time
+}
+
+// result runs the check on its own context, bounded by its timeout, so a probe going
+// away doesn't fail the check for the other probes.
+func (this *check) result() CheckResult {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if time.Now().Before(this.expires) {
+		return this.last
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), this.options.Timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- errors.New("check panicked")
+			}
+		}()
+		done <- this.fn(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// checks ignoring ctx can't hold the probe past their timeout
+		err = errors.New("timed out after " + this.options.Timeout.String())
+	}
+	result := CheckResult{Status: StatusUp, Optional: this.options.Optional, Duration: time.Since(start).String(), CheckedAt: start}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	this.last = result
+	this.expires = time.Now().Add(this.options.CacheTTL)
+	return result
+}
+
+// Register adds a check to DefaultRegistry.
+func Register(name string, fn Check, opts ...CheckOptions) {
+	DefaultRegistry.Register(name, fn, opts...)
+}
+
+func Unregister(name string) {
+	DefaultRegistry.Unregister(name)
+}
+
+func SetShuttingDown(shuttingDown bool) {
+	DefaultRegistry.SetShuttingDown(shuttingDown)
+}
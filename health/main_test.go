@@ -0,0 +1,174 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestReport(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("db", func(ctx context.Context) error {
+		return nil
+	}, CheckOptions{Liveness: true})
+	registry.Register("cache", func(ctx context.Context) error {
+		return errors.New("unreachable")
+	}, CheckOptions{Optional: true})
+	registry.Register("queue", func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	liveness := registry.Liveness(context.Background())
+	if liveness.Status != StatusUp || len(liveness.Checks) != 1 || liveness.Checks["db"].Status != StatusUp {
+		t.Errorf("expected only the db check to be up, got %+v", liveness)
+	}
+	readiness := registry.Readiness(context.Background())
+	if readiness.Status != StatusDown || len(readiness.Checks) != 3 {
+		t.Fatalf("expected the 3 checks down, got %+v", readiness)
+	}
+	if cache := readiness.Checks["cache"]; cache.Status != StatusDown || !cache.Optional || cache.Error != "unreachable" {
+		t.Errorf("unexpected cache result %+v", cache)
+	}
+	if queue := readiness.Checks["queue"]; queue.Status != StatusDown || queue.Error != "check panicked" {
+		t.Errorf("unexpected queue result %+v", queue)
+	}
+
+	registry.Unregister("queue")
+	if readiness := registry.Readiness(context.Background()); readiness.Status != StatusUp {
+		t.Errorf("an optional check should not fail the report, got %+v", readiness)
+	}
+}
+
+func TestCaching(t *testing.T) {
+	registry := NewRegistry()
+	var runs int32
+	registry.Register("db", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}, CheckOptions{CacheTTL: 50 * time.Millisecond})
+	for i := 0; i < 3; i++ {
+		registry.Readiness(context.Background())
+	}
+	if runs != 1 {
+		t.Errorf("expected the result to be reused, got %d runs", runs)
+	}
+	time.Sleep(60 * time.Millisecond)
+	registry.Readiness(context.Background())
+	if runs != 2 {
+		t.Errorf("expected the check to run again once expired, got %d runs", runs)
+	}
+}
+
+func TestCancelledProbe(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("db", func(ctx context.Context) error {
+		select {
+		case <-time.After(20 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, CheckOptions{CacheTTL: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := registry.Readiness(ctx); report.Status != StatusUp {
+		t.Errorf("a probe going away should not fail the check, got %+v", report)
+	}
+	if report := registry.Readiness(context.Background()); report.Status != StatusUp {
+		t.Errorf("expected the cached result to be up, got %+v", report)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("stuck", func(ctx context.Context) error {
+		// ignores ctx
+		time.Sleep(time.Second)
+		return nil
+	}, CheckOptions{Timeout: 20 * time.Millisecond})
+	start := time.Now()
+	report := registry.Readiness(context.Background())
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("the probe should not wait for a stuck check")
+	}
+	if stuck := report.Checks["stuck"]; stuck.Status != StatusDown || stuck.Error != "timed out after 20ms" {
+		t.Errorf("unexpected result %+v", stuck)
+	}
+}
+
+func TestShuttingDown(t *testing.T) {
+	registry := NewRegistry()
+	var runs int32
+	registry.Register("db", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}, CheckOptions{Liveness: true})
+	registry.SetShuttingDown(true)
+	if report := registry.Readiness(context.Background()); report.Status != StatusDown || !report.ShuttingDown || report.Checks != nil {
+		t.Errorf("expected readiness down without running the checks, got %+v", report)
+	}
+	if runs != 0 {
+		t.Errorf("the checks should not run while shutting down")
+	}
+	if report := registry.Liveness(context.Background()); report.Status != StatusUp || !report.ShuttingDown {
+		t.Errorf("expected liveness up while shutting down, got %+v", report)
+	}
+	registry.SetShuttingDown(false)
+	if report := registry.Readiness(context.Background()); report.Status != StatusUp || report.ShuttingDown {
+		t.Errorf("expected readiness up again, got %+v", report)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("db", func(ctx context.Context) error {
+		return errors.New("unreachable")
+	})
+	responses := map[string]func(path string) (int, http.Header, []byte){
+		"net/http": func(path string) (int, http.Header, []byte) {
+			handler := LivenessHandler(registry)
+			if path == "/readyz" {
+				handler = ReadinessHandler(registry)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			return w.Code, w.Header(), w.Body.Bytes()
+		},
+		"fasthttp": func(path string) (int, http.Header, []byte) {
+			handler := FastHttpLivenessHandler(registry)
+			if path == "/readyz" {
+				handler = FastHttpReadinessHandler(registry)
+			}
+			requestCtx := &fasthttp.RequestCtx{}
+			handler(requestCtx)
+			header := http.Header{}
+			header.Set("Content-Type", string(requestCtx.Response.Header.ContentType()))
+			header.Set("Cache-Control", string(requestCtx.Response.Header.Peek("Cache-Control")))
+			return requestCtx.Response.StatusCode(), header, requestCtx.Response.Body()
+		},
+	}
+	for name, response := range responses {
+		t.Run(name, func(t *testing.T) {
+			for path, expected := range map[string]int{"/healthz": http.StatusOK, "/readyz": http.StatusServiceUnavailable} {
+				statusCode, header, body := response(path)
+				var report Report
+				if err := json.Unmarshal(body, &report); err != nil {
+					t.Fatal(err)
+				}
+				if statusCode != expected || header.Get("Content-Type") != "application/json; charset=utf-8" || header.Get("Cache-Control") != "no-store" {
+					t.Errorf("%s: expected %d, got %d and %v", path, expected, statusCode, header)
+				}
+				if statusCode == http.StatusServiceUnavailable && report.Checks["db"].Error != "unreachable" {
+					t.Errorf("%s: unexpected report %s", path, body)
+				}
+			}
+		})
+	}
+}
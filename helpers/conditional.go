@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MatchETag tells if etag is in the list of an If-Match or If-None-Match header, with
+// the strong comparison (weak ETags never match) or the weak one. "*" matches any
+// current representation, even one without an ETag.
+func MatchETag(header string, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if etag == "" {
+		return false
+	}
+	if !weak && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ConditionalStatus evaluates the preconditions of a request against the ETag and
+// the Last-Modified date of the current representation, it returns 304, 412 or 0 when
+// the request must be handled.
+func ConditionalStatus(method string, requestHeader func(string) string, etag string, lastModified string) int {
+	if ifMatch := requestHeader("If-Match"); ifMatch != "" {
+		if !MatchETag(ifMatch, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ifUnmodifiedSince := requestHeader("If-Unmodified-Since"); ifUnmodifiedSince != "" && lastModified != "" {
+		if modifiedAfter(lastModified, ifUnmodifiedSince) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	safe := method == "GET" || method == "HEAD"
+	if ifNoneMatch := requestHeader("If-None-Match"); ifNoneMatch != "" {
+		if MatchETag(ifNoneMatch, etag, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ifModifiedSince := requestHeader("If-Modified-Since"); safe && ifModifiedSince != "" && lastModified != "" {
+		if !modifiedAfter(lastModified, ifModifiedSince) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// modifiedAfter compares two HTTP dates, an invalid date counts as modified.
+func modifiedAfter(lastModified string, since string) bool {
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return true
+	}
+	t, err := http.ParseTime(since)
+	if err != nil {
+		return true
+	}
+	return modified.Truncate(time.Second).After(t)
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMatchETag(t *testing.T) {
+	for _, test := range []struct {
+		header string
+		etag   string
+		weak   bool
+		match  bool
+	}{
+		{`"a"`, `"a"`, false, true},
+		{`"b", "a"`, `"a"`, false, true},
+		{`"b"`, `"a"`, false, false},
+		{`W/"a"`, `"a"`, false, false},
+		{`"a"`, `W/"a"`, false, false},
+		{`W/"a"`, `"a"`, true, true},
+		{`"a"`, `W/"a"`, true, true},
+		{"*", `"a"`, false, true},
+		{" * ", `W/"a"`, false, true},
+		// "*" matches a representation without an ETag, a list never does
+		{"*", "", false, true},
+		{"*", "", true, true},
+		{`""`, "", false, false},
+		{`"a"`, "", true, false},
+	} {
+		if match := MatchETag(test.header, test.etag, test.weak); match != test.match {
+			t.Errorf("MatchETag(%q, %q, %v): expected %v", test.header, test.etag, test.weak, test.match)
+		}
+	}
+}
+
+func TestConditionalStatus(t *testing.T) {
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	for _, test := range []struct {
+		method string
+		header map[string]string
+		etag   string
+		status int
+	}{
+		{"GET", nil, `"a"`, 0},
+		{"GET", map[string]string{"If-None-Match": `W/"a"`}, `"a"`, http.StatusNotModified},
+		{"HEAD", map[string]string{"If-None-Match": `"b"`}, `"a"`, 0},
+		{"PUT", map[string]string{"If-None-Match": "*"}, "", http.StatusPreconditionFailed},
+		{"PUT", map[string]string{"If-Match": "*"}, "", 0},
+		{"PUT", map[string]string{"If-Match": `"b"`}, `"a"`, http.StatusPreconditionFailed},
+		{"PUT", map[string]string{"If-Match": `W/"a"`}, `W/"a"`, http.StatusPreconditionFailed},
+		{"GET", map[string]string{"If-Modified-Since": lastModified}, "", http.StatusNotModified},
+		{"GET", map[string]string{"If-Modified-Since": "Sun, 01 Jan 2006 15:04:05 GMT"}, "", 0},
+		// If-None-Match takes precedence over If-Modified-Since
+		{"GET", map[string]string{"If-None-Match": `"b"`, "If-Modified-Since": lastModified}, `"a"`, 0},
+		{"PUT", map[string]string{"If-Unmodified-Since": "Sun, 01 Jan 2006 15:04:05 GMT"}, "", http.StatusPreconditionFailed},
+		{"PUT", map[string]string{"If-Unmodified-Since": lastModified}, "", 0},
+	} {
+		header := func(key string) string {
+			return test.header[key]
+		}
+		if status := ConditionalStatus(test.method, header, test.etag, lastModified); status != test.status {
+			t.Errorf("%s %v with %q: expected %d, got %d", test.method, test.header, test.etag, test.status, status)
+		}
+	}
+}
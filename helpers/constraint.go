@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Constraint restricts the values accepted by a route param.
+type Constraint struct {
+	Kind   string
+	Values []string
+	match  func(string) bool
+}
+
+func (this Constraint) Match(value string) bool {
+	if this.match == nil {
+		return true
+	}
+	return this.match(value)
+}
+
+// String describes the constraint, e.g. int, regex(^[a-z]+$) or enum(asc|desc).
+func (this Constraint) String() string {
+	if len(this.Values) > 0 {
+		return this.Kind + "(" + strings.Join(this.Values, "|") + ")"
+	}
+	return this.Kind
+}
+
+var (
+	rxInt  = regexp.MustCompile(`^-?[0-9]+$`)
+	rxSlug = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+	IntConstraint  = Constraint{Kind: "int", match: rxInt.MatchString}
+	UUIDConstraint = Constraint{Kind: "uuid", match: rxUUID.MatchString}
+	SlugConstraint = Constraint{Kind: "slug", match: rxSlug.MatchString}
+)
+
+// RegexConstraint accepts the values fully matching expr. It panics if expr doesn't compile.
+func RegexConstraint(expr string) Constraint {
+	rx := regexp.MustCompile(`^(?:` + expr + `)$`)
+	return Constraint{Kind: "regex", Values: []string{expr}, match: rx.MatchString}
+}
+
+func EnumConstraint(values ...string) Constraint {
+	return Constraint{Kind: "enum", Values: values, match: func(value string) bool {
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// MatchConstraints reports whether every constrained param satisfies its constraint.
+func MatchConstraints(params map[string]string, constraints map[string]Constraint) bool {
+	for key, constraint := range constraints {
+		if !constraint.Match(params[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+// PatternParams returns the names of the wildcards and catch-alls of an httptreemux pattern.
+func PatternParams(pattern string) []string {
+	names := []string{}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment != "" && (segment[0] == ':' || segment[0] == '*') {
+			names = append(names, segment[1:])
+		}
+	}
+	return names
+}
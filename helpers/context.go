@@ -0,0 +1,6 @@
+package helpers
+
+// ContextKey is the type of the keys of the request context values set by the pill
+// packages on net/http. A value stored under ContextKey(name) is stored under the user
+// value name on fasthttp, so unified.Context finds it by name on both.
+type ContextKey string
@@ -0,0 +1,48 @@
+package helpers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NegotiateEncoding picks the preferred encoding accepted by an Accept-Encoding
+// header, honouring its q-values; it returns "" when none is acceptable.
+func NegotiateEncoding(acceptEncoding string, encodings []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	qualities := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if name == "*" {
+			wildcard = quality
+		} else if name != "" {
+			qualities[name] = quality
+		}
+	}
+	best, bestQuality := "", 0.0
+	for _, encoding := range encodings {
+		quality, ok := qualities[encoding]
+		if !ok && encoding == "gzip" {
+			quality, ok = qualities["x-gzip"]
+		}
+		if !ok {
+			quality = wildcard
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
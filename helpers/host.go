@@ -0,0 +1,67 @@
+package helpers
+
+import (
+	"net"
+	"strings"
+)
+
+// HostPattern matches request hosts, either exactly ("example.com") or with a leading
+// wildcard label ("*.example.com") which captures the subdomain.
+type HostPattern struct {
+	Pattern  string
+	suffix   string
+	wildcard bool
+}
+
+func ParseHostPattern(pattern string) HostPattern {
+	pattern = NormalizeHost(pattern)
+	if strings.HasPrefix(pattern, "*.") {
+		if strings.Contains(pattern[2:], "*") {
+			panic("only the first label of a host pattern can be a wildcard: " + pattern)
+		}
+		return HostPattern{Pattern: pattern, suffix: pattern[1:], wildcard: true}
+	}
+	if strings.Contains(pattern, "*") {
+		panic("only the first label of a host pattern can be a wildcard: " + pattern)
+	}
+	return HostPattern{Pattern: pattern}
+}
+
+// Match reports whether host matches the pattern and returns the label captured by
+// its wildcard; the wildcard matches a single label.
+func (this HostPattern) Match(host string) (string, bool) {
+	host = NormalizeHost(host)
+	if !this.wildcard {
+		return "", host == this.Pattern
+	}
+	if !strings.HasSuffix(host, this.suffix) {
+		return "", false
+	}
+	subdomain := host[:len(host)-len(this.suffix)]
+	if subdomain == "" || strings.Contains(subdomain, ".") {
+		return "", false
+	}
+	return subdomain, true
+}
+
+// NormalizeHost lowercases host and removes its port and trailing dot.
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// MatchPathPrefix reports whether path is under prefix, segment-wise: "/api" matches
+// "/api" and "/api/users" but not "/apis". The comparison ignores the case.
+func MatchPathPrefix(path string, prefix string) bool {
+	if prefix == "" || prefix == "/" {
+		return true
+	}
+	path, prefix = strings.ToLower(path), strings.ToLower(prefix)
+	if strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(path, prefix) || path == strings.TrimSuffix(prefix, "/")
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
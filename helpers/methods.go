@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"sort"
+	"strings"
+)
+
+// AllowHeader builds the value of the Allow header from the methods registered for a
+// path, adding HEAD when GET is handled and OPTIONS which is always answered.
+func AllowHeader(methods []string) string {
+	allowed := map[string]bool{"OPTIONS": true}
+	for _, method := range methods {
+		allowed[method] = true
+		if method == "GET" {
+			allowed["HEAD"] = true
+		}
+	}
+	list := make([]string, 0, len(allowed))
+	for method := range allowed {
+		list = append(list, method)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrMissingParam = errors.New("missing param")
+	ErrInvalidUUID  = errors.New("invalid uuid")
+
+	rxUUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// ParamError is returned by the typed param accessors of mux and fastmux when a
+// URL param is missing or can't be parsed.
+type ParamError struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+func (this *ParamError) Error() string {
+	if this.Err == ErrMissingParam {
+		return "param '" + this.Key + "': " + this.Err.Error()
+	}
+	return "param '" + this.Key + "' (" + strconv.Quote(this.Value) + "): " + this.Err.Error()
+}
+
+func (this *ParamError) Unwrap() error {
+	return this.Err
+}
+
+func ParseIntParam(key string, value string, bitSize int) (int64, error) {
+	if value == "" {
+		return 0, &ParamError{Key: key, Err: ErrMissingParam}
+	}
+	i, err := strconv.ParseInt(value, 10, bitSize)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok {
+			err = numErr.Err
+		}
+		return 0, &ParamError{Key: key, Value: value, Err: err}
+	}
+	return i, nil
+}
+
+// ParseUUIDParam checks that value is a canonical UUID and returns it lowercased.
+func ParseUUIDParam(key string, value string) (string, error) {
+	if value == "" {
+		return "", &ParamError{Key: key, Err: ErrMissingParam}
+	}
+	if !rxUUID.MatchString(value) {
+		return "", &ParamError{Key: key, Value: value, Err: ErrInvalidUUID}
+	}
+	return strings.ToLower(value), nil
+}
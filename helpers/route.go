@@ -0,0 +1,74 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var ErrOddRouteParams = errors.New("route params must be key/value pairs")
+
+// UnknownRouteError is returned for a route name a router doesn't know.
+type UnknownRouteError string
+
+func (this UnknownRouteError) Error() string {
+	return "unknown route '" + string(this) + "'"
+}
+
+// BuildRoutePath fills the wildcards (:name) and catch-alls (*name) of an httptreemux
+// pattern with the given key/value pairs. Pairs that don't match a wildcard of the
+// pattern are appended as a query string.
+//
+// e.g. BuildRoutePath("/users/:id", "id", 5, "tab", "posts") -> /users/5?tab=posts
+func BuildRoutePath(pattern string, params ...interface{}) (string, error) {
+	if len(params)%2 != 0 {
+		return "", ErrOddRouteParams
+	}
+	values := map[string]string{}
+	keys := []string{}
+	for i := 0; i < len(params); i += 2 {
+		key := fmt.Sprint(params[i])
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = fmt.Sprint(params[i+1])
+	}
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		switch segment[0] {
+		case ':', '*':
+			name := segment[1:]
+			value, ok := values[name]
+			if !ok {
+				return "", errors.New("missing route param '" + name + "' for " + pattern)
+			}
+			delete(values, name)
+			if segment[0] == '*' {
+				parts := strings.Split(strings.TrimPrefix(value, "/"), "/")
+				for j, part := range parts {
+					parts[j] = url.PathEscape(part)
+				}
+				segments[i] = strings.Join(parts, "/")
+			} else {
+				segments[i] = url.PathEscape(value)
+			}
+		case '\\':
+			segments[i] = segment[1:]
+		}
+	}
+	path := strings.Join(segments, "/")
+	if len(values) > 0 {
+		query := url.Values{}
+		for _, key := range keys {
+			if value, ok := values[key]; ok {
+				query.Add(key, value)
+			}
+		}
+		path += "?" + query.Encode()
+	}
+	return path, nil
+}
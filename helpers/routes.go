@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo describes a route registered in a mux.
+type RouteInfo struct {
+	Method      string
+	Pattern     string
+	Name        string
+	Middlewares []string
+	Handler     string
+	Constraints map[string]Constraint
+	Summary     string
+	Description string
+	Tags        []string
+	// Request and Responses describe the bodies of the route for the API docs.
+	Request   reflect.Type         `json:"-"`
+	Responses map[int]reflect.Type `json:"-"`
+}
+
+var rxFuncSuffix = regexp.MustCompile(`(\.func\d+)+$|-fm$`)
+
+// FuncName returns the name of a function, or the type name of any other value,
+// e.g. users.Show, users.(*Controller).Show or *users.Handler.
+func FuncName(f interface{}) string {
+	if f == nil {
+		return ""
+	}
+	v := reflect.ValueOf(f)
+	if v.Kind() == reflect.Func {
+		if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+			name := fn.Name()
+			if i := strings.LastIndex(name, "/"); i != -1 {
+				name = name[i+1:]
+			}
+			return rxFuncSuffix.ReplaceAllString(name, "")
+		}
+	}
+	return fmt.Sprintf("%T", f)
+}
+
+// WriteRoutes prints the routes as a table.
+func WriteRoutes(w io.Writer, routes []RouteInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATTERN\tNAME\tHANDLER\tMIDDLEWARES\tCONSTRAINTS")
+	for _, r := range routes {
+		keys := make([]string, 0, len(r.Constraints))
+		for key := range r.Constraints {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		constraints := make([]string, len(keys))
+		for i, key := range keys {
+			constraints[i] = key + "=" + r.Constraints[key].String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Method, r.Pattern, orDash(r.Name), orDash(r.Handler), orDash(strings.Join(r.Middlewares, ", ")), orDash(strings.Join(constraints, ", ")))
+	}
+	return tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,160 @@
+package helpers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrOutsideRoot = errors.New("path outside of root")
+	ErrHiddenFile  = errors.New("hidden file")
+)
+
+// PrecompressedSuffixes are the suffixes of the precompressed siblings of the static
+// files, by encoding in order of preference.
+var PrecompressedSuffixes = []struct{ Encoding, Suffix string }{{"br", ".br"}, {"gzip", ".gz"}}
+
+// types missing from the builtin table of the mime package
+var staticTypes = map[string]string{
+	".3gp":   "video/3gpp",
+	".apk":   "application/vnd.android.package-archive",
+	".avi":   "video/x-msvideo",
+	".eot":   "application/vnd.ms-fontobject",
+	".ico":   "image/x-icon",
+	".ipa":   "application/octet-stream",
+	".map":   "application/json",
+	".mkv":   "video/x-matroska",
+	".mov":   "video/quicktime",
+	".mp3":   "audio/mpeg",
+	".mp4":   "video/mp4",
+	".ogg":   "audio/ogg",
+	".otf":   "font/otf",
+	".plist": "application/x-plist",
+	".ttf":   "font/ttf",
+	".txt":   "text/plain; charset=utf-8",
+	".webm":  "video/webm",
+	".wmv":   "video/x-ms-wmv",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+}
+
+func init() {
+	for ext, contentType := range staticTypes {
+		if mime.TypeByExtension(ext) == "" {
+			mime.AddExtensionType(ext, contentType)
+		}
+	}
+}
+
+// StaticContentType returns the type of a file from its extension, or "" when it's
+// unknown and must be sniffed from the content.
+func StaticContentType(name string) string {
+	return mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+}
+
+// ConfinePath joins the slash-separated requested path to root. It fails when the
+// result is outside of root, through ".." segments or symlinks, and for the hidden
+// files and directories (starting with a dot) other than .well-known.
+func ConfinePath(root string, requested string) (string, error) {
+	if strings.ContainsAny(requested, "\x00\\") {
+		return "", ErrOutsideRoot
+	}
+	cleaned := path.Clean("/" + requested)
+	for _, segment := range strings.Split(cleaned, "/") {
+		if strings.HasPrefix(segment, ".") && segment != ".well-known" {
+			return "", ErrHiddenFile
+		}
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(absRoot); err == nil {
+		absRoot = resolved
+	}
+	name := filepath.Join(absRoot, filepath.FromSlash(cleaned))
+	resolved, err := filepath.EvalSymlinks(name)
+	if err != nil {
+		return "", err
+	}
+	if resolved != absRoot && !strings.HasPrefix(resolved, absRoot+string(filepath.Separator)) {
+		return "", ErrOutsideRoot
+	}
+	return resolved, nil
+}
+
+// ResolveStaticFile returns the regular file served for the requested path, the
+// index.html file of the requested directories.
+func ResolveStaticFile(root string, requested string) (string, os.FileInfo, error) {
+	name, err := ConfinePath(root, requested)
+	if err != nil {
+		return "", nil, err
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return "", nil, err
+	}
+	if info.IsDir() {
+		name = filepath.Join(name, "index.html")
+		if info, err = os.Stat(name); err != nil {
+			return "", nil, err
+		}
+	}
+	if !info.Mode().IsRegular() {
+		return "", nil, os.ErrNotExist
+	}
+	return name, info, nil
+}
+
+// PrecompressedVariant returns the precompressed sibling of a file (app.js.br,
+// app.js.gz) preferred by an Accept-Encoding header, if any; the siblings older than
+// the file are ignored. vary tells if the file has siblings, the response then
+// depends on the Accept-Encoding header.
+func PrecompressedVariant(name string, info os.FileInfo, acceptEncoding string) (variant string, variantInfo os.FileInfo, encoding string, vary bool) {
+	var encodings []string
+	variants := map[string]os.FileInfo{}
+	for _, precompressed := range PrecompressedSuffixes {
+		if sibling, err := os.Stat(name + precompressed.Suffix); err == nil && sibling.Mode().IsRegular() && !sibling.ModTime().Before(info.ModTime()) {
+			encodings = append(encodings, precompressed.Encoding)
+			variants[precompressed.Encoding] = sibling
+		}
+	}
+	if len(encodings) == 0 {
+		return "", nil, "", false
+	}
+	if encoding = NegotiateEncoding(acceptEncoding, encodings); encoding == "" {
+		return "", nil, "", true
+	}
+	for _, precompressed := range PrecompressedSuffixes {
+		if precompressed.Encoding == encoding {
+			return name + precompressed.Suffix, variants[encoding], encoding, true
+		}
+	}
+	return "", nil, "", true
+}
+
+// StaticETag returns the strong ETag of a file, from its size and modification time
+// and the encoding of its variant.
+func StaticETag(info os.FileInfo, encoding string) string {
+	etag := strconv.FormatInt(info.ModTime().UnixNano(), 16) + "-" + strconv.FormatInt(info.Size(), 16)
+	if encoding != "" {
+		etag += "-" + encoding
+	}
+	return `"` + etag + `"`
+}
+
+// IsSPARoute tells if a request for a missing static file may be answered with the
+// index of a single page app: a GET or HEAD request for an HTML page, without file
+// extension.
+func IsSPARoute(method string, requested string, accept string) bool {
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	return path.Ext(requested) == "" && strings.Contains(accept, "text/html")
+}
@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// staticTree creates a public directory and a secret file next to it, which
+// public/link.txt and public/up/secret.txt point to.
+func staticTree(t *testing.T) string {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"public/index.html":               "index",
+		"public/css/app.css":              "body{}",
+		"public/.env":                     "SECRET=1",
+		"public/.git/config":              "[core]",
+		"public/docs/.hidden/notes.txt":   "notes",
+		"public/.well-known/security.txt": "contact",
+		"secret.txt":                      "secret",
+	} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := filepath.Join(dir, "public")
+	if err := os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Skip("symlinks aren't supported: ", err)
+	}
+	if err := os.Symlink(dir, filepath.Join(root, "up")); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestConfinePath(t *testing.T) {
+	root := staticTree(t)
+	resolvedRoot, _ := filepath.EvalSymlinks(root)
+	for requested, expected := range map[string]string{
+		"/index.html":        "index.html",
+		"css/app.css":        "css/app.css",
+		"/":                  "",
+		"/css/../index.html": "index.html",
+		// ".." can't go above the root
+		"/../../index.html":         "index.html",
+		"/.well-known/security.txt": ".well-known/security.txt",
+	} {
+		name, err := ConfinePath(root, requested)
+		if err != nil || name != filepath.Join(resolvedRoot, filepath.FromSlash(expected)) {
+			t.Errorf("%q: expected %s, got %s, %v", requested, expected, name, err)
+		}
+	}
+	if name, err := ConfinePath(root, "/../secret.txt"); !os.IsNotExist(err) {
+		t.Errorf("the files above the root should be looked for in it, got %s, %v", name, err)
+	}
+	for requested, expected := range map[string]error{
+		"/link.txt":                ErrOutsideRoot,
+		"/up/secret.txt":           ErrOutsideRoot,
+		"/up":                      ErrOutsideRoot,
+		"/.env":                    ErrHiddenFile,
+		"/.git/config":             ErrHiddenFile,
+		"/docs/.hidden/notes.txt":  ErrHiddenFile,
+		"/css/../.env":             ErrHiddenFile,
+		"/css\\..\\..\\secret.txt": ErrOutsideRoot,
+		"/index.html\x00.js":       ErrOutsideRoot,
+	} {
+		if name, err := ConfinePath(root, requested); err != expected {
+			t.Errorf("%q: expected %v, got %s, %v", requested, expected, name, err)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package httpcache
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	// MaxEntries is the number of responses kept, 1000 by default; the least recently
+	// used ones are evicted first.
+	MaxEntries int
+	// MaxBytes is the total size of the bodies kept, 64MB by default.
+	MaxBytes int64
+	// MaxEntrySize is the size of the largest body kept, 1MB by default.
+	MaxEntrySize int
+	// TTL of the responses without max-age or s-maxage directive, 1 minute by default.
+	TTL time.Duration
+	// CacheAuthenticated caches the responses of the requests carrying credentials
+	// (Authorization header, access_token cookie or param), they must then not depend
+	// on the user or carry Vary: Authorization.
+	CacheAuthenticated bool
+}
+
+var defaultOptions = Options{MaxEntries: 1000, MaxBytes: 64 << 20, MaxEntrySize: 1 << 20, TTL: time.Minute}
+
+// Cache is an in-memory LRU cache of GET responses, shared by its middlewares.
+type Cache struct {
+	options Options
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// varies holds the Vary headers of the responses of each URL, and variants the
+	// number of responses stored for it
+	varies   map[string][]string
+	variants map[string]int
+	tags     map[string]map[string]bool
+	size     int64
+}
+
+type entry struct {
+	key        string
+	primary    string
+	statusCode int
+	header     http.Header
+	body       []byte
+	tags       []string
+	created    time.Time
+	expires    time.Time
+}
+
+func NewCache(opts ...Options) *Cache {
+	options := defaultOptions
+	if len(opts) > 0 {
+		options = opts[0]
+		if options.MaxEntries <= 0 {
+			options.MaxEntries = defaultOptions.MaxEntries
+		}
+		if options.MaxBytes <= 0 {
+			options.MaxBytes = defaultOptions.MaxBytes
+		}
+		if options.MaxEntrySize <= 0 {
+			options.MaxEntrySize = defaultOptions.MaxEntrySize
+		}
+		if options.TTL <= 0 {
+			options.TTL = defaultOptions.TTL
+		}
+	}
+	return &Cache{
+		options:  options,
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+		varies:   map[string][]string{},
+		variants: map[string]int{},
+		tags:     map[string]map[string]bool{},
+	}
+}
+
+func primaryKey(host string, requestURI string) string {
+	return strings.ToLower(host) + requestURI
+}
+
+// variantKey adds the values of the Vary headers of the request to its primary key.
+func variantKey(primary string, vary []string, requestHeader func(string) string) string {
+	key := primary
+	for _, name := range vary {
+		key += "\x00" + name + "=" + requestHeader(name)
+	}
+	return key
+}
+
+func (this *Cache) get(primary string, requestHeader func(string) string) *entry {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	vary, ok := this.varies[primary]
+	if !ok {
+		return nil
+	}
+	element, ok := this.entries[variantKey(primary, vary, requestHeader)]
+	if !ok {
+		return nil
+	}
+	e := element.Value.(*entry)
+	if time.Now().After(e.expires) {
+		this.remove(element)
+		return nil
+	}
+	this.lru.MoveToFront(element)
+	return e
+}
+
+// newEntry returns the entry of a response, or nil if it must not be stored.
+func (this *Cache) newEntry(statusCode int, header http.Header, body []byte) (*entry, []string) {
+	if statusCode != http.StatusOK || len(body) > this.options.MaxEntrySize || header.Get("Set-Cookie") != "" {
+		return nil, nil
+	}
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := cc[directive]; ok {
+			return nil, nil
+		}
+	}
+	ttl := this.options.TTL
+	if maxAge, ok := cc["s-maxage"]; ok {
+		ttl = parseSeconds(maxAge)
+	} else if maxAge, ok := cc["max-age"]; ok {
+		ttl = parseSeconds(maxAge)
+	}
+	if ttl <= 0 {
+		return nil, nil
+	}
+	var vary []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name == "*" {
+				return nil, nil
+			} else if name != "" {
+				vary = append(vary, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	var tags []string
+	for _, value := range header.Values(TagHeader) {
+		for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+			tags = append(tags, tag)
+		}
+	}
+	stored := http.Header{}
+	for name, values := range header {
+		switch name {
+		case TagHeader, "Date", "Content-Length", "Connection", "Transfer-Encoding", "X-Cache", "Age":
+			continue
+		}
+		stored[name] = append([]string(nil), values...)
+	}
+	now := time.Now()
+	return &entry{statusCode: statusCode, header: stored, body: append([]byte(nil), body...), tags: tags, created: now, expires: now.Add(ttl)}, vary
+}
+
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (this *Cache) set(primary string, requestHeader func(string) string, e *entry, vary []string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	// the variants stored with other Vary headers aren't reachable anymore, they're
+	// evicted as they age
+	e.primary = primary
+	e.key = variantKey(primary, vary, requestHeader)
+	if element, ok := this.entries[e.key]; ok {
+		this.remove(element)
+	}
+	this.varies[primary] = vary
+	this.variants[primary]++
+	this.entries[e.key] = this.lru.PushFront(e)
+	this.size += int64(len(e.body))
+	for _, tag := range e.tags {
+		if this.tags[tag] == nil {
+			this.tags[tag] = map[string]bool{}
+		}
+		this.tags[tag][e.key] = true
+	}
+	for this.lru.Len() > this.options.MaxEntries || this.size > this.options.MaxBytes {
+		this.remove(this.lru.Back())
+	}
+}
+
+// remove deletes an entry, the cache must be locked.
+func (this *Cache) remove(element *list.Element) {
+	e := this.lru.Remove(element).(*entry)
+	delete(this.entries, e.key)
+	this.size -= int64(len(e.body))
+	if this.variants[e.primary]--; this.variants[e.primary] <= 0 {
+		delete(this.variants, e.primary)
+		delete(this.varies, e.primary)
+	}
+	for _, tag := range e.tags {
+		delete(this.tags[tag], e.key)
+		if len(this.tags[tag]) == 0 {
+			delete(this.tags, tag)
+		}
+	}
+}
+
+// InvalidateTags removes the responses carrying one of the tags, it returns the
+// number of responses removed.
+func (this *Cache) InvalidateTags(tags ...string) int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	removed := 0
+	for _, tag := range tags {
+		for key := range this.tags[tag] {
+			if element, ok := this.entries[key]; ok {
+				this.remove(element)
+				removed++
+			}
+		}
+	}
+	return removed
+}
+
+// Invalidate removes all the variants of the responses of a URL, e.g.
+// Invalidate("example.com", "/posts?page=2").
+func (this *Cache) Invalidate(host string, requestURI string) int {
+	primary := primaryKey(host, requestURI)
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	removed := 0
+	for key, element := range this.entries {
+		if key == primary || strings.HasPrefix(key, primary+"\x00") {
+			this.remove(element)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Purge removes all the responses.
+func (this *Cache) Purge() {
+	this.mutex.Lock()
+	this.entries = map[string]*list.Element{}
+	this.lru.Init()
+	this.varies = map[string][]string{}
+	this.variants = map[string]int{}
+	this.tags = map[string]map[string]bool{}
+	this.size = 0
+	this.mutex.Unlock()
+}
+
+func (this *Cache) Len() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.lru.Len()
+}
+
+// age returns the value of the Age header of a cached response.
+func (this *entry) age() string {
+	return strconv.Itoa(int(time.Since(this.created) / time.Second))
+}
+
+// bypass tells if a request must skip the cache, and if it may still be stored.
+func (this *Cache) bypass(method string, requestHeader func(string) string, authenticated bool) (skip bool, store bool) {
+	if method != "GET" && method != "HEAD" {
+		return true, false
+	}
+	if authenticated && !this.options.CacheAuthenticated {
+		return true, false
+	}
+	cc := parseCacheControl(requestHeader("Cache-Control"))
+	if _, ok := cc["no-store"]; ok {
+		return true, false
+	}
+	if _, ok := cc["no-cache"]; ok {
+		return true, method == "GET"
+	}
+	return false, method == "GET"
+}
@@ -0,0 +1,127 @@
+package httpcache
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+// pageHandler answers the path, the language and the number of requests handled,
+// tagged with the path.
+func pageHandler(calls *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(calls, 1)
+		AddTags(w, "page:"+req.URL.Path)
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(req.URL.Path + " " + req.Header.Get("Accept-Language") + " " + strconv.Itoa(int(n))))
+	})
+}
+
+func fastHttpPageHandler(calls *int32) fasthttp.RequestHandler {
+	return func(requestCtx *fasthttp.RequestCtx) {
+		n := atomic.AddInt32(calls, 1)
+		FastHttpAddTags(requestCtx, "page:"+string(requestCtx.Path()))
+		requestCtx.Response.Header.Set("Vary", "Accept-Language")
+		requestCtx.WriteString(string(requestCtx.Path()) + " " + string(requestCtx.Request.Header.Peek("Accept-Language")) + " " + strconv.Itoa(int(n)))
+	}
+}
+
+var stacks = []string{"net/http", "fasthttp"}
+
+func newCacheClient(t *testing.T, stack string, cache *Cache) *pilltest.Client {
+	var calls int32
+	if stack == "fasthttp" {
+		return pilltest.NewFastHttp(t, cache.FastHttpMiddleware()(fastHttpPageHandler(&calls)))
+	}
+	return pilltest.New(t, cache.Middleware()(pageHandler(&calls)))
+}
+
+func get(client *pilltest.Client, path string, language string) *pilltest.Response {
+	return client.Get(path).Header("Accept-Language", language).Do().ExpectStatus(http.StatusOK)
+}
+
+func TestCacheVary(t *testing.T) {
+	for _, stack := range stacks {
+		t.Run(stack, func(t *testing.T) {
+			client := newCacheClient(t, stack, NewCache())
+			get(client, "/page", "en").ExpectHeader("X-Cache", "MISS").ExpectBodyContains("/page en 1").ExpectHeader(TagHeader, "")
+			get(client, "/page", "fr").ExpectHeader("X-Cache", "MISS").ExpectBodyContains("/page fr 2")
+			get(client, "/page", "en").ExpectHeader("X-Cache", "HIT").ExpectBodyContains("/page en 1").ExpectHeader("Vary", "Accept-Language").ExpectHeader("Age", "0")
+			get(client, "/page", "fr").ExpectHeader("X-Cache", "HIT").ExpectBodyContains("/page fr 2")
+			// the query string is part of the key
+			get(client, "/page?p=2", "en").ExpectHeader("X-Cache", "MISS")
+		})
+	}
+}
+
+func TestCacheLRU(t *testing.T) {
+	for _, stack := range stacks {
+		t.Run(stack, func(t *testing.T) {
+			cache := NewCache(Options{MaxEntries: 2})
+			client := newCacheClient(t, stack, cache)
+			get(client, "/a", "en").ExpectHeader("X-Cache", "MISS")
+			get(client, "/b", "en").ExpectHeader("X-Cache", "MISS")
+			get(client, "/a", "en").ExpectHeader("X-Cache", "HIT")
+			// /b is the least recently used
+			get(client, "/c", "en").ExpectHeader("X-Cache", "MISS")
+			if cache.Len() != 2 {
+				t.Fatalf("expected 2 entries, got %d", cache.Len())
+			}
+			get(client, "/a", "en").ExpectHeader("X-Cache", "HIT")
+			get(client, "/c", "en").ExpectHeader("X-Cache", "HIT")
+			get(client, "/b", "en").ExpectHeader("X-Cache", "MISS")
+			for i := 0; i < 50; i++ {
+				get(client, "/pages/"+strconv.Itoa(i), "en")
+			}
+			cache.mutex.Lock()
+			varies, variants := len(cache.varies), len(cache.variants)
+			cache.mutex.Unlock()
+			if varies != 2 || variants != 2 {
+				t.Errorf("the Vary headers of the evicted URLs should be forgotten, got %d and %d", varies, variants)
+			}
+		})
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	for _, stack := range stacks {
+		t.Run(stack, func(t *testing.T) {
+			cache := NewCache()
+			client := newCacheClient(t, stack, cache)
+			get(client, "/posts/1", "en")
+			get(client, "/posts/1", "fr")
+			get(client, "/posts/2", "en")
+			if removed := cache.InvalidateTags("page:/posts/1", "page:/unknown"); removed != 2 {
+				t.Errorf("expected the 2 variants of /posts/1 to be removed, got %d", removed)
+			}
+			get(client, "/posts/1", "en").ExpectHeader("X-Cache", "MISS")
+			get(client, "/posts/2", "en").ExpectHeader("X-Cache", "HIT")
+			if removed := cache.Invalidate("example.com", "/posts/2"); removed != 1 {
+				t.Errorf("expected /posts/2 to be removed, got %d", removed)
+			}
+			get(client, "/posts/2", "en").ExpectHeader("X-Cache", "MISS")
+			cache.Purge()
+			if cache.Len() != 0 || len(cache.tags) != 0 || len(cache.varies) != 0 {
+				t.Errorf("the cache should be empty, got %d entries", cache.Len())
+			}
+		})
+	}
+}
+
+func TestCacheAuthenticated(t *testing.T) {
+	for _, stack := range stacks {
+		t.Run(stack, func(t *testing.T) {
+			client := newCacheClient(t, stack, NewCache())
+			get(client, "/me?access_token=abc", "en").ExpectHeader("X-Cache", "")
+			client.Get("/me").Header("Authorization", "Bearer abc").Do().ExpectHeader("X-Cache", "")
+			client.WithToken("abc")
+			get(client, "/me", "en").ExpectHeader("X-Cache", "")
+			client.Logout()
+			get(client, "/me", "en").ExpectHeader("X-Cache", "MISS")
+		})
+	}
+}
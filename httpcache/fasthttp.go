@@ -0,0 +1,130 @@
+package httpcache
+
+import (
+	"net/http"
+
+	"github.com/nehmeroumani/fastchain"
+	"github.com/nehmeroumani/pill.go/auth"
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/valyala/fasthttp"
+)
+
+// FastHttpAddTags is the fasthttp version of AddTags.
+func FastHttpAddTags(requestCtx *fasthttp.RequestCtx, tags ...string) {
+	for _, tag := range tags {
+		requestCtx.Response.Header.Add(TagHeader, tag)
+	}
+}
+
+// FastHttpETag is the fasthttp version of ETag.
+func FastHttpETag(opts ...ETagOptions) fastchain.Constructor {
+	options := getETagOptions(opts)
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			h(requestCtx)
+			if !requestCtx.IsGet() && !requestCtx.IsHead() {
+				return
+			}
+			resp := &requestCtx.Response
+			if resp.IsBodyStream() || resp.StatusCode() != fasthttp.StatusOK || len(resp.Body()) > options.MaxSize {
+				return
+			}
+			if len(resp.Header.Peek("ETag")) == 0 {
+				resp.Header.Set("ETag", ComputeETag(resp.Body(), options.Weak))
+			}
+			fastHttpConditional(requestCtx)
+		}
+	}
+}
+
+// FastHttpMiddleware is the fasthttp version of Middleware.
+func (this *Cache) FastHttpMiddleware() fastchain.Constructor {
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			requestHeader := func(name string) string {
+				return string(requestCtx.Request.Header.Peek(name))
+			}
+			authenticated := len(requestCtx.Request.Header.Peek("Authorization")) > 0 || auth.FastHttpRequestToken(requestCtx) != ""
+			skip, store := this.bypass(string(requestCtx.Method()), requestHeader, authenticated)
+			primary := primaryKey(string(requestCtx.Host()), string(requestCtx.RequestURI()))
+			if !skip {
+				if e := this.get(primary, requestHeader); e != nil {
+					resp := &requestCtx.Response
+					for name, values := range e.header {
+						resp.Header.Del(name)
+						for _, value := range values {
+							resp.Header.Add(name, value)
+						}
+					}
+					resp.Header.Set("Age", e.age())
+					resp.Header.Set("X-Cache", "HIT")
+					resp.SetStatusCode(e.statusCode)
+					resp.SetBody(e.body)
+					fastHttpConditional(requestCtx)
+					return
+				}
+			}
+			if !store {
+				h(requestCtx)
+				return
+			}
+			// the handler gets an unconditional request, the conditions are evaluated
+			// on its response
+			conditions := map[string]string{}
+			for _, name := range conditionalHeaders {
+				if value := requestHeader(name); value != "" {
+					conditions[name] = value
+					requestCtx.Request.Header.Del(name)
+				}
+			}
+			h(requestCtx)
+			for name, value := range conditions {
+				requestCtx.Request.Header.Set(name, value)
+			}
+			resp := &requestCtx.Response
+			if resp.IsBodyStream() {
+				return
+			}
+			header := http.Header{}
+			resp.Header.VisitAll(func(key, value []byte) {
+				header.Add(string(key), string(value))
+			})
+			if e, vary := this.newEntry(resp.StatusCode(), header, resp.Body()); e != nil {
+				this.set(primary, requestHeader, e, vary)
+				resp.Header.Set("X-Cache", "MISS")
+			}
+			resp.Header.Del(TagHeader)
+			fastHttpConditional(requestCtx)
+		}
+	}
+}
+
+// fastHttpConditional turns a 200 response into a 304 or a 412 if the conditions of
+// the request say so.
+func fastHttpConditional(requestCtx *fasthttp.RequestCtx) {
+	resp := &requestCtx.Response
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return
+	}
+	requestHeader := func(name string) string {
+		return string(requestCtx.Request.Header.Peek(name))
+	}
+	etag := string(resp.Header.Peek("ETag"))
+	lastModified := string(resp.Header.Peek("Last-Modified"))
+	switch helpers.ConditionalStatus(helpers.BytesToString(requestCtx.Method()), requestHeader, etag, lastModified) {
+	case http.StatusNotModified:
+		var removed []string
+		resp.Header.VisitAll(func(key, value []byte) {
+			if name := http.CanonicalHeaderKey(string(key)); !notModifiedHeaders[name] && name != "Content-Type" {
+				removed = append(removed, name)
+			}
+		})
+		for _, name := range removed {
+			resp.Header.Del(name)
+		}
+		resp.ResetBody()
+		resp.SetStatusCode(fasthttp.StatusNotModified)
+	case http.StatusPreconditionFailed:
+		requestCtx.Error(fasthttp.StatusMessage(fasthttp.StatusPreconditionFailed), fasthttp.StatusPreconditionFailed)
+	}
+}
@@ -0,0 +1,181 @@
+package httpcache
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/pill.go/auth"
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+// AddTags tags the response, to invalidate it with Cache.InvalidateTags.
+func AddTags(w http.ResponseWriter, tags ...string) {
+	for _, tag := range tags {
+		w.Header().Add(TagHeader, tag)
+	}
+}
+
+// ETag sets the ETag of the 200 responses to GET and HEAD requests which have none,
+// and answers their If-None-Match and If-Match headers with 304 and 412.
+func ETag(opts ...ETagOptions) alice.Constructor {
+	options := getETagOptions(opts)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != "GET" && req.Method != "HEAD" {
+				h.ServeHTTP(w, req)
+				return
+			}
+			buffer := newBufferedWriter(w, options.MaxSize)
+			h.ServeHTTP(buffer, req)
+			if buffer.passthrough {
+				return
+			}
+			if buffer.status() == http.StatusOK && w.Header().Get("ETag") == "" {
+				w.Header().Set("ETag", ComputeETag(buffer.body.Bytes(), options.Weak))
+			}
+			writeResponse(w, req, buffer.status(), buffer.body.Bytes())
+		})
+	}
+}
+
+// Middleware serves the cached responses and stores the cacheable ones, it must be
+// used before the middlewares adding per-request headers to be cached, e.g.
+// m.Use(cache.Middleware(), httpcache.ETag()).
+func (this *Cache) Middleware() alice.Constructor {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			skip, store := this.bypass(req.Method, req.Header.Get, isAuthenticated(req))
+			primary := primaryKey(req.Host, req.URL.RequestURI())
+			if !skip {
+				if e := this.get(primary, req.Header.Get); e != nil {
+					for name, values := range e.header {
+						w.Header()[name] = append([]string(nil), values...)
+					}
+					w.Header().Set("Age", e.age())
+					w.Header().Set("X-Cache", "HIT")
+					writeResponse(w, req, e.statusCode, e.body)
+					return
+				}
+			}
+			if !store {
+				h.ServeHTTP(w, req)
+				return
+			}
+			// the handler gets an unconditional request, the conditions are evaluated
+			// on its response
+			forwarded := req.Clone(req.Context())
+			for _, name := range conditionalHeaders {
+				forwarded.Header.Del(name)
+			}
+			buffer := newBufferedWriter(w, this.options.MaxEntrySize)
+			h.ServeHTTP(buffer, forwarded)
+			if buffer.passthrough {
+				return
+			}
+			if e, vary := this.newEntry(buffer.status(), w.Header(), buffer.body.Bytes()); e != nil {
+				this.set(primary, req.Header.Get, e, vary)
+				w.Header().Set("X-Cache", "MISS")
+			}
+			w.Header().Del(TagHeader)
+			writeResponse(w, req, buffer.status(), buffer.body.Bytes())
+		})
+	}
+}
+
+func isAuthenticated(req *http.Request) bool {
+	if req.Header.Get("Authorization") != "" {
+		return true
+	}
+	return auth.RequestToken(req) != ""
+}
+
+// writeResponse sends a buffered response, or a 304 or 412 if the conditions of the
+// request say so.
+func writeResponse(w http.ResponseWriter, req *http.Request, statusCode int, body []byte) {
+	header := w.Header()
+	if statusCode == http.StatusOK {
+		if conditional := helpers.ConditionalStatus(req.Method, req.Header.Get, header.Get("ETag"), header.Get("Last-Modified")); conditional == http.StatusNotModified {
+			for name := range header {
+				if !notModifiedHeaders[name] {
+					delete(header, name)
+				}
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return
+		} else if conditional == http.StatusPreconditionFailed {
+			http.Error(w, http.StatusText(conditional), conditional)
+			return
+		}
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// bufferedWriter holds the response until the handler returns. Responses over limit
+// and flushed ones are written through.
+type bufferedWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	body        bytes.Buffer
+	limit       int
+	passthrough bool
+}
+
+func newBufferedWriter(w http.ResponseWriter, limit int) *bufferedWriter {
+	return &bufferedWriter{ResponseWriter: w, limit: limit}
+}
+
+func (this *bufferedWriter) status() int {
+	if this.statusCode == 0 {
+		return http.StatusOK
+	}
+	return this.statusCode
+}
+
+func (this *bufferedWriter) WriteHeader(statusCode int) {
+	if this.passthrough {
+		this.ResponseWriter.WriteHeader(statusCode)
+	} else if this.statusCode == 0 {
+		this.statusCode = statusCode
+	}
+}
+
+func (this *bufferedWriter) Write(data []byte) (int, error) {
+	if this.statusCode == 0 {
+		this.statusCode = http.StatusOK
+	}
+	if !this.passthrough && this.body.Len()+len(data) > this.limit {
+		this.startPassthrough()
+	}
+	if this.passthrough {
+		return this.ResponseWriter.Write(data)
+	}
+	return this.body.Write(data)
+}
+
+func (this *bufferedWriter) startPassthrough() {
+	this.passthrough = true
+	this.Header().Del(TagHeader)
+	this.ResponseWriter.WriteHeader(this.status())
+	if this.body.Len() > 0 {
+		this.ResponseWriter.Write(this.body.Bytes())
+		this.body.Reset()
+	}
+}
+
+func (this *bufferedWriter) Flush() {
+	if !this.passthrough {
+		this.startPassthrough()
+	}
+	if flusher, ok := this.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer.
+func (this *bufferedWriter) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
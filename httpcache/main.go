@@ -0,0 +1,107 @@
+// Package httpcache adds ETags and conditional requests to the dynamic responses, and
+// caches whole GET responses in memory.
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+// TagHeader carries the tags of a response, used to invalidate the cached responses
+// by tag; the cache middlewares remove it from the responses they may store.
+const TagHeader = "Cache-Tag"
+
+// conditionalHeaders are removed from the requests forwarded to the handlers by the
+// cache, so it gets full responses to store.
+var conditionalHeaders = []string{"If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"}
+
+type ETagOptions struct {
+	// Weak makes the computed ETags weak, for responses whose bytes may change
+	// without their meaning changing, e.g. compressed ones.
+	Weak bool
+	// MaxSize is the size of the largest response buffered to compute its ETag,
+	// 4MB by default; larger responses are sent without one.
+	MaxSize int
+}
+
+var defaultETagOptions = ETagOptions{MaxSize: 4 << 20}
+
+func getETagOptions(opts []ETagOptions) ETagOptions {
+	options := defaultETagOptions
+	if len(opts) > 0 {
+		options = opts[0]
+		if options.MaxSize <= 0 {
+			options.MaxSize = defaultETagOptions.MaxSize
+		}
+	}
+	return options
+}
+
+// ComputeETag returns the ETag of body.
+func ComputeETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// MatchETag tells if etag is in the list of an If-Match or If-None-Match header, with
+// the strong comparison (weak ETags never match) or the weak one. "*" matches any
+// current representation, even one without an ETag.
+func MatchETag(header string, etag string, weak bool) bool {
+	return helpers.MatchETag(header, etag, weak)
+}
+
+// CheckPreconditions evaluates the If-Match and If-None-Match headers of a request
+// against the current ETag of the resource, typically before updating it. It answers
+// 412 (or 304 for GET and HEAD requests) and returns false if the request must not
+// be handled.
+func CheckPreconditions(w http.ResponseWriter, req *http.Request, etag string) bool {
+	statusCode := helpers.ConditionalStatus(req.Method, req.Header.Get, etag, "")
+	if statusCode == 0 {
+		return true
+	}
+	if statusCode == http.StatusNotModified {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(statusCode)
+	} else {
+		http.Error(w, http.StatusText(statusCode), statusCode)
+	}
+	return false
+}
+
+// parseCacheControl returns the directives of a Cache-Control header.
+func parseCacheControl(header string) map[string]string {
+	directives := map[string]string{}
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+		name, value := directive, ""
+		if i := strings.IndexByte(directive, '='); i != -1 {
+			name, value = directive[:i], strings.Trim(directive[i+1:], `"`)
+		}
+		directives[strings.ToLower(name)] = value
+	}
+	return directives
+}
+
+// notModifiedHeaders are the headers kept on 304 responses.
+var notModifiedHeaders = map[string]bool{
+	"Cache-Control":    true,
+	"Content-Location": true,
+	"Date":             true,
+	"Etag":             true,
+	"Expires":          true,
+	"Last-Modified":    true,
+	"Vary":             true,
+	"Age":              true,
+	"X-Cache":          true,
+}
@@ -0,0 +1,26 @@
+package idempotency
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/nehmeroumani/pill.go/auth"
+	"github.com/valyala/fasthttp"
+)
+
+// ByUser scopes the keys per user, the subject of the auth access token of the
+// request. Anonymous requests have no scope and aren't deduplicated.
+func ByUser(req *http.Request) string {
+	return userScope(auth.RequestToken(req))
+}
+
+func FastHttpByUser(requestCtx *fasthttp.RequestCtx) string {
+	return userScope(auth.FastHttpRequestToken(requestCtx))
+}
+
+func userScope(token string) string {
+	if ok, userID, _ := auth.IsAuthenticated(token); ok {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return ""
+}
@@ -0,0 +1,387 @@
+// Package idempotency makes the retries of unsafe requests carrying an Idempotency-Key
+// header safe, by replaying the response of the first request instead of handling
+// them again.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/fastchain"
+	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	DefaultHeader = "Idempotency-Key"
+	// ReplayedHeader is set on the replayed responses.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Response is a stored response, replayed to the repeated requests.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+type Record struct {
+	// Fingerprint identifies the method, URI and body of the first request.
+	Fingerprint string
+	// Response is nil while the first request is in flight.
+	Response *Response
+}
+
+// Store keeps the records of the idempotency keys.
+type Store interface {
+	// Lock records key as in flight and returns nil if it's unknown or expired,
+	// otherwise it returns its record.
+	Lock(key string, fingerprint string, ttl time.Duration) (*Record, error)
+	// Save stores the record of key once its response is known.
+	Save(key string, record Record, ttl time.Duration) error
+	// Unlock forgets an in-flight key, so the request can be retried.
+	Unlock(key string) error
+}
+
+type Options struct {
+	// Store defaults to a memory store shared by the middlewares of the process.
+	Store Store
+	// Prefix separates the keys of the middlewares sharing a store.
+	Prefix string
+	// Header carrying the key, Idempotency-Key by default.
+	Header string
+	// Methods handled by the middleware, POST and PATCH by default.
+	Methods []string
+	// TTL is how long the responses are replayed, 24 hours by default.
+	TTL time.Duration
+	// LockTTL is how long a key stays in flight if its response is never saved, e.g.
+	// when the process dies, 1 minute by default.
+	LockTTL time.Duration
+	// ScopeFunc returns the scope of the keys of a request, it defaults to ByUser.
+	// Requests with an empty scope aren't deduplicated.
+	ScopeFunc         func(*http.Request) string
+	FastHttpScopeFunc func(*fasthttp.RequestCtx) string
+	// Required rejects the requests without key with a 400.
+	Required bool
+	// MaxBodySize is the largest body of the requests with a key, whose body is read to
+	// be fingerprinted; it defaults to 10MB and the larger bodies are refused with a 413.
+	MaxBodySize int64
+}
+
+var (
+	defaultStore     Store
+	defaultStoreOnce sync.Once
+)
+
+func (this *Options) setDefaults() {
+	if this.Store == nil {
+		defaultStoreOnce.Do(func() {
+			defaultStore = NewMemoryStore()
+		})
+		this.Store = defaultStore
+	}
+	if this.Header == "" {
+		this.Header = DefaultHeader
+	}
+	if len(this.Methods) == 0 {
+		this.Methods = []string{"POST", "PATCH"}
+	}
+	if this.TTL <= 0 {
+		this.TTL = 24 * time.Hour
+	}
+	if this.LockTTL <= 0 {
+		this.LockTTL = time.Minute
+	}
+	if this.MaxBodySize <= 0 {
+		this.MaxBodySize = 10 << 20
+	}
+}
+
+func (this *Options) handles(method string) bool {
+	for _, m := range this.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// outcome is what the middleware does with a request carrying a key.
+type outcome int
+
+const (
+	handle outcome = iota
+	replay
+	inFlight
+	mismatch
+)
+
+// lock returns what to do with a request and the record to replay.
+func (this *Options) lock(key string, fingerprint string) (outcome, *Record) {
+	record, err := this.Store.Lock(key, fingerprint, this.LockTTL)
+	if err != nil {
+		// without store the request is handled as if it had no key
+		clean.Error(err)
+		return handle, nil
+	}
+	switch {
+	case record == nil:
+		return handle, nil
+	case record.Fingerprint != fingerprint:
+		return mismatch, record
+	case record.Response == nil:
+		return inFlight, record
+	}
+	return replay, record
+}
+
+// finish saves the response of a handled request, or unlocks its key when it failed
+// on the server side so it can be retried.
+func (this *Options) finish(key string, fingerprint string, response *Response) {
+	var err error
+	if response == nil || response.StatusCode >= 500 {
+		err = this.Store.Unlock(key)
+	} else {
+		err = this.Store.Save(key, Record{Fingerprint: fingerprint, Response: response}, this.TTL)
+	}
+	if err != nil {
+		clean.Error(err)
+	}
+}
+
+func fingerprint(method string, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + uri + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// storedHeader tells if a response header is replayed.
+func storedHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Set-Cookie", "Date", "Content-Length", "Connection", "Transfer-Encoding":
+		return false
+	}
+	return true
+}
+
+func Middleware(options Options) alice.Constructor {
+	options.setDefaults()
+	if options.ScopeFunc == nil {
+		options.ScopeFunc = ByUser
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !options.handles(req.Method) {
+				h.ServeHTTP(w, req)
+				return
+			}
+			key := req.Header.Get(options.Header)
+			if key == "" {
+				if options.Required {
+					http.Error(w, options.Header+" header required", http.StatusBadRequest)
+				} else {
+					h.ServeHTTP(w, req)
+				}
+				return
+			}
+			if len(key) > maxKeyLength {
+				http.Error(w, options.Header+" header too long", http.StatusBadRequest)
+				return
+			}
+			if req.ContentLength > options.MaxBodySize {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			// the body is read before the scope, which may parse the form
+			body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, options.MaxBodySize))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				} else {
+					http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				}
+				return
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			scope := options.ScopeFunc(req)
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			if scope == "" {
+				h.ServeHTTP(w, req)
+				return
+			}
+			key = options.Prefix + scope + ":" + key
+			fp := fingerprint(req.Method, req.URL.RequestURI(), body)
+
+			outcome, record := options.lock(key, fp)
+			switch outcome {
+			case mismatch:
+				http.Error(w, options.Header+" already used by another request", http.StatusUnprocessableEntity)
+			case inFlight:
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "a request with the same "+options.Header+" is in progress", http.StatusConflict)
+			case replay:
+				for name, values := range record.Response.Header {
+					w.Header()[name] = append([]string(nil), values...)
+				}
+				w.Header().Set(ReplayedHeader, "true")
+				w.Header().Set("Content-Length", strconv.Itoa(len(record.Response.Body)))
+				w.WriteHeader(record.Response.StatusCode)
+				w.Write(record.Response.Body)
+			default:
+				recorder := &responseRecorder{ResponseWriter: w}
+				completed := false
+				defer func() {
+					if completed {
+						options.finish(key, fp, recorder.response())
+					} else {
+						// the handler panicked
+						options.finish(key, fp, nil)
+					}
+				}()
+				h.ServeHTTP(recorder, req)
+				completed = true
+			}
+		})
+	}
+}
+
+// responseRecorder keeps a copy of the response written to the client.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	header     http.Header
+	body       bytes.Buffer
+}
+
+func (this *responseRecorder) WriteHeader(statusCode int) {
+	if this.statusCode == 0 {
+		this.statusCode = statusCode
+		this.header = http.Header{}
+		for name, values := range this.ResponseWriter.Header() {
+			if storedHeader(name) {
+				this.header[name] = append([]string(nil), values...)
+			}
+		}
+	}
+	this.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (this *responseRecorder) Write(data []byte) (int, error) {
+	if this.statusCode == 0 {
+		this.WriteHeader(http.StatusOK)
+	}
+	this.body.Write(data)
+	return this.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer.
+func (this *responseRecorder) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
+
+func (this *responseRecorder) response() *Response {
+	if this.statusCode == 0 {
+		// nothing written, net/http sends an empty 200
+		this.WriteHeader(http.StatusOK)
+	}
+	return &Response{StatusCode: this.statusCode, Header: this.header, Body: this.body.Bytes()}
+}
+
+func FastHttpMiddleware(options Options) fastchain.Constructor {
+	options.setDefaults()
+	if options.FastHttpScopeFunc == nil {
+		options.FastHttpScopeFunc = FastHttpByUser
+	}
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			if !options.handles(string(requestCtx.Method())) {
+				h(requestCtx)
+				return
+			}
+			key := string(requestCtx.Request.Header.Peek(options.Header))
+			if key == "" {
+				if options.Required {
+					requestCtx.Error(options.Header+" header required", fasthttp.StatusBadRequest)
+				} else {
+					h(requestCtx)
+				}
+				return
+			}
+			if len(key) > maxKeyLength {
+				requestCtx.Error(options.Header+" header too long", fasthttp.StatusBadRequest)
+				return
+			}
+			scope := options.FastHttpScopeFunc(requestCtx)
+			if scope == "" {
+				h(requestCtx)
+				return
+			}
+			body := requestCtx.PostBody()
+			if int64(len(body)) > options.MaxBodySize {
+				requestCtx.Error(fasthttp.StatusMessage(fasthttp.StatusRequestEntityTooLarge), fasthttp.StatusRequestEntityTooLarge)
+				return
+			}
+			key = options.Prefix + scope + ":" + key
+			fp := fingerprint(string(requestCtx.Method()), string(requestCtx.RequestURI()), body)
+
+			outcome, record := options.lock(key, fp)
+			switch outcome {
+			case mismatch:
+				requestCtx.Error(options.Header+" already used by another request", fasthttp.StatusUnprocessableEntity)
+			case inFlight:
+				requestCtx.Response.Header.Set("Retry-After", "1")
+				requestCtx.Error("a request with the same "+options.Header+" is in progress", fasthttp.StatusConflict)
+			case replay:
+				for name, values := range record.Response.Header {
+					for i, value := range values {
+						if i == 0 {
+							requestCtx.Response.Header.Set(name, value)
+						} else {
+							requestCtx.Response.Header.Add(name, value)
+						}
+					}
+				}
+				requestCtx.Response.Header.Set(ReplayedHeader, "true")
+				requestCtx.SetStatusCode(record.Response.StatusCode)
+				requestCtx.SetBody(record.Response.Body)
+			default:
+				completed := false
+				defer func() {
+					if !completed {
+						// the handler panicked
+						options.finish(key, fp, nil)
+					}
+				}()
+				h(requestCtx)
+				completed = true
+				options.finish(key, fp, fastHttpResponse(&requestCtx.Response))
+			}
+		}
+	}
+}
+
+// fastHttpResponse copies a fasthttp response, streamed responses can't be stored.
+func fastHttpResponse(resp *fasthttp.Response) *Response {
+	if resp.IsBodyStream() {
+		return nil
+	}
+	response := &Response{StatusCode: resp.StatusCode(), Header: http.Header{}, Body: append([]byte(nil), resp.Body()...)}
+	resp.Header.VisitAll(func(key, value []byte) {
+		if name := string(key); storedHeader(name) {
+			response.Header.Add(name, string(value))
+		}
+	})
+	return response
+}
@@ -0,0 +1,156 @@
+package idempotency
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+// testOptions scopes the keys per tenant param, which is read from the form.
+func testOptions(t *testing.T) Options {
+	store := NewMemoryStore()
+	t.Cleanup(store.Close)
+	return Options{
+		Store: store,
+		ScopeFunc: func(req *http.Request) string {
+			return req.FormValue("tenant")
+		},
+		FastHttpScopeFunc: func(requestCtx *fasthttp.RequestCtx) string {
+			return string(requestCtx.FormValue("tenant"))
+		},
+	}
+}
+
+// counterHandler answers 201 with the number of requests handled and their body.
+func counterHandler(calls *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		n := atomic.AddInt32(calls, 1)
+		w.Header().Set("X-Call", strconv.Itoa(int(n)))
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s" + strconv.Itoa(int(n))})
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("call " + strconv.Itoa(int(n)) + ": " + string(body)))
+	})
+}
+
+func fastHttpCounterHandler(calls *int32) fasthttp.RequestHandler {
+	return func(requestCtx *fasthttp.RequestCtx) {
+		n := atomic.AddInt32(calls, 1)
+		requestCtx.Response.Header.Set("X-Call", strconv.Itoa(int(n)))
+		requestCtx.SetStatusCode(fasthttp.StatusCreated)
+		requestCtx.WriteString("call " + strconv.Itoa(int(n)) + ": " + string(requestCtx.PostBody()))
+	}
+}
+
+func postForm(client *pilltest.Client, path string, key string, form string) *pilltest.Response {
+	return client.Post(path).Header(DefaultHeader, key).Body("application/x-www-form-urlencoded", []byte(form)).Do()
+}
+
+func TestReplay(t *testing.T) {
+	var calls int32
+	client := pilltest.New(t, Middleware(testOptions(t))(counterHandler(&calls)))
+	postForm(client, "/orders", "k1", "tenant=a&item=1").
+		ExpectStatus(http.StatusCreated).
+		ExpectHeader(ReplayedHeader, "").
+		// the form parsed by the scope is still readable by the handler
+		ExpectBodyContains("call 1: tenant=a&item=1").
+		ExpectCookie("session")
+	response := postForm(client, "/orders", "k1", "tenant=a&item=1").
+		ExpectStatus(http.StatusCreated).
+		ExpectHeader(ReplayedHeader, "true").
+		ExpectHeader("X-Call", "1").
+		ExpectBodyContains("call 1: tenant=a&item=1")
+	if response.Header.Get("Set-Cookie") != "" {
+		t.Error("the cookies shouldn't be replayed")
+	}
+	// the keys are scoped, and the requests without scope or key aren't deduplicated
+	postForm(client, "/orders", "k1", "tenant=b&item=1").ExpectHeader("X-Call", "2")
+	postForm(client, "/orders", "k1", "item=1").ExpectHeader("X-Call", "3")
+	postForm(client, "/orders", "k1", "item=1").ExpectHeader("X-Call", "4")
+	client.Post("/orders").Form(map[string][]string{"tenant": {"a"}, "item": {"1"}}).Do().ExpectHeader("X-Call", "5")
+	client.Put("/orders").Header(DefaultHeader, "k1").Body("application/x-www-form-urlencoded", []byte("tenant=a&item=1")).Do().ExpectHeader("X-Call", "6")
+}
+
+func TestMismatch(t *testing.T) {
+	var calls int32
+	client := pilltest.New(t, Middleware(testOptions(t))(counterHandler(&calls)))
+	postForm(client, "/orders", "k1", "tenant=a&item=1").ExpectStatus(http.StatusCreated)
+	postForm(client, "/orders", "k1", "tenant=a&item=2").ExpectStatus(http.StatusUnprocessableEntity)
+	postForm(client, "/orders?dry_run=1", "k1", "tenant=a&item=1").ExpectStatus(http.StatusUnprocessableEntity)
+	postForm(client, "/carts", "k1", "tenant=a&item=1").ExpectStatus(http.StatusUnprocessableEntity)
+	if calls != 1 {
+		t.Errorf("the mismatching requests shouldn't be handled, got %d calls", calls)
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	var calls int32
+	started, release := make(chan bool), make(chan bool)
+	handler := counterHandler(&calls)
+	client := pilltest.New(t, Middleware(testOptions(t))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		started <- true
+		<-release
+		handler.ServeHTTP(w, req)
+	})))
+	done := make(chan *pilltest.Response)
+	go func() {
+		done <- postForm(client, "/orders", "k1", "tenant=a")
+	}()
+	<-started
+	postForm(client, "/orders", "k1", "tenant=a").ExpectStatus(http.StatusConflict).ExpectHeader("Retry-After", "1")
+	close(release)
+	(<-done).ExpectStatus(http.StatusCreated)
+	postForm(client, "/orders", "k1", "tenant=a").ExpectHeader(ReplayedHeader, "true")
+}
+
+func TestServerErrorsAreRetried(t *testing.T) {
+	var calls int32
+	client := pilltest.New(t, Middleware(testOptions(t))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})))
+	postForm(client, "/orders", "k1", "tenant=a").ExpectStatus(http.StatusServiceUnavailable)
+	postForm(client, "/orders", "k1", "tenant=a").ExpectStatus(http.StatusCreated).ExpectHeader(ReplayedHeader, "")
+	postForm(client, "/orders", "k1", "tenant=a").ExpectStatus(http.StatusCreated).ExpectHeader(ReplayedHeader, "true")
+}
+
+func TestInvalidKeys(t *testing.T) {
+	var calls int32
+	options := testOptions(t)
+	options.Required = true
+	options.MaxBodySize = 32
+	client := pilltest.New(t, Middleware(options)(counterHandler(&calls)))
+	client.Post("/orders").Form(map[string][]string{"tenant": {"a"}}).Do().ExpectStatus(http.StatusBadRequest)
+	postForm(client, "/orders", strings.Repeat("k", maxKeyLength+1), "tenant=a").ExpectStatus(http.StatusBadRequest)
+	postForm(client, "/orders", "k1", "tenant=a&item="+strings.Repeat("x", 32)).ExpectStatus(http.StatusRequestEntityTooLarge)
+	if calls != 0 {
+		t.Errorf("the invalid requests shouldn't be handled, got %d calls", calls)
+	}
+}
+
+func TestFastHttpMiddleware(t *testing.T) {
+	var calls int32
+	options := testOptions(t)
+	options.MaxBodySize = 32
+	client := pilltest.NewFastHttp(t, FastHttpMiddleware(options)(fastHttpCounterHandler(&calls)))
+	postForm(client, "/orders", "k1", "tenant=a&item=1").ExpectStatus(http.StatusCreated).ExpectBodyContains("call 1: tenant=a&item=1")
+	postForm(client, "/orders", "k1", "tenant=a&item=1").
+		ExpectStatus(http.StatusCreated).
+		ExpectHeader(ReplayedHeader, "true").
+		ExpectHeader("X-Call", "1").
+		ExpectBodyContains("call 1: tenant=a&item=1")
+	postForm(client, "/orders?dry_run=1", "k1", "tenant=a&item=1").ExpectStatus(http.StatusUnprocessableEntity)
+	postForm(client, "/orders", "k2", "tenant=a&item="+strings.Repeat("x", 32)).ExpectStatus(http.StatusRequestEntityTooLarge)
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
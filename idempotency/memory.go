@@ -0,0 +1,98 @@
+package idempotency
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const memoryStoreShards = 64
+
+// MemoryStore keeps the records in memory, split into shards to reduce the lock
+// contention. Expired records are removed every minute.
+type MemoryStore struct {
+	shards [memoryStoreShards]memoryShard
+	stop   chan bool
+}
+
+type memoryShard struct {
+	sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	record    Record
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{stop: make(chan bool)}
+	for i := range store.shards {
+		store.shards[i].entries = map[string]*memoryEntry{}
+	}
+	go store.cleanup(time.Minute)
+	return store
+}
+
+func (this *MemoryStore) Lock(key string, fingerprint string, ttl time.Duration) (*Record, error) {
+	shard := this.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	now := time.Now()
+	if entry, ok := shard.entries[key]; ok && now.Before(entry.expiresAt) {
+		record := entry.record
+		return &record, nil
+	}
+	shard.entries[key] = &memoryEntry{record: Record{Fingerprint: fingerprint}, expiresAt: now.Add(ttl)}
+	return nil, nil
+}
+
+func (this *MemoryStore) Save(key string, record Record, ttl time.Duration) error {
+	shard := this.shard(key)
+	shard.Lock()
+	shard.entries[key] = &memoryEntry{record: record, expiresAt: time.Now().Add(ttl)}
+	shard.Unlock()
+	return nil
+}
+
+func (this *MemoryStore) Unlock(key string) error {
+	shard := this.shard(key)
+	shard.Lock()
+	if entry, ok := shard.entries[key]; ok && entry.record.Response == nil {
+		delete(shard.entries, key)
+	}
+	shard.Unlock()
+	return nil
+}
+
+func (this *MemoryStore) Close() {
+	close(this.stop)
+}
+
+func (this *MemoryStore) shard(key string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &this.shards[h.Sum32()%memoryStoreShards]
+}
+
+func (this *MemoryStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-this.stop:
+			return
+		case now := <-ticker.C:
+			for i := range this.shards {
+				shard := &this.shards[i]
+				shard.Lock()
+				for key, entry := range shard.entries {
+					if now.After(entry.expiresAt) {
+						delete(shard.entries, key)
+					}
+				}
+				shard.Unlock()
+			}
+		}
+	}
+}
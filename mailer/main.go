@@ -2,13 +2,19 @@ package mailer
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
+	"net"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/nehmeroumani/pill.go/health"
 	"github.com/nehmeroumani/pill.go/templates"
+	"github.com/nehmeroumani/pill.go/tracing"
 	"github.com/parnurzeal/gorequest"
 
 	gomail "gopkg.in/gomail.v2"
@@ -22,7 +28,34 @@ var password string
 var senderName string
 var attachFromURL bool
 
-var ch = make(chan *gomail.Message)
+var ch = make(chan *queuedMessage)
+
+// queuedMessage carries the span of the Send call to the sending goroutine.
+type queuedMessage struct {
+	message *gomail.Message
+	span    *tracing.Span
+}
+
+var flush = make(chan chan struct{})
+
+// running is set by Init, the sending goroutine never stops once started.
+var running int32
+
+var errNotRunning = errors.New("mailer: Init wasn't called, the email is dropped")
+
+// counters read by Stats
+var queued, sent, failed int64
+
+type Stats struct {
+	// Queued is the number of emails handed to Send and waiting to be sent.
+	Queued int64
+	Sent   int64
+	Failed int64
+}
+
+func GetStats() Stats {
+	return Stats{Queued: atomic.LoadInt64(&queued), Sent: atomic.LoadInt64(&sent), Failed: atomic.LoadInt64(&failed)}
+}
 
 func Init(Host string, Port int, SenderName string, Email string, Password string, AttachFromURL ...bool) {
 	host = Host
@@ -33,46 +66,104 @@ func Init(Host string, Port int, SenderName string, Email string, Password strin
 	if AttachFromURL != nil && len(AttachFromURL) > 0 {
 		attachFromURL = AttachFromURL[0]
 	}
-	go run()
+	// emails are queued while the SMTP server is unreachable, so it doesn't make the
+	// app unready
+	health.Register("mailer", health.TCPCheck(net.JoinHostPort(host, strconv.Itoa(port))), health.CheckOptions{Optional: true})
+	if atomic.CompareAndSwapInt32(&running, 0, 1) {
+		go run()
+	}
 }
 
-func run() {
-	d := gomail.NewDialer(host, port, email, password)
+// Shutdown waits for the emails handed to the mailer to be sent and closes the
+// connection to the SMTP server, it returns at once if the mailer isn't running.
+func Shutdown(ctx context.Context) error {
+	if atomic.LoadInt32(&running) == 0 {
+		return nil
+	}
+	done := make(chan struct{})
+	select {
+	case flush <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+func run() {
 	var s gomail.SendCloser
-	var err error
 	open := false
-	for {
-		select {
-		case m, ok := <-ch:
-			if !ok {
+	closeConnection := func() {
+		if open {
+			if err := s.Close(); err != nil {
+				clean.Error(err)
+			}
+			open = false
+		}
+	}
+	deliver := func(qm *queuedMessage) {
+		atomic.AddInt64(&queued, -1)
+		defer qm.span.End()
+		if !open {
+			var err error
+			// dialed again for every batch, a failure only loses the current email
+			if s, err = gomail.NewDialer(host, port, email, password).Dial(); err != nil {
+				clean.Error(err)
+				atomic.AddInt64(&failed, 1)
+				qm.span.SetError(err)
 				return
 			}
-			if !open {
-				if s, err = d.Dial(); err != nil {
-					clean.Error(err)
-					return
+			open = true
+		}
+		if err := gomail.Send(s, qm.message); err != nil {
+			clean.Error(err)
+			atomic.AddInt64(&failed, 1)
+			qm.span.SetError(err)
+			// the connection may be broken, the next email dials again
+			closeConnection()
+			return
+		}
+		atomic.AddInt64(&sent, 1)
+	}
+	for {
+		select {
+		case qm := <-ch:
+			deliver(qm)
+		case done := <-flush:
+			// the emails of the senders already waiting are sent before closing
+			for drained := false; !drained; {
+				select {
+				case qm := <-ch:
+					deliver(qm)
+				default:
+					drained = true
 				}
-				open = true
-			}
-			if err := gomail.Send(s, m); err != nil {
-				clean.Error(err)
 			}
+			closeConnection()
+			close(done)
 		// Close the connection to the SMTP server if no email was sent in
 		// the last 30 seconds.
 		case <-time.After(30 * time.Second):
-			if open {
-				if err := s.Close(); err != nil {
-					clean.Error(err)
-					return
-				}
-				open = false
-			}
+			closeConnection()
 		}
 	}
 }
 
 func Send(to []string, subject string, templateName string, data interface{}, attachments ...string) {
+	SendContext(context.Background(), to, subject, templateName, data, attachments...)
+}
+
+// SendContext is Send with a context, the span it records ends once the email is
+// handed to the SMTP server.
+func SendContext(ctx context.Context, to []string, subject string, templateName string, data interface{}, attachments ...string) {
+	_, span := tracing.StartSpan(ctx, "mailer.Send", tracing.KindProducer)
+	span.SetAttribute("mail.template", templateName)
+	span.SetAttribute("mail.recipients", len(to))
+	span.SetAttribute("mail.attachments", len(attachments))
 	m := gomail.NewMessage()
 	m.SetHeader("From", senderName+" <"+email+">")
 	m.SetHeader("To", to...)
@@ -108,14 +199,30 @@ func Send(to []string, subject string, templateName string, data interface{}, at
 	var body bytes.Buffer
 	tmpl := templates.GetTemplate(templateName)
 	if tmpl == nil {
-		clean.Error(errors.New("Template '" + templateName + "' not exist"))
+		err := errors.New("Template '" + templateName + "' not exist")
+		clean.Error(err)
+		atomic.AddInt64(&failed, 1)
+		span.SetError(err)
+		span.End()
 		return
 	}
 	if err := tmpl.Execute(&body, data); err != nil {
 		clean.Error(err)
+		atomic.AddInt64(&failed, 1)
+		span.SetError(err)
+		span.End()
 		return
 	}
 	content := body.String()
 	m.SetBody("text/html", content)
-	ch <- m
+	if atomic.LoadInt32(&running) == 0 {
+		// nothing would ever receive the email
+		clean.Error(errNotRunning)
+		atomic.AddInt64(&failed, 1)
+		span.SetError(errNotRunning)
+		span.End()
+		return
+	}
+	atomic.AddInt64(&queued, 1)
+	ch <- &queuedMessage{message: m, span: span}
 }
@@ -0,0 +1,180 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/nehmeroumani/pill.go/templates"
+)
+
+// smtpServer accepts every email, holding them until gate is closed.
+type smtpServer struct {
+	listener net.Listener
+	gate     chan struct{}
+	holding  chan struct{}
+	received int64
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	this := &smtpServer{listener: listener, gate: make(chan struct{}), holding: make(chan struct{}, 100)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go this.serve(conn)
+		}
+	}()
+	return this
+}
+
+func (this *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	fmt.Fprint(conn, "220 localhost\r\n")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(strings.TrimSpace(strings.SplitN(line, " ", 2)[0])) {
+		case "DATA":
+			fmt.Fprint(conn, "354 go ahead\r\n")
+			for line != ".\r\n" {
+				if line, err = reader.ReadString('\n'); err != nil {
+					return
+				}
+			}
+			this.holding <- struct{}{}
+			<-this.gate
+			atomic.AddInt64(&this.received, 1)
+			fmt.Fprint(conn, "250 queued\r\n")
+		case "QUIT":
+			fmt.Fprint(conn, "221 bye\r\n")
+			return
+		default:
+			fmt.Fprint(conn, "250 localhost\r\n")
+		}
+	}
+}
+
+func (this *smtpServer) port() int {
+	return this.listener.Addr().(*net.TCPAddr).Port
+}
+
+// waitStats waits for the stats to satisfy ok.
+func waitStats(t *testing.T, ok func(Stats) bool) Stats {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := GetStats()
+		if ok(stats) {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected stats %+v", stats)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// sendAsync sends an email and reports when Send returned.
+func sendAsync() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		Send([]string{"user@example.com"}, "Hello", "mail.html", "user")
+		close(done)
+	}()
+	return done
+}
+
+func returns(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(2 * time.Second):
+		return false
+	}
+}
+
+// the tests share the mailer goroutine, they run in order
+
+func setupTemplate() {
+	templates.Templates = template.Must(template.New("mail.html").Parse(`Hello {{.}}`))
+}
+
+func TestSendBeforeInit(t *testing.T) {
+	setupTemplate()
+	if atomic.LoadInt32(&running) == 1 {
+		t.Skip("the mailer was started by a previous run")
+	}
+	before := GetStats()
+	if !returns(sendAsync()) {
+		t.Fatal("Send shouldn't block when the mailer isn't running")
+	}
+	if stats := GetStats(); stats.Failed != before.Failed+1 || stats.Queued != 0 {
+		t.Errorf("the email should be counted as failed, got %+v", stats)
+	}
+	if err := Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown should return at once, got %v", err)
+	}
+}
+
+func TestDialFailure(t *testing.T) {
+	setupTemplate()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	Init("127.0.0.1", closedPort, "App", "app@example.com", "")
+	before := GetStats()
+	for i := 0; i < 2; i++ {
+		if !returns(sendAsync()) {
+			t.Fatal("Send shouldn't block once the dial failed")
+		}
+	}
+	waitStats(t, func(stats Stats) bool { return stats.Failed == before.Failed+2 && stats.Queued == 0 })
+}
+
+func TestShutdownDrainsTheQueue(t *testing.T) {
+	setupTemplate()
+	server := newSMTPServer(t)
+	// the configuration is read by the mailer goroutine once it receives the next email
+	Init("127.0.0.1", server.port(), "App", "app@example.com", "")
+	before := GetStats()
+	first := sendAsync()
+	<-server.holding
+	// the next senders wait for the mailer, busy with the first email
+	waiting := []<-chan struct{}{sendAsync(), sendAsync(), sendAsync()}
+	waitStats(t, func(stats Stats) bool { return stats.Queued == 3 })
+	time.Sleep(20 * time.Millisecond)
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- Shutdown(context.Background()) }()
+	time.Sleep(20 * time.Millisecond)
+	close(server.gate)
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if stats := GetStats(); stats.Sent != before.Sent+4 || stats.Queued != 0 || atomic.LoadInt64(&server.received) != 4 {
+		t.Errorf("the waiting emails should be sent before the shutdown returns, got %+v", stats)
+	}
+	for _, done := range append(waiting, first) {
+		if !returns(done) {
+			t.Error("Send should have returned")
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package mailer
+
+import "github.com/nehmeroumani/pill.go/metrics"
+
+// RegisterMetrics adds the queue depth, sent and failures metrics of GetStats to a
+// registry, metrics.DefaultRegistry by default.
+func RegisterMetrics(registry ...*metrics.Registry) {
+	r := metrics.DefaultRegistry
+	if len(registry) > 0 && registry[0] != nil {
+		r = registry[0]
+	}
+	r.MustRegister(
+		metrics.NewGaugeFunc("mailer_queue_depth", "Number of emails waiting to be sent.", func() float64 {
+			return float64(GetStats().Queued)
+		}),
+		metrics.NewCounterFunc("mailer_sent_total", "Number of emails sent.", func() float64 {
+			return float64(GetStats().Sent)
+		}),
+		metrics.NewCounterFunc("mailer_failures_total", "Number of emails which couldn't be sent.", func() float64 {
+			return float64(GetStats().Failed)
+		}),
+	)
+}
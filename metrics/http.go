@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/fastchain"
+	"github.com/nehmeroumani/pill.go/middleware"
+	"github.com/valyala/fasthttp"
+)
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	RequestsTotal    = NewCounterVec("http_requests_total", "Number of HTTP requests handled.", "route", "method", "status")
+	RequestDuration  = NewHistogramVec("http_request_duration_seconds", "Time taken to handle HTTP requests.", DefBuckets, "route", "method", "status")
+	RequestsInFlight = NewGaugeVec("http_requests_in_flight", "Number of HTTP requests being handled.", "route", "method")
+)
+
+func init() {
+	DefaultRegistry.MustRegister(RequestsTotal, RequestDuration, RequestsInFlight)
+}
+
+var (
+	routePatternGetter         func(*http.Request) string
+	fastHttpRoutePatternGetter func(*fasthttp.RequestCtx) string
+)
+
+// SetRoutePatternGetter sets the function returning the route pattern of a request,
+// e.g. SetRoutePatternGetter(mux.RoutePattern); the requests are labelled "unmatched"
+// until it's set.
+func SetRoutePatternGetter(getter func(*http.Request) string) {
+	routePatternGetter = getter
+}
+
+// SetFastHttpRoutePatternGetter is the fasthttp version of SetRoutePatternGetter, e.g.
+// SetFastHttpRoutePatternGetter(fastmux.RoutePattern).
+func SetFastHttpRoutePatternGetter(getter func(*fasthttp.RequestCtx) string) {
+	fastHttpRoutePatternGetter = getter
+}
+
+// Middleware records the requests by route pattern, method and status. It must be
+// used in the route chains, e.g. m.Use(metrics.Middleware()), for the pattern to be
+// known; requests handled outside of a route are labelled "unmatched". A handler
+// panicking before writing its response is recorded as a 500.
+func Middleware() alice.Constructor {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			route := ""
+			if routePatternGetter != nil {
+				route = routePatternGetter(req)
+			}
+			if route == "" {
+				route = "unmatched"
+			}
+			RequestsInFlight.Inc(route, req.Method)
+			start := time.Now()
+			recorder := middleware.NewStatusRecorder(w)
+			panicked := true
+			defer func() {
+				statusCode := recorder.Status()
+				if statusCode == 0 {
+					if panicked {
+						// the panic goes on to the recovery middleware or net/http
+						statusCode = http.StatusInternalServerError
+					} else {
+						// nothing written, net/http sends a 200
+						statusCode = http.StatusOK
+					}
+				}
+				status := strconv.Itoa(statusCode)
+				RequestsInFlight.Dec(route, req.Method)
+				RequestsTotal.Inc(route, req.Method, status)
+				RequestDuration.Observe(time.Since(start).Seconds(), route, req.Method, status)
+			}()
+			h.ServeHTTP(recorder, req)
+			panicked = false
+		})
+	}
+}
+
+// FastHttpMiddleware is the fasthttp version of Middleware.
+func FastHttpMiddleware() fastchain.Constructor {
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			route := ""
+			if fastHttpRoutePatternGetter != nil {
+				route = fastHttpRoutePatternGetter(requestCtx)
+			}
+			if route == "" {
+				route = "unmatched"
+			}
+			method := string(requestCtx.Method())
+			RequestsInFlight.Inc(route, method)
+			start := time.Now()
+			panicked := true
+			defer func() {
+				status := strconv.Itoa(requestCtx.Response.StatusCode())
+				if panicked {
+					// the response is only sent once the handler returned
+					status = strconv.Itoa(fasthttp.StatusInternalServerError)
+				}
+				RequestsInFlight.Dec(route, method)
+				RequestsTotal.Inc(route, method, status)
+				RequestDuration.Observe(time.Since(start).Seconds(), route, method, status)
+			}()
+			h(requestCtx)
+			panicked = false
+		}
+	}
+}
+
+// Handler serves the metrics of a registry, DefaultRegistry by default, e.g.
+// m.Get("/metrics").Then(metrics.Handler()).
+func Handler(registry ...*Registry) http.Handler {
+	r := getRegistry(registry)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		r.WriteTo(w)
+	})
+}
+
+// FastHttpHandler is the fasthttp version of Handler.
+func FastHttpHandler(registry ...*Registry) fasthttp.RequestHandler {
+	r := getRegistry(registry)
+	return func(requestCtx *fasthttp.RequestCtx) {
+		requestCtx.SetContentType(ContentType)
+		requestCtx.Response.Header.Set("Cache-Control", "no-store")
+		r.WriteTo(requestCtx)
+	}
+}
+
+func getRegistry(registry []*Registry) *Registry {
+	if len(registry) > 0 && registry[0] != nil {
+		return registry[0]
+	}
+	return DefaultRegistry
+}
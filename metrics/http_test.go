@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+func requestsTotal(labelValues ...string) float64 {
+	RequestsTotal.mutex.Lock()
+	defer RequestsTotal.mutex.Unlock()
+	if s, ok := RequestsTotal.series[RequestsTotal.key(labelValues)]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func TestMiddleware(t *testing.T) {
+	SetRoutePatternGetter(func(req *http.Request) string {
+		if req.URL.Path == "/unmatched" {
+			return ""
+		}
+		return "/http" + req.URL.Path
+	})
+	SetFastHttpRoutePatternGetter(func(requestCtx *fasthttp.RequestCtx) string {
+		if string(requestCtx.Path()) == "/unmatched" {
+			return ""
+		}
+		return "/fasthttp" + string(requestCtx.Path())
+	})
+	defer SetRoutePatternGetter(nil)
+	defer SetFastHttpRoutePatternGetter(nil)
+
+	clients := map[string]*pilltest.Client{
+		"http": pilltest.New(t, Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/missing" {
+				http.NotFound(w, req)
+			}
+		}))),
+		"fasthttp": pilltest.NewFastHttp(t, FastHttpMiddleware()(func(requestCtx *fasthttp.RequestCtx) {
+			if string(requestCtx.Path()) == "/missing" {
+				requestCtx.NotFound()
+			}
+		})),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			ok := requestsTotal("/"+name+"/ok", "GET", "200")
+			missing := requestsTotal("/"+name+"/missing", "GET", "404")
+			unmatched := requestsTotal("unmatched", "GET", "200")
+			client.Get("/ok").Do().ExpectStatus(http.StatusOK)
+			client.Get("/ok").Do().ExpectStatus(http.StatusOK)
+			client.Get("/missing").Do().ExpectStatus(http.StatusNotFound)
+			client.Get("/unmatched").Do().ExpectStatus(http.StatusOK)
+			if count := requestsTotal("/"+name+"/ok", "GET", "200") - ok; count != 2 {
+				t.Errorf("expected 2 requests to /ok, got %v", count)
+			}
+			if count := requestsTotal("/"+name+"/missing", "GET", "404") - missing; count != 1 {
+				t.Errorf("expected 1 request to /missing, got %v", count)
+			}
+			if count := requestsTotal("unmatched", "GET", "200") - unmatched; count != 1 {
+				t.Errorf("expected 1 unmatched request, got %v", count)
+			}
+		})
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	SetRoutePatternGetter(func(req *http.Request) string {
+		return "/http/panic"
+	})
+	SetFastHttpRoutePatternGetter(func(requestCtx *fasthttp.RequestCtx) string {
+		return "/fasthttp/panic"
+	})
+	defer SetRoutePatternGetter(nil)
+	defer SetFastHttpRoutePatternGetter(nil)
+
+	handlers := map[string]func(){
+		"http": func() {
+			Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				panic("boom")
+			})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		},
+		"fasthttp": func() {
+			FastHttpMiddleware()(func(requestCtx *fasthttp.RequestCtx) {
+				panic("boom")
+			})(&fasthttp.RequestCtx{})
+		},
+	}
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			failed := requestsTotal("/"+name+"/panic", "GET", "500")
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("the panic should go on")
+					}
+				}()
+				handler()
+			}()
+			if count := requestsTotal("/"+name+"/panic", "GET", "500") - failed; count != 1 {
+				t.Errorf("expected 1 request recorded as a 500, got %v", count)
+			}
+			if count := requestsTotal("/"+name+"/panic", "GET", "200"); count != 0 {
+				t.Errorf("expected no request recorded as a 200, got %v", count)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.MustRegister(NewGaugeFunc("answer", "The answer.", func() float64 {
+		return 42
+	}))
+	clients := map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, Handler(registry)),
+		"fasthttp": pilltest.NewFastHttp(t, FastHttpHandler(registry)),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			response := client.Get("/metrics").Do().
+				ExpectStatus(http.StatusOK).
+				ExpectHeader("Content-Type", ContentType).
+				ExpectHeader("Cache-Control", "no-store")
+			if !strings.HasSuffix(string(response.Body), "answer 42\n") {
+				t.Errorf("unexpected body %q", response.Body)
+			}
+		})
+	}
+}
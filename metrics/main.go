@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are the default histogram buckets, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector is a metric family written in the Prometheus text format.
+type Collector interface {
+	Name() string
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mutex      sync.RWMutex
+	collectors map[string]Collector
+}
+
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: map[string]Collector{}}
+}
+
+// MustRegister adds collectors to the registry, it panics if one of their names is
+// already registered.
+func (this *Registry) MustRegister(collectors ...Collector) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, collector := range collectors {
+		if _, ok := this.collectors[collector.Name()]; ok {
+			panic("metrics: '" + collector.Name() + "' is already registered")
+		}
+		this.collectors[collector.Name()] = collector
+	}
+}
+
+func (this *Registry) Unregister(name string) {
+	this.mutex.Lock()
+	delete(this.collectors, name)
+	this.mutex.Unlock()
+}
+
+// WriteTo writes all the metrics in the Prometheus text format, sorted by name.
+func (this *Registry) WriteTo(w io.Writer) (int64, error) {
+	this.mutex.RLock()
+	names := make([]string, 0, len(this.collectors))
+	for name := range this.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]Collector, len(names))
+	for i, name := range names {
+		collectors[i] = this.collectors[name]
+	}
+	this.mutex.RUnlock()
+
+	counter := &countingWriter{w: w}
+	buffer := bufio.NewWriter(counter)
+	for _, collector := range collectors {
+		collector.write(buffer)
+	}
+	err := buffer.Flush()
+	return counter.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (this *countingWriter) Write(p []byte) (int, error) {
+	n, err := this.w.Write(p)
+	this.n += int64(n)
+	return n, err
+}
+
+// desc is the name, help and label names shared by the metric types.
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (this *desc) Name() string {
+	return this.name
+}
+
+func (this *desc) writeHeader(w *bufio.Writer, metricType string) {
+	w.WriteString("# HELP " + this.name + " " + escapeHelp(this.help) + "\n")
+	w.WriteString("# TYPE " + this.name + " " + metricType + "\n")
+}
+
+func (this *desc) key(labelValues []string) string {
+	if len(labelValues) != len(this.labels) {
+		panic("metrics: '" + this.name + "' expects " + strconv.Itoa(len(this.labels)) + " label values")
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// writeSample writes one line, extra is an additional label such as le="0.5".
+func writeSample(w *bufio.Writer, name string, labels []string, labelValues []string, extra string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extra != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + escapeLabelValue(labelValues[i]) + `"`)
+		}
+		if extra != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extra)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
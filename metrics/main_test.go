@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	registry := NewRegistry()
+	requests := NewCounterVec("requests_total", "Number of requests.", "path")
+	inFlight := NewGaugeVec("in_flight", "Number of requests\nbeing handled.")
+	duration := NewHistogramVec("duration_seconds", "Time taken.", []float64{1, 0.5})
+	registry.MustRegister(requests, inFlight, duration, NewGaugeFunc("answer", "The answer.", func() float64 {
+		return 42
+	}))
+	requests.Inc(`/a"b`)
+	requests.Add(2, "/")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+	duration.Observe(0.2)
+	duration.Observe(0.7)
+	duration.Observe(3)
+
+	buffer := &bytes.Buffer{}
+	n, err := registry.WriteTo(buffer)
+	if err != nil || n != int64(buffer.Len()) {
+		t.Fatalf("expected %d bytes written, got %d, %v", buffer.Len(), n, err)
+	}
+	expected := `# HELP answer The answer.
+# TYPE answer gauge
+answer 42
+# HELP duration_seconds Time taken.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="0.5"} 1
+duration_seconds_bucket{le="1"} 2
+duration_seconds_bucket{le="+Inf"} 3
+duration_seconds_sum 3.9
+duration_seconds_count 3
+# HELP in_flight Number of requests\nbeing handled.
+# TYPE in_flight gauge
+in_flight 1
+# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{path="/"} 2
+requests_total{path="/a\"b"} 1
+`
+	if buffer.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buffer.String())
+	}
+
+	registry.Unregister("answer")
+	buffer.Reset()
+	registry.WriteTo(buffer)
+	if bytes.Contains(buffer.Bytes(), []byte("answer")) {
+		t.Errorf("answer should be unregistered")
+	}
+}
+
+func TestMustRegisterTwice(t *testing.T) {
+	registry := NewRegistry()
+	registry.MustRegister(NewCounterVec("requests_total", "Number of requests."))
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a name twice should panic")
+		}
+	}()
+	registry.MustRegister(NewGaugeVec("requests_total", "Number of requests."))
+}
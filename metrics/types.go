@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"bufio"
+	"sort"
+	"sync"
+)
+
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+// vec holds the series of a counter or a gauge, by label values.
+type vec struct {
+	desc
+	mutex  sync.Mutex
+	series map[string]*series
+}
+
+func (this *vec) add(delta float64, labelValues []string) {
+	key := this.key(labelValues)
+	this.mutex.Lock()
+	s, ok := this.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		this.series[key] = s
+	}
+	s.value += delta
+	this.mutex.Unlock()
+}
+
+func (this *vec) set(value float64, labelValues []string) {
+	key := this.key(labelValues)
+	this.mutex.Lock()
+	s, ok := this.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		this.series[key] = s
+	}
+	s.value = value
+	this.mutex.Unlock()
+}
+
+func (this *vec) writeSeries(w *bufio.Writer) {
+	this.mutex.Lock()
+	keys := make([]string, 0, len(this.series))
+	for key := range this.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := this.series[key]
+		writeSample(w, this.name, this.labels, s.labelValues, "", s.value)
+	}
+	this.mutex.Unlock()
+}
+
+type CounterVec struct {
+	vec
+}
+
+func NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	return &CounterVec{vec{desc: desc{name: name, help: help, labels: labels}, series: map[string]*series{}}}
+}
+
+func (this *CounterVec) Inc(labelValues ...string) {
+	this.add(1, labelValues)
+}
+
+// Add adds delta, which must not be negative.
+func (this *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counters can't decrease")
+	}
+	this.add(delta, labelValues)
+}
+
+func (this *CounterVec) write(w *bufio.Writer) {
+	this.writeHeader(w, "counter")
+	this.writeSeries(w)
+}
+
+type GaugeVec struct {
+	vec
+}
+
+func NewGaugeVec(name string, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{vec{desc: desc{name: name, help: help, labels: labels}, series: map[string]*series{}}}
+}
+
+func (this *GaugeVec) Set(value float64, labelValues ...string) {
+	this.set(value, labelValues)
+}
+
+func (this *GaugeVec) Inc(labelValues ...string) {
+	this.add(1, labelValues)
+}
+
+func (this *GaugeVec) Dec(labelValues ...string) {
+	this.add(-1, labelValues)
+}
+
+func (this *GaugeVec) Add(delta float64, labelValues ...string) {
+	this.add(delta, labelValues)
+}
+
+func (this *GaugeVec) write(w *bufio.Writer) {
+	this.writeHeader(w, "gauge")
+	this.writeSeries(w)
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mutex   sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+// NewHistogramVec returns a histogram with the given upper bounds, DefBuckets when
+// none are given.
+func NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &HistogramVec{desc: desc{name: name, help: help, labels: labels}, buckets: buckets, series: map[string]*histogramSeries{}}
+}
+
+func (this *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := this.key(labelValues)
+	this.mutex.Lock()
+	s, ok := this.series[key]
+	if !ok {
+		s = &histogramSeries{labelValues: append([]string(nil), labelValues...), counts: make([]uint64, len(this.buckets))}
+		this.series[key] = s
+	}
+	// counts are per bucket, they're made cumulative when written
+	if i := sort.SearchFloat64s(this.buckets, value); i < len(this.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+	this.mutex.Unlock()
+}
+
+func (this *HistogramVec) write(w *bufio.Writer) {
+	this.writeHeader(w, "histogram")
+	this.mutex.Lock()
+	keys := make([]string, 0, len(this.series))
+	for key := range this.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := this.series[key]
+		var cumulative uint64
+		for i, bound := range this.buckets {
+			cumulative += s.counts[i]
+			writeSample(w, this.name+"_bucket", this.labels, s.labelValues, `le="`+formatFloat(bound)+`"`, float64(cumulative))
+		}
+		writeSample(w, this.name+"_bucket", this.labels, s.labelValues, `le="+Inf"`, float64(s.count))
+		writeSample(w, this.name+"_sum", this.labels, s.labelValues, "", s.sum)
+		writeSample(w, this.name+"_count", this.labels, s.labelValues, "", float64(s.count))
+	}
+	this.mutex.Unlock()
+}
+
+// funcCollector reads its value when the metrics are written.
+type funcCollector struct {
+	desc
+	metricType string
+	value      func() float64
+}
+
+func NewGaugeFunc(name string, help string, value func() float64) Collector {
+	return &funcCollector{desc: desc{name: name, help: help}, metricType: "gauge", value: value}
+}
+
+func NewCounterFunc(name string, help string, value func() float64) Collector {
+	return &funcCollector{desc: desc{name: name, help: help}, metricType: "counter", value: value}
+}
+
+func (this *funcCollector) write(w *bufio.Writer) {
+	this.writeHeader(w, this.metricType)
+	writeSample(w, this.name, nil, nil, "", this.value())
+}
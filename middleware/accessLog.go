@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/fastchain"
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/valyala/fasthttp"
+)
+
+type AccessLogEntry struct {
+	Time      time.Time     `json:"time"`
+	RequestID string        `json:"request_id,omitempty"`
+	ClientIP  string        `json:"client_ip"`
+	Method    string        `json:"method"`
+	Host      string        `json:"host"`
+	Path      string        `json:"path"`
+	Query     string        `json:"query,omitempty"`
+	Status    int           `json:"status"`
+	Bytes     int64         `json:"bytes"`
+	Latency   time.Duration `json:"latency_ns"`
+	UserAgent string        `json:"user_agent,omitempty"`
+	Referer   string        `json:"referer,omitempty"`
+}
+
+// LogAccess is the default access logger, it prints the entries as JSON lines using
+// the standard logger.
+func LogAccess(entry AccessLogEntry) {
+	if data, err := json.Marshal(entry); err == nil {
+		log.Println(string(data))
+	}
+}
+
+// AccessLog logs every request once its response is written, using LogAccess when no
+// logger is given. Put it after RequestID and RealIP to log their values.
+func AccessLog(logger ...func(AccessLogEntry)) alice.Constructor {
+	logAccess := LogAccess
+	if len(logger) > 0 && logger[0] != nil {
+		logAccess = logger[0]
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			recorder := NewStatusRecorder(w)
+			defer func() {
+				status := recorder.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				logAccess(AccessLogEntry{
+					Time:      start,
+					RequestID: GetRequestID(req),
+					ClientIP:  GetClientIP(req),
+					Method:    req.Method,
+					Host:      req.Host,
+					Path:      req.URL.Path,
+					Query:     req.URL.RawQuery,
+					Status:    status,
+					Bytes:     recorder.BytesWritten(),
+					Latency:   time.Since(start),
+					UserAgent: req.UserAgent(),
+					Referer:   req.Referer(),
+				})
+			}()
+			h.ServeHTTP(recorder, req)
+		})
+	}
+}
+
+func FastHttpAccessLog(logger ...func(AccessLogEntry)) fastchain.Constructor {
+	logAccess := LogAccess
+	if len(logger) > 0 && logger[0] != nil {
+		logAccess = logger[0]
+	}
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			start := time.Now()
+			defer func() {
+				logAccess(AccessLogEntry{
+					Time:      start,
+					RequestID: GetFastHttpRequestID(requestCtx),
+					ClientIP:  GetFastHttpClientIP(requestCtx),
+					Method:    helpers.BytesToString(requestCtx.Method()),
+					Host:      helpers.BytesToString(requestCtx.Host()),
+					Path:      helpers.BytesToString(requestCtx.Path()),
+					Query:     helpers.BytesToString(requestCtx.URI().QueryString()),
+					Status:    requestCtx.Response.StatusCode(),
+					Bytes:     int64(len(requestCtx.Response.Body())),
+					Latency:   time.Since(start),
+					UserAgent: helpers.BytesToString(requestCtx.UserAgent()),
+					Referer:   helpers.BytesToString(requestCtx.Referer()),
+				})
+			}()
+			h(requestCtx)
+		}
+	}
+}
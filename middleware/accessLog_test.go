@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/fastchain"
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestAccessLog(t *testing.T) {
+	var entries []AccessLogEntry
+	logger := func(entry AccessLogEntry) {
+		entries = append(entries, entry)
+	}
+	clients := map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, alice.New(RequestID(), AccessLog(logger)).ThenFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/empty" {
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("hello"))
+		})),
+		"fasthttp": pilltest.NewFastHttp(t, fastchain.New(FastHttpRequestID(), FastHttpAccessLog(logger)).ThenFunc(func(requestCtx *fasthttp.RequestCtx) {
+			if string(requestCtx.Path()) == "/empty" {
+				return
+			}
+			requestCtx.SetStatusCode(http.StatusCreated)
+			requestCtx.WriteString("hello")
+		})),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			entries = nil
+			client.Get("/users?page=2").Header(RequestIDHeader, "abc").Header("User-Agent", "test").Do()
+			client.Get("/empty").Do()
+			if len(entries) != 2 {
+				t.Fatalf("expected 2 entries, got %d", len(entries))
+			}
+			entry := entries[0]
+			if entry.RequestID != "abc" || entry.Method != "GET" || entry.Path != "/users" || entry.Query != "page=2" || entry.Status != http.StatusCreated || entry.Bytes != 5 || entry.UserAgent != "test" || entry.Latency < 0 || time.Since(entry.Time) > time.Minute {
+				t.Errorf("unexpected entry %+v", entry)
+			}
+			if entries[1].Status != http.StatusOK || entries[1].Bytes != 0 {
+				t.Errorf("an empty response should be logged as a 200, got %+v", entries[1])
+			}
+		})
+	}
+}
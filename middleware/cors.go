@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/fastchain"
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/valyala/fasthttp"
+)
+
+type CORSOptions struct {
+	// AllowedOrigins accepts exact origins (https://app.example.com), wildcard
+	// subdomains (https://*.example.com) or "*" for any origin.
+	AllowedOrigins []string
+	// AllowOriginFunc accepts the origins it returns true for, in addition to
+	// AllowedOrigins.
+	AllowOriginFunc func(origin string) bool
+	// AllowedMethods defaults to GET, HEAD, POST, PUT, PATCH and DELETE.
+	AllowedMethods []string
+	// AllowedHeaders accepted in preflights, "*" accepts any header. It defaults to
+	// Accept, Authorization, Content-Type, X-Csrf-Token and X-Requested-With.
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is the number of seconds a preflight result can be cached.
+	MaxAge int
+}
+
+var (
+	defaultCORSMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	defaultCORSHeaders = []string{"Accept", "Authorization", "Content-Type", "X-Csrf-Token", "X-Requested-With"}
+)
+
+type corsPolicy struct {
+	CORSOptions
+	anyOrigin      bool
+	origins        map[string]bool
+	wildcards      [][2]string
+	methods        map[string]bool
+	anyHeader      bool
+	headers        map[string]bool
+	exposedHeaders string
+	// varyOrigin is false for a bare "*" policy, whose responses don't depend on the
+	// origin.
+	varyOrigin bool
+}
+
+func newCORSPolicy(options CORSOptions) *corsPolicy {
+	policy := &corsPolicy{CORSOptions: options, origins: map[string]bool{}, methods: map[string]bool{}, headers: map[string]bool{}}
+	for _, origin := range options.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin == "*" {
+			policy.anyOrigin = true
+		} else if i := strings.Index(origin, "*"); i != -1 {
+			policy.wildcards = append(policy.wildcards, [2]string{origin[:i], origin[i+1:]})
+		} else {
+			policy.origins[origin] = true
+		}
+	}
+	if len(policy.AllowedMethods) == 0 {
+		policy.AllowedMethods = defaultCORSMethods
+	}
+	for _, method := range policy.AllowedMethods {
+		policy.methods[strings.ToUpper(method)] = true
+	}
+	if len(policy.AllowedHeaders) == 0 {
+		policy.AllowedHeaders = defaultCORSHeaders
+	}
+	for _, header := range policy.AllowedHeaders {
+		if header == "*" {
+			policy.anyHeader = true
+		}
+		policy.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	policy.exposedHeaders = strings.Join(options.ExposedHeaders, ", ")
+	policy.varyOrigin = !policy.anyOrigin || policy.AllowCredentials
+	return policy
+}
+
+func (this *corsPolicy) isAllowedOrigin(origin string) bool {
+	if this.anyOrigin {
+		return true
+	}
+	lowerOrigin := strings.ToLower(origin)
+	if this.origins[lowerOrigin] {
+		return true
+	}
+	for _, wildcard := range this.wildcards {
+		if len(lowerOrigin) > len(wildcard[0])+len(wildcard[1]) && strings.HasPrefix(lowerOrigin, wildcard[0]) && strings.HasSuffix(lowerOrigin, wildcard[1]) {
+			return true
+		}
+	}
+	return this.AllowOriginFunc != nil && this.AllowOriginFunc(origin)
+}
+
+func (this *corsPolicy) areAllowedHeaders(requestedHeaders string) bool {
+	if this.anyHeader {
+		return true
+	}
+	for _, header := range strings.Split(requestedHeaders, ",") {
+		if header = strings.TrimSpace(header); header != "" && !this.headers[http.CanonicalHeaderKey(header)] {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *corsPolicy) allowOriginValue(origin string) string {
+	if this.anyOrigin && !this.AllowCredentials {
+		return "*"
+	}
+	return origin
+}
+
+// preflight returns the headers answering a preflight, or nil if it's refused.
+func (this *corsPolicy) preflight(origin string, method string, requestedHeaders string) map[string]string {
+	if !this.isAllowedOrigin(origin) || !this.methods[strings.ToUpper(method)] || !this.areAllowedHeaders(requestedHeaders) {
+		return nil
+	}
+	headers := map[string]string{
+		"Access-Control-Allow-Origin":  this.allowOriginValue(origin),
+		"Access-Control-Allow-Methods": strings.ToUpper(method),
+	}
+	if requestedHeaders != "" {
+		headers["Access-Control-Allow-Headers"] = requestedHeaders
+	}
+	if this.AllowCredentials {
+		headers["Access-Control-Allow-Credentials"] = "true"
+	}
+	if this.MaxAge > 0 {
+		headers["Access-Control-Max-Age"] = strconv.Itoa(this.MaxAge)
+	}
+	return headers
+}
+
+// actual returns the headers added to an actual request, or nil if its origin is refused.
+func (this *corsPolicy) actual(origin string) map[string]string {
+	if !this.isAllowedOrigin(origin) {
+		return nil
+	}
+	headers := map[string]string{"Access-Control-Allow-Origin": this.allowOriginValue(origin)}
+	if this.AllowCredentials {
+		headers["Access-Control-Allow-Credentials"] = "true"
+	}
+	if this.exposedHeaders != "" {
+		headers["Access-Control-Expose-Headers"] = this.exposedHeaders
+	}
+	return headers
+}
+
+// CORS applies a CORS policy to a mux, a group or a single route. It answers the
+// preflights itself, the mux dispatching them to the route of the requested method,
+// so it should come before the middlewares rejecting anonymous requests.
+func CORS(options CORSOptions) alice.Constructor {
+	policy := newCORSPolicy(options)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			if origin == "" {
+				// a shared cache mustn't serve this response to cross-origin callers
+				if policy.varyOrigin {
+					w.Header().Add("Vary", "Origin")
+				}
+				h.ServeHTTP(w, req)
+				return
+			}
+			w.Header().Add("Vary", "Origin")
+			if method := req.Header.Get("Access-Control-Request-Method"); req.Method == "OPTIONS" && method != "" {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				headers := policy.preflight(origin, method, req.Header.Get("Access-Control-Request-Headers"))
+				if headers == nil {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				for key, value := range headers {
+					w.Header().Set(key, value)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			for key, value := range policy.actual(origin) {
+				w.Header().Set(key, value)
+			}
+			h.ServeHTTP(w, req)
+		})
+	}
+}
+
+func FastHttpCORS(options CORSOptions) fastchain.Constructor {
+	policy := newCORSPolicy(options)
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			origin := helpers.BytesToString(requestCtx.Request.Header.Peek("Origin"))
+			if origin == "" {
+				// a shared cache mustn't serve this response to cross-origin callers
+				if policy.varyOrigin {
+					requestCtx.Response.Header.Add("Vary", "Origin")
+				}
+				h(requestCtx)
+				return
+			}
+			requestCtx.Response.Header.Add("Vary", "Origin")
+			if method := helpers.BytesToString(requestCtx.Request.Header.Peek("Access-Control-Request-Method")); requestCtx.IsOptions() && method != "" {
+				requestCtx.Response.Header.Add("Vary", "Access-Control-Request-Method")
+				requestCtx.Response.Header.Add("Vary", "Access-Control-Request-Headers")
+				headers := policy.preflight(origin, method, helpers.BytesToString(requestCtx.Request.Header.Peek("Access-Control-Request-Headers")))
+				if headers == nil {
+					requestCtx.SetStatusCode(fasthttp.StatusForbidden)
+					return
+				}
+				for key, value := range headers {
+					requestCtx.Response.Header.Set(key, value)
+				}
+				requestCtx.SetStatusCode(fasthttp.StatusNoContent)
+				return
+			}
+			for key, value := range policy.actual(origin) {
+				requestCtx.Response.Header.Set(key, value)
+			}
+			h(requestCtx)
+		}
+	}
+}
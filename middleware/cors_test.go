@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+var testCORSOptions = CORSOptions{
+	AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+	AllowedMethods:   []string{"GET", "POST", "DELETE"},
+	ExposedHeaders:   []string{"X-Request-Id"},
+	AllowCredentials: true,
+	MaxAge:           600,
+}
+
+func corsClients(t *testing.T, options CORSOptions) map[string]*pilltest.Client {
+	return map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, CORS(options)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte("handled"))
+		}))),
+		"fasthttp": pilltest.NewFastHttp(t, FastHttpCORS(options)(func(requestCtx *fasthttp.RequestCtx) {
+			requestCtx.WriteString("handled")
+		})),
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	for name, client := range corsClients(t, testCORSOptions) {
+		t.Run(name, func(t *testing.T) {
+			response := client.Options("/posts").
+				Header("Origin", "https://app.example.com").
+				Header("Access-Control-Request-Method", "DELETE").
+				Header("Access-Control-Request-Headers", "Content-Type, X-Csrf-Token").
+				Do().
+				ExpectStatus(http.StatusNoContent).
+				ExpectHeader("Access-Control-Allow-Origin", "https://app.example.com").
+				ExpectHeader("Access-Control-Allow-Methods", "DELETE").
+				ExpectHeader("Access-Control-Allow-Headers", "Content-Type, X-Csrf-Token").
+				ExpectHeader("Access-Control-Allow-Credentials", "true").
+				ExpectHeader("Access-Control-Max-Age", "600").
+				ExpectHeaderContains("Vary", "Origin").
+				ExpectHeaderContains("Vary", "Access-Control-Request-Method")
+			if len(response.Body) != 0 {
+				t.Errorf("the preflight shouldn't reach the handler, got %q", response.Body)
+			}
+			client.Options("/posts").
+				Header("Origin", "https://api.example.org").
+				Header("Access-Control-Request-Method", "GET").
+				Do().
+				ExpectStatus(http.StatusNoContent).
+				ExpectHeader("Access-Control-Allow-Origin", "https://api.example.org")
+			for _, refused := range []map[string]string{
+				{"Origin": "https://evil.com", "Access-Control-Request-Method": "GET"},
+				// the wildcard needs a subdomain
+				{"Origin": "https://.example.org", "Access-Control-Request-Method": "GET"},
+				{"Origin": "https://app.example.com", "Access-Control-Request-Method": "PUT"},
+				{"Origin": "https://app.example.com", "Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "X-Secret"},
+			} {
+				request := client.Options("/posts")
+				for key, value := range refused {
+					request.Header(key, value)
+				}
+				request.Do().ExpectStatus(http.StatusForbidden).ExpectHeader("Access-Control-Allow-Origin", "")
+			}
+		})
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	for name, client := range corsClients(t, testCORSOptions) {
+		t.Run(name, func(t *testing.T) {
+			client.Get("/posts").Header("Origin", "https://app.example.com").Do().
+				ExpectStatus(http.StatusOK).
+				ExpectBodyContains("handled").
+				ExpectHeader("Access-Control-Allow-Origin", "https://app.example.com").
+				ExpectHeader("Access-Control-Expose-Headers", "X-Request-Id").
+				ExpectHeader("Vary", "Origin")
+			// the refused origins are handled without CORS headers, the browser blocks them
+			client.Get("/posts").Header("Origin", "https://evil.com").Do().
+				ExpectStatus(http.StatusOK).
+				ExpectHeader("Access-Control-Allow-Origin", "")
+			// the same-origin responses may be cached for the cross-origin requests
+			client.Get("/posts").Do().ExpectStatus(http.StatusOK).ExpectHeader("Vary", "Origin")
+		})
+	}
+	for name, client := range corsClients(t, CORSOptions{AllowedOrigins: []string{"*"}}) {
+		t.Run(name+" any origin", func(t *testing.T) {
+			client.Get("/posts").Header("Origin", "https://app.example.com").Do().
+				ExpectHeader("Access-Control-Allow-Origin", "*")
+			client.Get("/posts").Do().ExpectHeader("Vary", "")
+		})
+	}
+}
@@ -0,0 +1,16 @@
+// Package middleware provides common middlewares in two flavors: alice.Constructor
+// for mux and fastchain.Constructor (prefixed with FastHttp) for fastmux.
+package middleware
+
+import "github.com/nehmeroumani/pill.go/helpers"
+
+type contextKey = helpers.ContextKey
+
+const (
+	requestIDKey contextKey = "requestID"
+	clientIPKey  contextKey = "clientIP"
+
+	// user values keys of the fasthttp flavors, named like the context keys
+	requestIDUserValue = "requestID"
+	clientIPUserValue  = "clientIP"
+)
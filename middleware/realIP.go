@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/fastchain"
+	"github.com/valyala/fasthttp"
+)
+
+// RealIP resolves the client IP from the X-Forwarded-For and X-Real-IP headers when the
+// request comes from one of the trusted proxies (IPs or CIDRs), and makes it available
+// through GetClientIP. The headers of untrusted peers are ignored.
+func RealIP(trustedProxies ...string) alice.Constructor {
+	trusted := parseNetworks(trustedProxies)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ip := clientIP(hostIP(req.RemoteAddr), req.Header.Get("X-Forwarded-For"), req.Header.Get("X-Real-IP"), trusted)
+			h.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), clientIPKey, ip)))
+		})
+	}
+}
+
+func FastHttpRealIP(trustedProxies ...string) fastchain.Constructor {
+	trusted := parseNetworks(trustedProxies)
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			// the headers are copied since the resolved IP is kept as a user value
+			ip := clientIP(requestCtx.RemoteIP().String(), string(requestCtx.Request.Header.Peek("X-Forwarded-For")), string(requestCtx.Request.Header.Peek("X-Real-IP")), trusted)
+			requestCtx.SetUserValue(clientIPUserValue, ip)
+			h(requestCtx)
+		}
+	}
+}
+
+// GetClientIP returns the IP resolved by RealIP, or the IP of the peer.
+func GetClientIP(req *http.Request) string {
+	if ip, ok := req.Context().Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return hostIP(req.RemoteAddr)
+}
+
+func GetFastHttpClientIP(requestCtx *fasthttp.RequestCtx) string {
+	if ip, ok := requestCtx.UserValue(clientIPUserValue).(string); ok {
+		return ip
+	}
+	return requestCtx.RemoteIP().String()
+}
+
+func clientIP(remoteIP string, forwardedFor string, realIP string, trusted []*net.IPNet) string {
+	if !isTrusted(remoteIP, trusted) {
+		return remoteIP
+	}
+	if forwardedFor != "" {
+		// walk the chain from the closest hop, the first untrusted address is the client
+		ips := strings.Split(forwardedFor, ",")
+		for i := len(ips) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(ips[i])
+			if net.ParseIP(ip) == nil {
+				break
+			}
+			if i == 0 || !isTrusted(ip, trusted) {
+				return ip
+			}
+		}
+	}
+	if ip := strings.TrimSpace(realIP); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return remoteIP
+}
+
+func isTrusted(ip string, trusted []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseNetworks(addresses []string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, address := range addresses {
+		address = strings.TrimSpace(address)
+		if !strings.Contains(address, "/") {
+			if ip := net.ParseIP(address); ip != nil {
+				if ip.To4() != nil {
+					address += "/32"
+				} else {
+					address += "/128"
+				}
+			}
+		}
+		if _, network, err := net.ParseCIDR(address); err == nil {
+			networks = append(networks, network)
+		} else {
+			panic("invalid trusted proxy '" + address + "'")
+		}
+	}
+	return networks
+}
+
+func hostIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := parseNetworks([]string{"10.0.0.0/8", "192.0.2.1"})
+	for _, test := range []struct {
+		remoteIP, forwardedFor, realIP, expected string
+	}{
+		{"203.0.113.9", "198.51.100.1", "198.51.100.2", "203.0.113.9"},
+		{"192.0.2.1", "198.51.100.1", "", "198.51.100.1"},
+		{"192.0.2.1", "198.51.100.1, 198.51.100.7, 10.0.0.3", "", "198.51.100.7"},
+		{"192.0.2.1", "10.0.0.4, 10.0.0.3", "", "10.0.0.4"},
+		{"192.0.2.1", "not-an-ip", "198.51.100.2", "198.51.100.2"},
+		{"192.0.2.1", "", " 198.51.100.2 ", "198.51.100.2"},
+		{"192.0.2.1", "", "", "192.0.2.1"},
+	} {
+		if ip := clientIP(test.remoteIP, test.forwardedFor, test.realIP, trusted); ip != test.expected {
+			t.Errorf("clientIP(%q, %q, %q): expected %s, got %s", test.remoteIP, test.forwardedFor, test.realIP, test.expected, ip)
+		}
+	}
+}
+
+func TestParseNetworksPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("an invalid trusted proxy should panic")
+		}
+	}()
+	parseNetworks([]string{"proxy.local"})
+}
+
+func TestRealIP(t *testing.T) {
+	// the peers of the pilltest clients
+	trusted := []string{"192.0.2.1", "0.0.0.0"}
+	clients := map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, RealIP(trusted...)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(GetClientIP(req)))
+		}))),
+		"fasthttp": pilltest.NewFastHttp(t, FastHttpRealIP(trusted...)(func(requestCtx *fasthttp.RequestCtx) {
+			requestCtx.WriteString(GetFastHttpClientIP(requestCtx))
+		})),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			client.Get("/").Header("X-Forwarded-For", "198.51.100.1").Do().ExpectBodyContains("198.51.100.1")
+			client.Get("/").Header("X-Real-IP", "198.51.100.2").Do().ExpectBodyContains("198.51.100.2")
+		})
+	}
+	untrusted := pilltest.New(t, RealIP("10.0.0.1")(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(GetClientIP(req)))
+	})))
+	untrusted.Get("/").Header("X-Forwarded-For", "198.51.100.1").Do().ExpectBodyContains("192.0.2.1")
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/fastchain"
+	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/valyala/fasthttp"
+)
+
+// Recovery recovers from the panics of the next handlers, logs them with their stack
+// trace through clean.Error and answers with a 500 if nothing was written yet.
+func Recovery() alice.Constructor {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			recorder := NewStatusRecorder(w)
+			defer func() {
+				if p := recover(); p != nil {
+					if p == http.ErrAbortHandler {
+						// the server handles it to abort the response silently
+						panic(p)
+					}
+					clean.Error(fmt.Errorf("panic serving %s %s: %v\n%s", req.Method, req.URL.Path, p, debug.Stack()))
+					if !recorder.Written() {
+						http.Error(recorder, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					}
+				}
+			}()
+			h.ServeHTTP(recorder, req)
+		})
+	}
+}
+
+func FastHttpRecovery() fastchain.Constructor {
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			defer func() {
+				if p := recover(); p != nil {
+					clean.Error(fmt.Errorf("panic serving %s %s: %v\n%s", requestCtx.Method(), requestCtx.Path(), p, debug.Stack()))
+					requestCtx.Response.Reset()
+					requestCtx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+				}
+			}()
+			h(requestCtx)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRecovery(t *testing.T) {
+	clients := map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, Recovery()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/written" {
+				w.WriteHeader(http.StatusAccepted)
+			}
+			panic("boom")
+		}))),
+		"fasthttp": pilltest.NewFastHttp(t, FastHttpRecovery()(func(requestCtx *fasthttp.RequestCtx) {
+			requestCtx.Response.Header.Set("X-Partial", "1")
+			panic("boom")
+		})),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			response := client.Get("/").Do().ExpectStatus(http.StatusInternalServerError).ExpectBodyContains("Internal Server Error")
+			if response.Header.Get("X-Partial") != "" {
+				t.Error("the partial response should be discarded")
+			}
+		})
+	}
+	clients["net/http"].Get("/written").Do().ExpectStatus(http.StatusAccepted)
+}
+
+func TestRecoveryRepanicsAbortHandler(t *testing.T) {
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler to reach the server, got %v", p)
+		}
+	}()
+	Recovery()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(nil, &http.Request{})
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/fastchain"
+	"github.com/valyala/fasthttp"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID reuses the X-Request-ID header of the request, or generates a new id,
+// makes it available through GetRequestID and sends it back in the response.
+func RequestID() alice.Constructor {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requestID := req.Header.Get(RequestIDHeader)
+			if !isValidRequestID(requestID) {
+				requestID = NewRequestID()
+				req.Header.Set(RequestIDHeader, requestID)
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+			h.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), requestIDKey, requestID)))
+		})
+	}
+}
+
+func FastHttpRequestID() fastchain.Constructor {
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			// copied since the user values outlive the request buffers
+			requestID := string(requestCtx.Request.Header.Peek(RequestIDHeader))
+			if !isValidRequestID(requestID) {
+				requestID = NewRequestID()
+				requestCtx.Request.Header.Set(RequestIDHeader, requestID)
+			}
+			requestCtx.Response.Header.Set(RequestIDHeader, requestID)
+			requestCtx.SetUserValue(requestIDUserValue, requestID)
+			h(requestCtx)
+		}
+	}
+}
+
+func GetRequestID(req *http.Request) string {
+	return RequestIDFromContext(req.Context())
+}
+
+// RequestIDFromContext returns the request id of a context derived from the request
+// context, e.g. to propagate it to outbound requests.
+func RequestIDFromContext(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
+		return requestID
+	}
+	return ""
+}
+
+func GetFastHttpRequestID(requestCtx *fasthttp.RequestCtx) string {
+	if requestID, ok := requestCtx.UserValue(requestIDUserValue).(string); ok {
+		return requestID
+	}
+	return ""
+}
+
+func NewRequestID() string {
+	randBytes := make([]byte, 16)
+	rand.Read(randBytes)
+	return hex.EncodeToString(randBytes)
+}
+
+// isValidRequestID rejects empty, too long or non printable ids sent by clients.
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > 128 {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
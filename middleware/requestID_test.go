@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/nehmeroumani/pill.go/unified"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRequestID(t *testing.T) {
+	clients := map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, RequestID()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(GetRequestID(req) + " " + req.Header.Get(RequestIDHeader)))
+		}))),
+		"fasthttp": pilltest.NewFastHttp(t, FastHttpRequestID()(func(requestCtx *fasthttp.RequestCtx) {
+			requestCtx.WriteString(GetFastHttpRequestID(requestCtx) + " " + string(requestCtx.Request.Header.Peek(RequestIDHeader)))
+		})),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			client.Get("/").Header(RequestIDHeader, "abc-123").Do().
+				ExpectHeader(RequestIDHeader, "abc-123").
+				ExpectBodyContains("abc-123 abc-123")
+			for _, invalid := range []string{"", "has space", strings.Repeat("a", 129)} {
+				response := client.Get("/").Header(RequestIDHeader, invalid).Do()
+				requestID := response.Header.Get(RequestIDHeader)
+				if len(requestID) != 32 || string(response.Body) != requestID+" "+requestID {
+					t.Errorf("expected a generated id for %q, got %q and %q", invalid, requestID, response.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestIDValue(t *testing.T) {
+	clients := map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, RequestID()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requestID, _ := unified.NewHTTPContext(w, req).Value("requestID").(string)
+			w.Write([]byte(requestID))
+		}))),
+		"fasthttp": pilltest.NewFastHttp(t, FastHttpRequestID()(func(requestCtx *fasthttp.RequestCtx) {
+			requestID, _ := unified.NewFastHttpContext(requestCtx).Value("requestID").(string)
+			requestCtx.WriteString(requestID)
+		})),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			client.Get("/").Header(RequestIDHeader, "abc-123").Do().ExpectBodyContains("abc-123")
+		})
+	}
+}
+
+func TestFastHttpRequestIDOutlivesTheRequest(t *testing.T) {
+	requestCtx := &fasthttp.RequestCtx{}
+	requestCtx.Request.Header.Set(RequestIDHeader, "abc-123")
+	FastHttpRequestID()(func(requestCtx *fasthttp.RequestCtx) {})(requestCtx)
+	// the request buffers are reused by the next requests
+	copy(requestCtx.Request.Header.Peek(RequestIDHeader), "xyz-789")
+	if requestID := GetFastHttpRequestID(requestCtx); requestID != "abc-123" {
+		t.Errorf("the request id should be a copy of the header, got %q", requestID)
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// StatusRecorder wraps an http.ResponseWriter to record the status code and the
+// number of bytes written. Flush, Hijack and Push are delegated to the wrapped writer
+// and report http.ErrNotSupported when it doesn't support them, so
+// http.ResponseController sees through the recorder.
+type StatusRecorder struct {
+	http.ResponseWriter
+	status       int
+	bytesWritten int64
+}
+
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	if recorder, ok := w.(*StatusRecorder); ok {
+		return recorder
+	}
+	return &StatusRecorder{ResponseWriter: w}
+}
+
+func (this *StatusRecorder) WriteHeader(statusCode int) {
+	if this.status == 0 {
+		this.status = statusCode
+	}
+	this.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (this *StatusRecorder) Write(data []byte) (int, error) {
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	n, err := this.ResponseWriter.Write(data)
+	this.bytesWritten += int64(n)
+	return n, err
+}
+
+// Status returns the status code sent, 200 if only the body was written, or 0 if
+// nothing was written yet.
+func (this *StatusRecorder) Status() int {
+	return this.status
+}
+
+func (this *StatusRecorder) BytesWritten() int64 {
+	return this.bytesWritten
+}
+
+func (this *StatusRecorder) Written() bool {
+	return this.status != 0
+}
+
+func (this *StatusRecorder) Flush() {
+	this.FlushError()
+}
+
+// FlushError is used by http.ResponseController instead of Flush.
+func (this *StatusRecorder) FlushError() error {
+	if err := http.NewResponseController(this.ResponseWriter).Flush(); err != nil {
+		return err
+	}
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	return nil
+}
+
+func (this *StatusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(this.ResponseWriter).Hijack()
+}
+
+func (this *StatusRecorder) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := this.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer.
+func (this *StatusRecorder) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
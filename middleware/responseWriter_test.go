@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// plainWriter only implements http.ResponseWriter.
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestStatusRecorder(t *testing.T) {
+	w := httptest.NewRecorder()
+	recorder := NewStatusRecorder(w)
+	if NewStatusRecorder(recorder) != recorder {
+		t.Error("a recorder shouldn't be wrapped twice")
+	}
+	if recorder.Written() || recorder.Status() != 0 {
+		t.Error("nothing was written yet")
+	}
+	recorder.WriteHeader(http.StatusCreated)
+	recorder.WriteHeader(http.StatusAccepted)
+	recorder.Write([]byte("hello"))
+	if recorder.Status() != http.StatusCreated || recorder.BytesWritten() != 5 {
+		t.Errorf("expected the first status and 5 bytes, got %d and %d", recorder.Status(), recorder.BytesWritten())
+	}
+}
+
+func TestStatusRecorderOptionalInterfaces(t *testing.T) {
+	flushed := httptest.NewRecorder()
+	recorder := NewStatusRecorder(flushed)
+	if err := http.NewResponseController(recorder).Flush(); err != nil || !flushed.Flushed || recorder.Status() != http.StatusOK {
+		t.Errorf("the flush should reach the wrapped writer, got %v", err)
+	}
+
+	recorder = NewStatusRecorder(plainWriter{httptest.NewRecorder()})
+	controller := http.NewResponseController(recorder)
+	if err := controller.Flush(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected http.ErrNotSupported from Flush, got %v", err)
+	}
+	if recorder.Written() {
+		t.Error("a failed flush shouldn't count as written")
+	}
+	if _, _, err := controller.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected http.ErrNotSupported from Hijack, got %v", err)
+	}
+	if err := recorder.Push("/app.js", nil); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected http.ErrNotSupported from Push, got %v", err)
+	}
+}
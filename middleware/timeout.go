@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/fastchain"
+	"github.com/valyala/fasthttp"
+)
+
+const timeoutMessage = "Service Unavailable: request timeout"
+
+// Timeout answers with a 503 when the next handlers take more than d; their request
+// context is canceled at the deadline. Since the response is buffered, don't use it on
+// streaming routes.
+func Timeout(d time.Duration) alice.Constructor {
+	return func(h http.Handler) http.Handler {
+		return http.TimeoutHandler(h, d, timeoutMessage)
+	}
+}
+
+func FastHttpTimeout(d time.Duration) fastchain.Constructor {
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return fasthttp.TimeoutHandler(h, d, timeoutMessage)
+	}
+}
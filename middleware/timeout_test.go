@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+)
+
+func TestTimeout(t *testing.T) {
+	client := pilltest.New(t, Timeout(10*time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/slow" {
+			<-req.Context().Done()
+			return
+		}
+		w.Write([]byte("fast"))
+	})))
+	client.Get("/fast").Do().ExpectStatus(http.StatusOK).ExpectBodyContains("fast")
+	client.Get("/slow").Do().ExpectStatus(http.StatusServiceUnavailable).ExpectBodyContains(timeoutMessage)
+}
@@ -0,0 +1,20 @@
+package mux
+
+import "github.com/nehmeroumani/pill.go/helpers"
+
+type Constraint = helpers.Constraint
+
+var (
+	Int  = helpers.IntConstraint
+	UUID = helpers.UUIDConstraint
+	Slug = helpers.SlugConstraint
+)
+
+// Regex accepts the param values fully matching expr.
+func Regex(expr string) Constraint {
+	return helpers.RegexConstraint(expr)
+}
+
+func Enum(values ...string) Constraint {
+	return helpers.EnumConstraint(values...)
+}
@@ -0,0 +1,45 @@
+package mux
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+)
+
+func TestConstraints(t *testing.T) {
+	m := New()
+	m.Get("/posts/:id").Where("id", Int).ThenFunc(pattern)
+	m.Get("/posts/:slug/:order").Where("slug", Slug).Where("order", Enum("asc", "desc")).ThenFunc(pattern)
+	m.Get("/users/:id").Where("id", UUID).ThenFunc(pattern)
+	m.Get("/codes/:code").Where("code", Regex("[A-Z]{3}")).ThenFunc(pattern)
+	client := pilltest.New(t, m)
+	for path, status := range map[string]int{
+		"/posts/42":        http.StatusOK,
+		"/posts/-1":        http.StatusOK,
+		"/posts/4x":        http.StatusNotFound,
+		"/posts/hello/asc": http.StatusOK,
+		"/posts/Hello/asc": http.StatusNotFound,
+		"/posts/hello/up":  http.StatusNotFound,
+		"/users/3f2504e0-4f89-11d3-9a0c-0305e82c3301": http.StatusOK,
+		"/users/42":   http.StatusNotFound,
+		"/codes/ABC":  http.StatusOK,
+		"/codes/ABCD": http.StatusNotFound,
+	} {
+		client.Get(path).Do().ExpectStatus(status)
+	}
+
+	m.ConstraintFailedHandler(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "invalid "+RoutePattern(req), http.StatusBadRequest)
+	})
+	client.Get("/posts/4x").Do().ExpectStatus(http.StatusBadRequest).ExpectBodyContains("invalid /posts/:id")
+}
+
+func TestWhereUnknownParam(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("a constraint on a param the pattern doesn't have should panic")
+		}
+	}()
+	New().Get("/posts/:id").Where("slug", Slug)
+}
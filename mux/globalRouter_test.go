@@ -0,0 +1,50 @@
+package mux
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+)
+
+// namedMux answers every path with its name and the subdomain of the request.
+func namedMux(name string) *Mux {
+	m := New()
+	m.Get("/*path").ThenFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(name + " " + Subdomain(req)))
+	})
+	return m
+}
+
+func TestNewGlobalRouter(t *testing.T) {
+	web, api := namedMux("web"), namedMux("api")
+	// a value is a handler too
+	client := pilltest.New(t, *NewGlobalRouter(web, api, "", "/API"))
+	for path, expected := range map[string]string{"/api/users": "api", "/Api": "api", "/apiv2": "api", "/web": "web", "/users/api": "web"} {
+		client.Get(path).Do().ExpectStatus(http.StatusOK).ExpectBodyContains(expected + " ")
+	}
+	client = pilltest.New(t, NewGlobalRouter(web, api, "API.example.com", "/api"))
+	client.Get("/users").Host("api.example.com").Do().ExpectBodyContains("api ")
+	client.Get("/api/users").Host("example.com").Do().ExpectBodyContains("web ")
+}
+
+func TestRoutingTable(t *testing.T) {
+	router := NewRoutingTable(nil).
+		Route("admin.example.com", "/api", namedMux("admin-api")).
+		Host("admin.example.com", namedMux("admin")).
+		Host("*.example.com", namedMux("tenant")).
+		Prefix("/api/", namedMux("api"))
+	client := pilltest.New(t, router)
+	for _, test := range []struct{ host, path, expected string }{
+		{"admin.example.com", "/api/users", "admin-api "},
+		{"admin.example.com", "/apiv2", "admin "},
+		{"acme.example.com", "/api", "tenant acme"},
+		{"example.com", "/api", "api "},
+		{"example.com", "/api/users", "api "},
+	} {
+		client.Get(test.path).Host(test.host).Do().ExpectStatus(http.StatusOK).ExpectBodyContains(test.expected)
+	}
+	client.Get("/apiv2").Host("example.com").Do().ExpectStatus(http.StatusNotFound)
+	router.Fallback(namedMux("web"))
+	client.Get("/apiv2").Host("example.com").Do().ExpectStatus(http.StatusOK).ExpectBodyContains("web ")
+}
@@ -1,12 +1,23 @@
 package mux
 
 import (
+	"context"
 	"net/http"
+	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/dimfeld/httptreemux"
-	"github.com/gorilla/context"
 	"github.com/justinas/alice"
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+type contextKey = helpers.ContextKey
+
+const (
+	paramsKey    contextKey = "params"
+	preflightKey contextKey = "preflight"
+	patternKey   contextKey = "pattern"
 )
 
 func New(opts ...string) *Mux {
@@ -16,49 +27,132 @@ func New(opts ...string) *Mux {
 			basePath = opts[0]
 		}
 	}
-	return &Mux{Router: httptreemux.New(), basePath: basePath}
+	m := &Mux{Router: httptreemux.New(), basePath: basePath, shared: &muxShared{}}
+	m.Router.MethodNotAllowedHandler = m.methodNotAllowed
+	return m
 }
 
-func wrapHandler(h http.Handler) httptreemux.HandlerFunc {
+func wrapHandler(pattern string, h http.Handler) httptreemux.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request, params map[string]string) {
-		context.Set(req, "params", params)
+		ctx := context.WithValue(req.Context(), paramsKey, params)
+		req = req.WithContext(context.WithValue(ctx, patternKey, pattern))
 		defer func() {
 			req.Body.Close()
 			req.Header.Set("Connection", "close")
 		}()
-		context.ClearHandler(h).ServeHTTP(w, req)
+		if req.Method == "HEAD" {
+			w = headResponseWriter{w}
+		}
+		h.ServeHTTP(w, req)
 	}
 }
 
+// headResponseWriter drops the body written by a GET handler answering a HEAD request.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (this headResponseWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
 type Mux struct {
-	Router   *httptreemux.TreeMux
-	Chain    alice.Chain
-	basePath string
+	Router      *httptreemux.TreeMux
+	Chain       alice.Chain
+	basePath    string
+	shared      *muxShared
+	middlewares []string
+}
+
+// muxShared holds the state shared by a mux and all of its groups.
+type muxShared struct {
+	constraintFailedHandler http.HandlerFunc
+	methodNotAllowedHandler http.HandlerFunc
+	optionsHandler          http.HandlerFunc
+	routes                  []RouteInfo
+	// namedRoutes maps the route names to their patterns, they're unique per mux
+	namedRoutes map[string]string
+	routesMutex sync.RWMutex
+}
+
+func (this *Mux) getShared() *muxShared {
+	if this.shared == nil {
+		this.shared = &muxShared{}
+	}
+	return this.shared
+}
+
+func (this *Mux) SetBasePath(bp string) {
+	bp = strings.TrimSpace(bp)
+	if bp != "/" {
+		this.basePath = bp
+	}
+}
+
+// Group returns a sub-mux sharing the same router, with bp appended to the current
+// base path and a copy of the current middleware chain, so middlewares added to the
+// group don't affect its parent.
+func (this *Mux) Group(bp string) *Mux {
+	r := &Mux{Router: this.Router, basePath: this.basePath, shared: this.getShared(), middlewares: copyStrings(this.middlewares)}
+	bp = strings.TrimSuffix(strings.TrimSpace(bp), "/")
+	if bp != "" {
+		if !strings.HasPrefix(bp, "/") {
+			bp = "/" + bp
+		}
+		r.basePath += bp
+	}
+	r.Chain = this.Chain.Append()
+	return r
+}
+
+func (this *Mux) Path(bp string, h func(r *Mux)) {
+	r := this.Group(bp)
+	if h != nil {
+		h(r)
+	}
 }
 
 func (this *Mux) Use(middlewares ...alice.Constructor) {
 	this.Chain = this.Chain.Append(middlewares...)
+	for _, middleware := range middlewares {
+		this.middlewares = append(this.middlewares, helpers.FuncName(middleware))
+	}
+}
+
+// Routes returns the routes registered in the mux and all of its groups, in
+// registration order.
+func (this *Mux) Routes() []RouteInfo {
+	shared := this.getShared()
+	shared.routesMutex.RLock()
+	defer shared.routesMutex.RUnlock()
+	routes := make([]RouteInfo, len(shared.routes))
+	copy(routes, shared.routes)
+	return routes
+}
+
+func (this *Mux) newRoute(method string, p string) *route {
+	return &route{mux: this, pattern: this.basePath + p, method: method, chain: this.Chain, middlewares: copyStrings(this.middlewares)}
 }
 func (this *Mux) Get(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "GET", chain: this.Chain}
+	return this.newRoute("GET", p)
 }
 func (this *Mux) Post(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "POST", chain: this.Chain}
+	return this.newRoute("POST", p)
 }
 func (this *Mux) Put(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "PUT", chain: this.Chain}
+	return this.newRoute("PUT", p)
 }
 func (this *Mux) Patch(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "PATCH", chain: this.Chain}
+	return this.newRoute("PATCH", p)
 }
 func (this *Mux) Delete(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "DELETE", chain: this.Chain}
+	return this.newRoute("DELETE", p)
 }
 func (this *Mux) Head(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "HEAD", chain: this.Chain}
+	return this.newRoute("HEAD", p)
 }
 func (this *Mux) Options(p string) *route {
-	return &route{mux: this, pattern: this.basePath + p, method: "OPTIONS", chain: this.Chain}
+	return this.newRoute("OPTIONS", p)
 }
 func (this *Mux) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	this.Router.ServeHTTP(w, req)
@@ -67,33 +161,226 @@ func (this *Mux) NotFoundHandler(h func(http.ResponseWriter, *http.Request)) {
 	this.Router.NotFoundHandler = h
 }
 
+// ConstraintFailedHandler sets the handler called when a route matches but one of its
+// params doesn't satisfy the route constraints. It defaults to the not found handler.
+func (this *Mux) ConstraintFailedHandler(h func(http.ResponseWriter, *http.Request)) {
+	this.getShared().constraintFailedHandler = h
+}
+
+// MethodNotAllowedHandler sets the handler called when a path matches but has no route
+// for the request method. The Allow header is already set when it's called.
+func (this *Mux) MethodNotAllowedHandler(h func(http.ResponseWriter, *http.Request)) {
+	this.getShared().methodNotAllowedHandler = h
+}
+
+// OptionsHandler sets the handler answering the OPTIONS requests of the paths without
+// an OPTIONS route. The Allow header is already set when it's called.
+func (this *Mux) OptionsHandler(h func(http.ResponseWriter, *http.Request)) {
+	this.getShared().optionsHandler = h
+}
+
+func (this *Mux) methodNotAllowed(w http.ResponseWriter, req *http.Request, methods map[string]httptreemux.HandlerFunc) {
+	list := make([]string, 0, len(methods))
+	for method := range methods {
+		list = append(list, method)
+	}
+	w.Header().Set("Allow", helpers.AllowHeader(list))
+	shared := this.getShared()
+	if req.Method == "OPTIONS" {
+		// a CORS preflight is answered by the CORS middleware of the route it's about
+		if method := req.Header.Get("Access-Control-Request-Method"); method != "" && req.Header.Get("Origin") != "" {
+			h, ok := methods[method]
+			if !ok && method == "HEAD" {
+				h, ok = methods["GET"]
+			}
+			if ok {
+				h(w, req.WithContext(context.WithValue(req.Context(), preflightKey, true)), nil)
+				return
+			}
+		}
+		this.options(w, req)
+	} else if shared.methodNotAllowedHandler != nil {
+		shared.methodNotAllowedHandler(w, req)
+	} else {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (this *Mux) options(w http.ResponseWriter, req *http.Request) {
+	if h := this.getShared().optionsHandler; h != nil {
+		h(w, req)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func isPreflight(req *http.Request) bool {
+	preflight, _ := req.Context().Value(preflightKey).(bool)
+	return preflight
+}
+
+func (this *Mux) constraintFailed(w http.ResponseWriter, req *http.Request) {
+	if h := this.getShared().constraintFailedHandler; h != nil {
+		h(w, req)
+	} else {
+		this.Router.NotFoundHandler(w, req)
+	}
+}
+
 type route struct {
-	mux     *Mux
-	chain   alice.Chain
-	pattern string
-	method  string
+	mux         *Mux
+	chain       alice.Chain
+	pattern     string
+	method      string
+	name        string
+	constraints map[string]Constraint
+	middlewares []string
+	hidden      bool
+	doc         RouteInfo
+}
+
+// Name registers the route under name, to be used with URL to build its path.
+func (this *route) Name(name string) *route {
+	this.name = name
+	return this
+}
+
+// Where constrains the values accepted by the param key of the route, e.g.
+// Get("/posts/:id").Where("id", Int). It panics if the pattern has no such param.
+func (this *route) Where(key string, constraint Constraint) *route {
+	found := false
+	for _, name := range helpers.PatternParams(this.pattern) {
+		if name == key {
+			found = true
+			break
+		}
+	}
+	if !found {
+		panic("route " + this.pattern + " has no param '" + key + "'")
+	}
+	if this.constraints == nil {
+		this.constraints = map[string]Constraint{}
+	}
+	this.constraints[key] = constraint
+	return this
+}
+
+func (this *route) Constraints() map[string]Constraint {
+	return this.constraints
+}
+
+func (this *route) Summary(summary string) *route {
+	this.doc.Summary = summary
+	return this
+}
+
+func (this *route) Description(description string) *route {
+	this.doc.Description = description
+	return this
+}
+
+func (this *route) Tags(tags ...string) *route {
+	this.doc.Tags = append(this.doc.Tags, tags...)
+	return this
+}
+
+// Request documents the body (or the query string for GET routes) expected by the
+// route with a value of its Go type, e.g. Request(CreateUserRequest{}).
+func (this *route) Request(v interface{}) *route {
+	this.doc.Request = reflect.TypeOf(v)
+	return this
+}
+
+// Response documents a response of the route with a value of its Go type, or nil
+// for a response without body.
+func (this *route) Response(statusCode int, v interface{}) *route {
+	if this.doc.Responses == nil {
+		this.doc.Responses = map[int]reflect.Type{}
+	}
+	this.doc.Responses[statusCode] = reflect.TypeOf(v)
+	return this
+}
+
+func (this *route) register(h interface{}) {
+	if this.name != "" {
+		this.mux.getShared().addNamedRoute(this.name, this.pattern)
+	}
+	if this.hidden {
+		return
+	}
+	info := this.doc
+	info.Method = this.method
+	info.Pattern = this.pattern
+	info.Name = this.name
+	info.Middlewares = this.middlewares
+	info.Handler = helpers.FuncName(h)
+	info.Constraints = this.constraints
+	shared := this.mux.getShared()
+	shared.routesMutex.Lock()
+	shared.routes = append(shared.routes, info)
+	shared.routesMutex.Unlock()
 }
 
 func (this *route) Use(middlewares ...alice.Constructor) *route {
 	this.chain = this.chain.Append(middlewares...)
+	for _, middleware := range middlewares {
+		this.middlewares = append(this.middlewares, helpers.FuncName(middleware))
+	}
 	return this
 }
 
 func (this *route) Then(h http.Handler) {
-	this.mux.Router.Handle(this.method, this.pattern, wrapHandler(this.chain.Then(h)))
+	this.register(h)
+	this.handle(h)
 }
 
 func (this *route) ThenFunc(h http.HandlerFunc) {
-	this.mux.Router.Handle(this.method, this.pattern, wrapHandler(this.chain.ThenFunc(h)))
+	this.register(h)
+	this.handle(h)
+}
+
+func (this *route) handle(h http.Handler) {
+	mux := this.mux
+	// the preflights dispatched to the route must be answered by its middlewares
+	guarded := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if isPreflight(req) {
+			mux.options(w, req)
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+	this.mux.Router.Handle(this.method, this.pattern, wrapHandler(this.pattern, this.checkConstraints(this.chain.Then(guarded))))
+}
+
+func (this *route) checkConstraints(h http.Handler) http.Handler {
+	if len(this.constraints) == 0 {
+		return h
+	}
+	mux, constraints := this.mux, this.constraints
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !isPreflight(req) && !helpers.MatchConstraints(Params(req), constraints) {
+			mux.constraintFailed(w, req)
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
 }
 
 // Params(r *http.Request) is a function to get URL params from the request context
 func Params(req *http.Request) map[string]string {
-	if params, ok := context.GetOk(req, "params"); ok {
-		return params.(map[string]string)
+	if params, ok := req.Context().Value(paramsKey).(map[string]string); ok {
+		return params
 	}
 	return nil
 }
+
+// RoutePattern returns the pattern of the route which matched the request, e.g.
+// "/users/:id", or "" outside of a route.
+func RoutePattern(req *http.Request) string {
+	pattern, _ := req.Context().Value(patternKey).(string)
+	return pattern
+}
+
 func GetParam(r *http.Request, key string) string {
 	if params := Params(r); params != nil {
 		if value, ok := params[key]; ok {
@@ -103,29 +390,6 @@ func GetParam(r *http.Request, key string) string {
 	return ""
 }
 
-type GlobalRouter struct {
-	webRouter *Mux
-	apiRouter *Mux
-	apiDomainName string
-	apiPath   string
-}
-
-func NewGlobalRouter(WebRouter *Mux, APIRouter *Mux, APIDomainName string, APIPath string) *GlobalRouter {
-	return &GlobalRouter{webRouter: WebRouter, apiRouter: APIRouter, apiDomainName: strings.ToLower(strings.TrimSpace(APIDomainName)), apiPath: strings.ToLower(strings.TrimSpace(APIPath))}
-}
-
-func (this GlobalRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if this.apiDomainName != "" {
-		if strings.ToLower(req.Host) == this.apiDomainName {
-			this.apiRouter.ServeHTTP(w, req)
-		} else {
-			this.webRouter.ServeHTTP(w, req)
-		}
-	} else {
-		if strings.HasPrefix(strings.ToLower(req.URL.Path), this.apiPath) {
-			this.apiRouter.ServeHTTP(w, req)
-		} else {
-			this.webRouter.ServeHTTP(w, req)
-		}
-	}
+func copyStrings(s []string) []string {
+	return append([]string(nil), s...)
 }
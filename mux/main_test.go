@@ -0,0 +1,101 @@
+package mux
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/pill.go/pilltest"
+)
+
+// tag returns a middleware appending name to the X-Chain header.
+func tag(name string) alice.Constructor {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("X-Chain", name)
+			h.ServeHTTP(w, req)
+		})
+	}
+}
+
+// pattern answers the pattern of the route and its params.
+func pattern(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte(RoutePattern(req) + " " + GetParam(req, "id")))
+}
+
+func TestGroup(t *testing.T) {
+	m := New()
+	m.Use(tag("root"))
+	api := m.Group("/api")
+	api.Use(tag("api"))
+	users := api.Group("users/")
+	users.Use(tag("users"))
+	users.Get("/:id").ThenFunc(pattern)
+	api.Path("/posts", func(r *Mux) {
+		r.Get("").Use(tag("route")).ThenFunc(pattern)
+		r.Path("/:id/comments", func(r *Mux) {
+			r.Get("/").ThenFunc(pattern)
+		})
+	})
+	m.Get("/").ThenFunc(pattern)
+
+	client := pilltest.New(t, m)
+	response := client.Get("/api/users/5").Do().ExpectStatus(http.StatusOK).ExpectBodyContains("/api/users/:id 5")
+	if chain := response.Header.Values("X-Chain"); len(chain) != 3 || chain[0] != "root" || chain[1] != "api" || chain[2] != "users" {
+		t.Errorf("expected the root, api and users middlewares in order, got %v", chain)
+	}
+	response = client.Get("/api/posts").Do().ExpectBodyContains("/api/posts")
+	if chain := response.Header.Values("X-Chain"); len(chain) != 3 || chain[2] != "route" {
+		t.Errorf("the middlewares of a group shouldn't leak to its siblings, got %v", chain)
+	}
+	client.Get("/api/posts/7/comments/").Do().ExpectStatus(http.StatusOK).ExpectBodyContains("/api/posts/:id/comments/ 7")
+	response = client.Get("/").Do().ExpectStatus(http.StatusOK)
+	if chain := response.Header.Values("X-Chain"); len(chain) != 1 {
+		t.Errorf("the middlewares of the groups shouldn't affect their parent, got %v", chain)
+	}
+	client.Get("/apiusers/5").Do().ExpectStatus(http.StatusNotFound)
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	m := New()
+	m.Get("/posts").ThenFunc(pattern)
+	m.Post("/posts").ThenFunc(pattern)
+	m.Delete("/posts/:id").ThenFunc(pattern)
+	client := pilltest.New(t, m)
+	client.Put("/posts").Do().ExpectStatus(http.StatusMethodNotAllowed).ExpectHeader("Allow", "GET, HEAD, OPTIONS, POST")
+	client.Get("/posts/1").Do().ExpectStatus(http.StatusMethodNotAllowed).ExpectHeader("Allow", "DELETE, OPTIONS")
+	client.Get("/comments").Do().ExpectStatus(http.StatusNotFound)
+
+	m.MethodNotAllowedHandler(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "allowed: "+w.Header().Get("Allow"), http.StatusMethodNotAllowed)
+	})
+	client.Patch("/posts").Do().ExpectStatus(http.StatusMethodNotAllowed).ExpectBodyContains("allowed: GET, HEAD, OPTIONS, POST")
+}
+
+func TestAutomaticOptionsAndHead(t *testing.T) {
+	m := New()
+	m.Get("/posts").ThenFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Method", req.Method)
+		w.Write([]byte("posts"))
+	})
+	m.Options("/custom").ThenFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	m.Get("/custom").ThenFunc(pattern)
+	client := pilltest.New(t, m)
+	response := client.Options("/posts").Do().ExpectStatus(http.StatusNoContent).ExpectHeader("Allow", "GET, HEAD, OPTIONS")
+	if len(response.Body) != 0 {
+		t.Errorf("the automatic OPTIONS answer has no body, got %q", response.Body)
+	}
+	client.Options("/custom").Do().ExpectStatus(http.StatusOK)
+	response = client.Head("/posts").Do().ExpectStatus(http.StatusOK).ExpectHeader("X-Method", "HEAD")
+	if len(response.Body) != 0 {
+		t.Errorf("the body of a HEAD answer should be dropped, got %q", response.Body)
+	}
+
+	m.OptionsHandler(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Options", "custom")
+		w.WriteHeader(http.StatusOK)
+	})
+	client.Options("/posts").Do().ExpectStatus(http.StatusOK).ExpectHeader("X-Options", "custom").ExpectHeader("Allow", "GET, HEAD, OPTIONS")
+}
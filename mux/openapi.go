@@ -0,0 +1,54 @@
+package mux
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/nehmeroumani/pill.go/openapi"
+)
+
+// ServeOpenAPI serves the OpenAPI document of the routes registered in the mux and all
+// of its groups. The document is generated on the first request, so routes registered
+// after ServeOpenAPI are included; the documentation routes themselves are not.
+func (this *Mux) ServeOpenAPI(config openapi.Config) {
+	var once sync.Once
+	var doc *openapi.Document
+	getDoc := func() *openapi.Document {
+		once.Do(func() {
+			doc = openapi.Generate(config.Info, this.Routes(), config.Servers...)
+		})
+		return doc
+	}
+	serve := func(contentType string, encode func(*openapi.Document) ([]byte, error)) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			data, err := encode(getDoc())
+			if err != nil {
+				clean.Error(err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.Write(data)
+		}
+	}
+	if config.Path != "" {
+		r := this.Get(config.Path)
+		r.hidden = true
+		r.ThenFunc(serve("application/json", (*openapi.Document).JSON))
+	}
+	if config.YAMLPath != "" {
+		r := this.Get(config.YAMLPath)
+		r.hidden = true
+		r.ThenFunc(serve("application/yaml", (*openapi.Document).YAML))
+	}
+	if config.DocsPath != "" && config.Path != "" {
+		page := openapi.DocsPage(config, this.basePath+config.Path)
+		r := this.Get(config.DocsPath)
+		r.hidden = true
+		r.ThenFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(page)
+		})
+	}
+}
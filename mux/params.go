@@ -0,0 +1,34 @@
+package mux
+
+import (
+	"net/http"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+// ParamOr returns the URL param key, or def if it's missing or empty.
+func ParamOr(req *http.Request, key string, def string) string {
+	if value := GetParam(req, key); value != "" {
+		return value
+	}
+	return def
+}
+
+func ParamInt(req *http.Request, key string) (int, error) {
+	i, err := helpers.ParseIntParam(key, GetParam(req, key), 0)
+	return int(i), err
+}
+
+func ParamInt32(req *http.Request, key string) (int32, error) {
+	i, err := helpers.ParseIntParam(key, GetParam(req, key), 32)
+	return int32(i), err
+}
+
+func ParamInt64(req *http.Request, key string) (int64, error) {
+	return helpers.ParseIntParam(key, GetParam(req, key), 64)
+}
+
+// ParamUUID returns the URL param key lowercased, or an error if it isn't a valid UUID.
+func ParamUUID(req *http.Request, key string) (string, error) {
+	return helpers.ParseUUIDParam(key, GetParam(req, key))
+}
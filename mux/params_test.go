@@ -0,0 +1,61 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/nehmeroumani/pill.go/pilltest"
+)
+
+func TestParams(t *testing.T) {
+	m := New()
+	m.Get("/users/:id/posts/:slug").ThenFunc(func(w http.ResponseWriter, req *http.Request) {
+		id, err := ParamInt(req, "id")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(strconv.Itoa(id) + " " + GetParam(req, "slug") + " " + ParamOr(req, "page", "1") + " " + strconv.Itoa(len(Params(req)))))
+	})
+	client := pilltest.New(t, m)
+	client.Get("/users/42/posts/hello").Do().ExpectStatus(http.StatusOK).ExpectBodyContains("42 hello 1 2")
+	client.Get("/users/x/posts/hello").Do().ExpectStatus(http.StatusBadRequest).ExpectBodyContains(`param 'id' ("x"): invalid syntax`)
+	if params := Params(httptest.NewRequest("GET", "/", nil)); params != nil || GetParam(httptest.NewRequest("GET", "/", nil), "id") != "" {
+		t.Errorf("a request outside of a route has no params, got %v", params)
+	}
+}
+
+func TestTypedParams(t *testing.T) {
+	req := func(params map[string]string) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		var got *http.Request
+		wrapHandler("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r
+		}))(httptest.NewRecorder(), r, params)
+		return got
+	}
+	r := req(map[string]string{"small": "127", "big": "2147483648", "uuid": "3F2504E0-4F89-11D3-9A0C-0305E82C3301", "bad": "3f2504e0"})
+	if i, err := ParamInt32(r, "small"); err != nil || i != 127 {
+		t.Errorf("expected 127, got %d, %v", i, err)
+	}
+	if _, err := ParamInt32(r, "big"); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("expected a range error, got %v", err)
+	}
+	if i, err := ParamInt64(r, "big"); err != nil || i != 2147483648 {
+		t.Errorf("expected 2147483648, got %d, %v", i, err)
+	}
+	if _, err := ParamInt(r, "missing"); !errors.Is(err, helpers.ErrMissingParam) {
+		t.Errorf("expected a missing param error, got %v", err)
+	}
+	if u, err := ParamUUID(r, "uuid"); err != nil || u != "3f2504e0-4f89-11d3-9a0c-0305e82c3301" {
+		t.Errorf("expected the lowercased UUID, got %s, %v", u, err)
+	}
+	var paramErr *helpers.ParamError
+	if _, err := ParamUUID(r, "bad"); !errors.As(err, &paramErr) || paramErr.Key != "bad" || !errors.Is(err, helpers.ErrInvalidUUID) {
+		t.Errorf("expected an invalid UUID error, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package mux
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func showUser(w http.ResponseWriter, req *http.Request) {}
+
+func TestRoutes(t *testing.T) {
+	m := New()
+	m.Use(tag("root"))
+	api := m.Group("/api")
+	api.Get("/users/:id").Name("users.show").Where("id", Int).Use(tag("route")).ThenFunc(showUser)
+	api.Post("/users").Then(http.HandlerFunc(showUser))
+	routes := m.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %+v", routes)
+	}
+	show := routes[0]
+	if show.Method != "GET" || show.Pattern != "/api/users/:id" || show.Name != "users.show" || show.Handler != "mux.showUser" {
+		t.Errorf("unexpected route %+v", show)
+	}
+	if len(show.Middlewares) != 2 || !strings.HasPrefix(show.Middlewares[0], "mux.tag") || show.Constraints["id"].String() != "int" {
+		t.Errorf("unexpected middlewares or constraints %v %v", show.Middlewares, show.Constraints)
+	}
+	if routes[1].Method != "POST" || routes[1].Handler != "mux.showUser" {
+		t.Errorf("unexpected route %+v", routes[1])
+	}
+	// the registry is a copy
+	routes[0].Pattern = "/changed"
+	if m.Routes()[0].Pattern != "/api/users/:id" || len(api.Routes()) != 2 {
+		t.Error("the routes of the groups should be shared with their mux, and not be modified by the callers")
+	}
+
+	var table bytes.Buffer
+	if err := m.PrintRoutes(&table); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "METHOD") || !strings.Contains(lines[1], "users.show") || !strings.Contains(lines[1], "id=int") || !strings.Contains(lines[2], "POST") {
+		t.Errorf("unexpected table:\n%s", table.String())
+	}
+}
@@ -0,0 +1,27 @@
+package mux
+
+import (
+	"net/http"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/pill.go/unified"
+)
+
+// Unified runs h as a net/http handler, e.g. m.Get("/users/:id").Then(mux.Unified(h)).
+func Unified(h unified.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		h(unified.NewHTTPContext(w, req, Params(req)))
+	})
+}
+
+// UnifiedMiddleware runs middleware in an alice chain, the values it sets are visible
+// to the next handlers.
+func UnifiedMiddleware(middleware unified.Middleware) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			middleware(func(c unified.Context) {
+				next.ServeHTTP(c.ResponseWriter(), c.Request())
+			})(unified.NewHTTPContext(w, req, Params(req)))
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package mux
+
+import (
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+func (this *muxShared) addNamedRoute(name string, pattern string) {
+	this.routesMutex.Lock()
+	defer this.routesMutex.Unlock()
+	if p, ok := this.namedRoutes[name]; ok && p != pattern {
+		panic("route name '" + name + "' is already used by " + p)
+	}
+	if this.namedRoutes == nil {
+		this.namedRoutes = map[string]string{}
+	}
+	this.namedRoutes[name] = pattern
+}
+
+// URL builds the path of the route registered under name in the mux or one of its
+// groups, filling its params with the given key/value pairs; extra pairs are added to
+// the query string.
+//
+// e.g. m.URL("users.show", "id", 5) -> /users/5
+func (this *Mux) URL(name string, params ...interface{}) (string, error) {
+	shared := this.getShared()
+	shared.routesMutex.RLock()
+	pattern, ok := shared.namedRoutes[name]
+	shared.routesMutex.RUnlock()
+	if !ok {
+		return "", helpers.UnknownRouteError(name)
+	}
+	return helpers.BuildRoutePath(pattern, params...)
+}
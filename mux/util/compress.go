@@ -0,0 +1,322 @@
+package util
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+type CompressOptions struct {
+	// Level of compression, from 1 (fastest) to 9 (best); it defaults to 5.
+	Level int
+	// MinSize is the size under which responses are sent uncompressed, it defaults
+	// to 1024 bytes.
+	MinSize int
+	// ContentTypes allowed to be compressed; "text/*" allows a whole type. It
+	// defaults to DefaultCompressContentTypes.
+	ContentTypes []string
+	// Encodings enabled, by order of preference; it defaults to br, gzip and deflate.
+	Encodings []string
+}
+
+var DefaultCompressContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/problem+json",
+	"application/wasm",
+	"application/x-font-ttf",
+	"application/vnd.ms-fontobject",
+	"font/otf",
+	"font/ttf",
+	"image/svg+xml",
+	"image/x-icon",
+}
+
+var defaultCompressOptions = newCompressOptions(CompressOptions{})
+
+type compressOptions struct {
+	CompressOptions
+	exactTypes  map[string]bool
+	prefixTypes []string
+	pools       map[string]*sync.Pool
+}
+
+// compressor is implemented by the writers of the 3 supported encodings.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+func newCompressOptions(options CompressOptions) *compressOptions {
+	if options.Level <= 0 || options.Level > 9 {
+		options.Level = 5
+	}
+	if options.MinSize <= 0 {
+		options.MinSize = 1024
+	}
+	if len(options.ContentTypes) == 0 {
+		options.ContentTypes = DefaultCompressContentTypes
+	}
+	if len(options.Encodings) == 0 {
+		options.Encodings = []string{"br", "gzip", "deflate"}
+	}
+	this := &compressOptions{CompressOptions: options, exactTypes: map[string]bool{}, pools: map[string]*sync.Pool{}}
+	for _, contentType := range options.ContentTypes {
+		contentType = strings.ToLower(contentType)
+		if strings.HasSuffix(contentType, "*") {
+			this.prefixTypes = append(this.prefixTypes, strings.TrimSuffix(contentType, "*"))
+		} else {
+			this.exactTypes[contentType] = true
+		}
+	}
+	level := options.Level
+	for _, encoding := range options.Encodings {
+		var pool *sync.Pool
+		switch encoding {
+		case "br":
+			pool = &sync.Pool{New: func() interface{} { return brotli.NewWriterLevel(nil, level) }}
+		case "gzip":
+			pool = &sync.Pool{New: func() interface{} {
+				w, _ := gzip.NewWriterLevel(nil, level)
+				return w
+			}}
+		case "deflate":
+			pool = &sync.Pool{New: func() interface{} {
+				w, _ := flate.NewWriter(nil, level)
+				return w
+			}}
+		default:
+			panic("unsupported encoding '" + encoding + "'")
+		}
+		this.pools[encoding] = pool
+	}
+	return this
+}
+
+func (this *compressOptions) isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if this.exactTypes[mediaType] {
+		return true
+	}
+	for _, prefix := range this.prefixTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NegotiateEncoding picks the preferred encoding accepted by an Accept-Encoding
+// header, honouring its q-values; it returns "" when none is acceptable.
+func NegotiateEncoding(acceptEncoding string, encodings []string) string {
+	return helpers.NegotiateEncoding(acceptEncoding, encodings)
+}
+
+// Compress compresses the responses of the next handlers with the encoding negotiated
+// from the Accept-Encoding header, when they're large enough and of a compressible
+// type and aren't already encoded.
+func Compress(opts ...CompressOptions) alice.Constructor {
+	options := defaultCompressOptions
+	if len(opts) > 0 {
+		options = newCompressOptions(opts[0])
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := NegotiateEncoding(req.Header.Get("Accept-Encoding"), options.Encodings)
+			if encoding == "" || req.Method == "HEAD" || req.Header.Get("Upgrade") != "" {
+				h.ServeHTTP(w, req)
+				return
+			}
+			cw := newCompressResponseWriter(w, encoding, options)
+			defer cw.Close()
+			h.ServeHTTP(cw, req)
+		})
+	}
+}
+
+// compressResponseWriter buffers the beginning of a response until it knows if it
+// should be compressed: when it reaches the minimum size, or on Flush or Close.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	options  *compressOptions
+	encoding string
+	writer   compressor
+	buffer   []byte
+	status   int
+	decided  bool
+	hijacked bool
+}
+
+func newCompressResponseWriter(w http.ResponseWriter, encoding string, options *compressOptions) *compressResponseWriter {
+	return &compressResponseWriter{ResponseWriter: w, options: options, encoding: encoding}
+}
+
+func (this *compressResponseWriter) WriteHeader(statusCode int) {
+	if this.decided || this.status != 0 {
+		if !this.decided && statusCode < 200 {
+			this.ResponseWriter.WriteHeader(statusCode)
+		}
+		return
+	}
+	if statusCode < 200 {
+		// informational responses go through, the final one is still to come
+		this.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	this.status = statusCode
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		this.decide(false)
+	}
+}
+
+func (this *compressResponseWriter) Write(data []byte) (int, error) {
+	if this.decided {
+		if this.writer != nil {
+			return this.writer.Write(data)
+		}
+		return this.ResponseWriter.Write(data)
+	}
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	header := this.Header()
+	if header.Get("Content-Encoding") != "" {
+		this.decide(false)
+		return this.ResponseWriter.Write(data)
+	}
+	if contentLength := header.Get("Content-Length"); contentLength != "" {
+		if size, err := strconv.Atoi(contentLength); err == nil && size < this.options.MinSize {
+			this.decide(false)
+			return this.ResponseWriter.Write(data)
+		}
+	}
+	this.buffer = append(this.buffer, data...)
+	if len(this.buffer) >= this.options.MinSize {
+		this.decide(true)
+		if err := this.flushBuffer(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// decide sends the headers, compressing the response if allowed and wanted.
+func (this *compressResponseWriter) decide(compress bool) {
+	this.decided = true
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	header := this.Header()
+	if compress {
+		contentType := header.Get("Content-Type")
+		if contentType == "" {
+			contentType = http.DetectContentType(this.buffer)
+			header.Set("Content-Type", contentType)
+		}
+		// the ranges of partial responses refer to the uncompressed representation
+		compress = header.Get("Content-Encoding") == "" && this.status != http.StatusPartialContent && header.Get("Content-Range") == "" && this.options.isCompressible(contentType)
+	}
+	if compress {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", this.encoding)
+		// the compressed representation isn't byte for byte the one of a strong ETag
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		this.writer = this.options.pools[this.encoding].Get().(compressor)
+		this.writer.Reset(this.ResponseWriter)
+	}
+	this.ResponseWriter.WriteHeader(this.status)
+}
+
+func (this *compressResponseWriter) flushBuffer() error {
+	if len(this.buffer) == 0 {
+		return nil
+	}
+	var err error
+	if this.writer != nil {
+		_, err = this.writer.Write(this.buffer)
+	} else {
+		_, err = this.ResponseWriter.Write(this.buffer)
+	}
+	this.buffer = nil
+	return err
+}
+
+// Close ends the response, sending what's still buffered, and releases the compressor.
+func (this *compressResponseWriter) Close() error {
+	if this.hijacked {
+		return nil
+	}
+	if !this.decided {
+		if this.status == 0 && len(this.buffer) == 0 {
+			// nothing was written, let the server send its default response
+			return nil
+		}
+		this.decide(false)
+	}
+	err := this.flushBuffer()
+	if this.writer != nil {
+		if closeErr := this.writer.Close(); err == nil {
+			err = closeErr
+		}
+		this.writer.Reset(nil)
+		this.options.pools[this.encoding].Put(this.writer)
+		this.writer = nil
+	}
+	return err
+}
+
+func (this *compressResponseWriter) Flush() {
+	if !this.decided {
+		// streamed responses are compressed whatever their size
+		this.decide(len(this.buffer) > 0 || this.status == http.StatusOK)
+	}
+	this.flushBuffer()
+	if this.writer != nil {
+		this.writer.Flush()
+	}
+	if flusher, ok := this.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (this *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := this.ResponseWriter.(http.Hijacker); ok {
+		this.hijacked = true
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("the response writer doesn't support hijacking")
+}
+
+func (this *compressResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := this.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (this *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
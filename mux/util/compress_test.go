@@ -0,0 +1,82 @@
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+)
+
+var largeText = strings.Repeat("compressible text ", 100)
+
+func newCompressClient(t *testing.T) *pilltest.Client {
+	handler := Compress(CompressOptions{Encodings: []string{"gzip"}})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if etag := req.URL.Query().Get("etag"); etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		switch req.URL.Path {
+		case "/small":
+			w.Write([]byte("small"))
+			return
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+		case "/partial":
+			w.Header().Set("Content-Range", "bytes 0-1799/5000")
+			w.WriteHeader(http.StatusPartialContent)
+		case "/range":
+			w.Header().Set("Content-Range", "bytes */5000")
+		case "/stream":
+			w.Write([]byte("first"))
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(largeText))
+	}))
+	return pilltest.New(t, handler).SetHeader("Accept-Encoding", "gzip")
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCompress(t *testing.T) {
+	client := newCompressClient(t)
+	response := client.Get("/").Do().ExpectStatus(http.StatusOK).ExpectHeader("Content-Encoding", "gzip").ExpectHeaderContains("Vary", "Accept-Encoding")
+	if body := gunzip(t, response.Body); body != largeText {
+		t.Errorf("unexpected body %q", body)
+	}
+	response = client.Get("/stream").Do().ExpectHeader("Content-Encoding", "gzip")
+	if body := gunzip(t, response.Body); body != "first"+largeText {
+		t.Errorf("unexpected streamed body %q", body)
+	}
+	for _, path := range []string{"/small", "/image"} {
+		client.Get(path).Do().ExpectStatus(http.StatusOK).ExpectHeader("Content-Encoding", "")
+	}
+	client.Head("/").Do().ExpectHeader("Content-Encoding", "")
+	client.Get("/").Header("Accept-Encoding", "br").Do().ExpectHeader("Content-Encoding", "").ExpectBodyContains(largeText)
+}
+
+func TestCompressPartialContent(t *testing.T) {
+	client := newCompressClient(t)
+	client.Get("/partial").Do().ExpectStatus(http.StatusPartialContent).ExpectHeader("Content-Encoding", "").ExpectHeader("Content-Range", "bytes 0-1799/5000").ExpectBodyContains(largeText)
+	client.Get("/range").Do().ExpectHeader("Content-Encoding", "").ExpectBodyContains(largeText)
+}
+
+func TestCompressWeakensETag(t *testing.T) {
+	client := newCompressClient(t)
+	client.Get("/").Query("etag", `"v1"`).Do().ExpectHeader("Content-Encoding", "gzip").ExpectHeader("ETag", `W/"v1"`)
+	client.Get("/").Query("etag", `W/"v1"`).Do().ExpectHeader("ETag", `W/"v1"`)
+	client.Get("/small").Query("etag", `"v1"`).Do().ExpectHeader("ETag", `"v1"`)
+}
@@ -1,10 +1,8 @@
 package util
 
 import (
-	"compress/gzip"
 	"net/http"
 	"strconv"
-	"strings"
 )
 
 type CloseableResponseWriter interface {
@@ -15,32 +13,26 @@ type CloseableResponseWriter interface {
 	SetStatusCode(int)
 }
 
-type gzipResponseWriter struct {
-	http.ResponseWriter
-	*gzip.Writer
-}
-
-func (this gzipResponseWriter) Write(data []byte) (int, error) {
-	return this.Writer.Write(data)
+// compressedResponseWriter is returned by GetResponseWriter when the client accepts
+// one of the supported encodings.
+type compressedResponseWriter struct {
+	*compressResponseWriter
 }
 
-func (this gzipResponseWriter) Close() {
-	this.Writer.Close()
+func (this compressedResponseWriter) Close() {
+	this.compressResponseWriter.Close()
 }
 
-func (this gzipResponseWriter) SetContentType(contentType string) {
-	this.ResponseWriter.Header().Set("Content-Type", contentType)
+func (this compressedResponseWriter) SetContentType(contentType string) {
+	this.Header().Set("Content-Type", contentType)
 }
 
-func (this gzipResponseWriter) SetCacheControl(TLL int, opts ...bool) {
+func (this compressedResponseWriter) SetCacheControl(TLL int, opts ...bool) {
 	setCacheControl(this, TLL, opts...)
 }
-func (this gzipResponseWriter) SetStatusCode(statusCode int) {
-	this.Header().Del("Content-Length")
-	this.ResponseWriter.WriteHeader(statusCode)
-}
-func (this gzipResponseWriter) Header() http.Header {
-	return this.ResponseWriter.Header()
+
+func (this compressedResponseWriter) SetStatusCode(statusCode int) {
+	this.WriteHeader(statusCode)
 }
 
 type closeableResponseWriter struct {
@@ -66,13 +58,11 @@ func GetResponseWriter(w http.ResponseWriter, r *http.Request, contentType strin
 		w.Header().Set("Content-Type", contentType)
 	}
 
-	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-		w.Header().Set("Content-Encoding", "gzip")
-		return gzipResponseWriter{ResponseWriter: w,
-			Writer: gzip.NewWriter(w)}
-	} else {
-		return closeableResponseWriter{ResponseWriter: w}
+	w.Header().Add("Vary", "Accept-Encoding")
+	if encoding := NegotiateEncoding(r.Header.Get("Accept-Encoding"), defaultCompressOptions.Encodings); encoding != "" && r.Method != "HEAD" {
+		return compressedResponseWriter{newCompressResponseWriter(w, encoding, defaultCompressOptions)}
 	}
+	return closeableResponseWriter{ResponseWriter: w}
 }
 func setCacheControl(crw CloseableResponseWriter, TLL int, opts ...bool) {
 	if TLL > 0 {
@@ -2,6 +2,7 @@ package util
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
@@ -21,6 +22,8 @@ import (
 	"image/png"
 
 	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/nehmeroumani/pill.go/health"
+	"github.com/nehmeroumani/pill.go/tracing"
 	"github.com/nfnt/resize"
 	"github.com/oliamb/cutter"
 	"github.com/parnurzeal/gorequest"
@@ -45,6 +48,7 @@ var (
 func InitUploader(baseUploadDirectryPath string, imgSizes map[string]map[string][]uint) {
 	imageSizes = imgSizes
 	baseUploadDirPath = filepath.FromSlash(baseUploadDirectryPath)
+	health.Register("uploads", health.WritableDirCheck(baseUploadDirPath))
 }
 
 type MultipleUpload struct {
@@ -125,14 +129,16 @@ func (this *MultipleUpload) Upload() (error, []string) {
 }
 
 func (this *MultipleUpload) UploadFromUrls() (error, []string) {
+	return this.UploadFromUrlsContext(context.Background())
+}
+
+// UploadFromUrlsContext is UploadFromUrls with a context, each fetch is recorded as a
+// span.
+func (this *MultipleUpload) UploadFromUrlsContext(ctx context.Context) (error, []string) {
 	if this.Urls != nil {
 		uploadedFilesNames := []string{}
 		for _, u := range this.Urls {
-			request := gorequest.New()
-			resp, body, errs := request.Get(u).End()
-			if errs != nil && len(errs) > 0 {
-				clean.Error(errs[0])
-			}
+			resp, body := fetch(ctx, u)
 			if body == "" {
 				return errors.New("file_not_found"), nil
 			}
@@ -189,6 +195,27 @@ func (this *MultipleUpload) UploadFromUrls() (error, []string) {
 	return errors.New("invalid_file_url"), nil
 }
 
+func fetch(ctx context.Context, u string) (gorequest.Response, string) {
+	ctx, span := tracing.StartSpan(ctx, "uploader.fetch", tracing.KindClient)
+	defer span.End()
+	span.SetAttribute("http.url", u)
+	header := http.Header{}
+	tracing.Inject(ctx, header)
+	request := gorequest.New().Get(u)
+	for key := range header {
+		request.Set(key, header.Get(key))
+	}
+	resp, body, errs := request.End()
+	if errs != nil && len(errs) > 0 {
+		clean.Error(errs[0])
+		span.SetError(errs[0])
+	}
+	if resp != nil {
+		span.SetAttribute("http.status_code", resp.StatusCode)
+	}
+	return resp, body
+}
+
 func (this *MultipleUpload) SetUploadDirectoryPath(directoryPath string) {
 	directoryPath = filepath.FromSlash(directoryPath)
 	this.uploadDirectoryPath = filepath.Join(baseUploadDirPath, directoryPath)
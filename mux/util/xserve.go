@@ -1,20 +1,34 @@
 package util
 
 import (
-	"bufio"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
-	"time"
+
+	"github.com/nehmeroumani/pill.go/assets"
+	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/nehmeroumani/pill.go/helpers"
 )
 
 var staticFilesPath, uploadsPath, appVersion string
 var staticFilesURLPath, uploadsURLPath string
 var staticFilesFromCloud, uploadsFromCloud bool
 var cacheTTL = 60 * 60 * 24 * 30 * 6
+var xServeOptions XServeOptions
+var assetsURLPrefix = "/static"
+
+type XServeOptions struct {
+	// SPAFallback is the file, relative to the static files path, served to the page
+	// requests matching no static file, e.g. "index.html" for a single page app. The
+	// missing files are answered with a 404 when it's empty.
+	SPAFallback string
+	// AssetsManifest is the manifest written by pill-assets, outside of the static
+	// files path; the fingerprinted names of its files are served and returned by
+	// the Asset template function.
+	AssetsManifest string
+}
 
 func InitXServe(StaticFilesPath string, StaticFilesURLPath string, StaticFilesFromCloud bool, UploadsPath string, UploadsURLPath string, UploadsFromCloud bool, AppVersion string) {
 	staticFilesPath = StaticFilesPath
@@ -24,6 +38,22 @@ func InitXServe(StaticFilesPath string, StaticFilesURLPath string, StaticFilesFr
 	uploadsURLPath = UploadsURLPath
 	uploadsFromCloud = UploadsFromCloud
 	appVersion = AppVersion
+	assetsURLPrefix = "/static"
+	if StaticFilesFromCloud {
+		assetsURLPrefix = StaticFilesURLPath
+	}
+	initAssets()
+}
+
+func SetXServeOptions(options XServeOptions) {
+	xServeOptions = options
+	initAssets()
+}
+
+func initAssets() {
+	if err := assets.Init(xServeOptions.AssetsManifest, assetsURLPrefix); err != nil {
+		clean.Error(err)
+	}
 }
 
 func StaticFilesServe(w http.ResponseWriter, r *http.Request) {
@@ -40,9 +70,9 @@ func xServe(w http.ResponseWriter, r *http.Request, filesPath string, filesURLPa
 		requestedFile = requestedFile[len("/public"):]
 	}
 	if isStatic {
-		requestedFile = requestedFile[len("/static"):]
+		requestedFile = strings.TrimPrefix(requestedFile, "/static")
 	} else {
-		requestedFile = requestedFile[len("/uploads"):]
+		requestedFile = strings.TrimPrefix(requestedFile, "/uploads")
 	}
 	if fromCloud {
 		requestedFile += "?" + r.URL.RawQuery
@@ -51,77 +81,76 @@ func xServe(w http.ResponseWriter, r *http.Request, filesPath string, filesURLPa
 		}
 		requestedFile += "app_version=" + url.QueryEscape(appVersion)
 		http.Redirect(w, r, filesURLPath+requestedFile, 307)
+		return
+	}
+	// the fingerprinted names are served from the original files
+	immutable := false
+	if isStatic {
+		if original, ok := assets.Original(requestedFile); ok {
+			requestedFile, immutable = "/"+original, true
+		}
+	}
+	name, info, err := helpers.ResolveStaticFile(filesPath, requestedFile)
+	if err != nil {
+		if isStatic && xServeOptions.SPAFallback != "" && helpers.IsSPARoute(r.Method, requestedFile, r.Header.Get("Accept")) {
+			if name, info, err = helpers.ResolveStaticFile(filesPath, xServeOptions.SPAFallback); err == nil {
+				// the index changes with every release
+				w.Header().Set("Cache-Control", "no-cache")
+				serveFile(w, r, name, info)
+				return
+			}
+		}
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	if immutable {
+		w.Header().Set("Cache-Control", assets.CacheControl)
 	} else {
-		f, err := os.Open(filesPath + filepath.FromSlash(requestedFile))
-		defer f.Close()
-
-		fileExtension := strings.ToLower(filepath.Ext(requestedFile))
+		setResponseWriterCacheControl(w, cacheTTL)
+	}
+	serveFile(w, r, name, info)
+}
 
-		if err == nil {
-			var contentType string
-			switch fileExtension {
-			case ".js":
-				contentType = "text/javascript"
-			case ".css":
-				contentType = "text/css"
-			case ".jpg":
-				contentType = "image/jpg"
-			case ".png":
-				contentType = "image/png"
-			case ".jpeg":
-				contentType = "image/jpeg"
-			case ".gif":
-				contentType = "image/gif"
-			case ".mp3":
-				contentType = "audio/mpeg"
-			case ".ogg":
-				contentType = "audio/ogg"
-			case ".woff":
-				contentType = "application/x-font-woff"
-			case ".ttf":
-				contentType = "application/font-sfnt"
-			case ".svg":
-				contentType = "image/svg+xml"
-			case ".eot":
-				contentType = "application/vnd.ms-fontobject"
-			case ".pdf":
-				contentType = "application/pdf"
-			case ".ipa":
-				contentType = "application/octet-stream"
-			case ".html":
-				contentType = "text/html"
-			case ".plist":
-				contentType = "application/x-plist"
-			case ".apk":
-				contentType = "application/vnd.android.package-archive"
-			default:
-				contentType = "text/plain"
-			}
-			w.Header().Set("Content-Type", contentType)
-			setResponseWriterCacheControl(w, cacheTTL)
-			cached := false
-			if fileInfo, infoErr := f.Stat(); infoErr == nil {
-				w.Header().Set("Content-Length", strconv.FormatInt(fileInfo.Size(), 10))
-				lastModifiedTime := fileInfo.ModTime().UTC()
-				w.Header().Set("Last-Modified", lastModifiedTime.Format(http.TimeFormat))
-				if modifiedSince := r.Header.Get("If-Modified-Since"); modifiedSince != "" {
-					if modifiedSinceTime, TPErr := time.Parse(http.TimeFormat, modifiedSince); TPErr == nil {
-						modifiedSinceTime = modifiedSinceTime.UTC()
-						if modifiedSinceTime.Equal(lastModifiedTime) {
-							w.WriteHeader(304)
-							w.Write([]byte{})
-							cached = true
-						}
-					}
-				}
-			}
-			if !cached {
-				bufferedReader := bufio.NewReader(f)
-				bufferedReader.WriteTo(w)
-			}
-		} else {
-			w.WriteHeader(404)
-			w.Write([]byte(http.StatusText(404)))
+// serveFile serves a file, or its precompressed sibling preferred by the client, with
+// http.ServeContent: byte ranges and conditional requests on its ETag and modification
+// time.
+func serveFile(w http.ResponseWriter, r *http.Request, name string, info os.FileInfo) {
+	contentType := helpers.StaticContentType(name)
+	served, servedInfo, encoding := name, info, ""
+	variant, variantInfo, variantEncoding, vary := helpers.PrecompressedVariant(name, info, r.Header.Get("Accept-Encoding"))
+	if variant != "" {
+		served, servedInfo, encoding = variant, variantInfo, variantEncoding
+		if contentType == "" {
+			// ServeContent would sniff the compressed bytes
+			contentType = sniffContentType(name)
 		}
 	}
+	f, err := os.Open(served)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	if vary {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	w.Header().Set("ETag", helpers.StaticETag(servedInfo, encoding))
+	http.ServeContent(w, r, name, servedInfo.ModTime(), f)
+}
+
+func sniffContentType(name string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	buffer := make([]byte, 512)
+	n, _ := io.ReadFull(f, buffer)
+	return http.DetectContentType(buffer[:n])
 }
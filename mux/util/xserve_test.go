@@ -0,0 +1,105 @@
+package util
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nehmeroumani/pill.go/assets"
+	"github.com/nehmeroumani/pill.go/pilltest"
+)
+
+// writeFiles creates the files of a map of slash-separated names to contents in dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newStaticClient(t *testing.T) *pilltest.Client {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"public/css/app.css":              "body{}",
+		"public/js/app.js":                "app()",
+		"public/.env":                     "SECRET=1",
+		"public/.well-known/security.txt": "contact",
+		"secret.txt":                      "secret",
+	})
+	root := filepath.Join(dir, "public")
+	// the sibling must not be older than the file
+	writeFiles(t, dir, map[string]string{"public/js/app.js.gz": "gzipped app()"})
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(root, "js/app.js.gz"), later, later)
+	if err := os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Skip("symlinks aren't supported: ", err)
+	}
+	InitXServe(root, "/static", false, filepath.Join(dir, "uploads"), "/uploads", false, "1")
+	return pilltest.New(t, http.HandlerFunc(StaticFilesServe))
+}
+
+func TestStaticFilesConfinement(t *testing.T) {
+	client := newStaticClient(t)
+	client.Get("/static/css/app.css").Do().ExpectStatus(http.StatusOK).ExpectBodyContains("body{}")
+	client.Get("/static/.well-known/security.txt").Do().ExpectStatus(http.StatusOK)
+	for _, path := range []string{"/static/../secret.txt", "/static/css/../../secret.txt", "/static/link.txt", "/static/.env", "/static/css/../.env"} {
+		response := client.Get(path).Do().ExpectStatus(http.StatusNotFound)
+		if strings.Contains(string(response.Body), "SECRET") || strings.Contains(string(response.Body), "secret") {
+			t.Errorf("%s: the file shouldn't be served", path)
+		}
+	}
+}
+
+func TestStaticFilesPrecompressed(t *testing.T) {
+	client := newStaticClient(t)
+	client.Get("/static/js/app.js").Header("Accept-Encoding", "gzip, br").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Encoding", "gzip").
+		ExpectHeader("Vary", "Accept-Encoding").
+		ExpectHeader("Content-Type", "text/javascript; charset=utf-8").
+		ExpectBodyContains("gzipped")
+	// the response still depends on the header
+	client.Get("/static/js/app.js").Do().
+		ExpectHeader("Content-Encoding", "").
+		ExpectHeader("Vary", "Accept-Encoding").
+		ExpectBodyContains("app()")
+	client.Get("/static/css/app.css").Header("Accept-Encoding", "gzip").Do().
+		ExpectHeader("Content-Encoding", "").
+		ExpectHeader("Vary", "")
+}
+
+func TestStaticFilesFingerprinted(t *testing.T) {
+	client := newStaticClient(t)
+	m, err := assets.Build(staticFilesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(t.TempDir(), assets.ManifestName)
+	if err = m.Save(name); err != nil {
+		t.Fatal(err)
+	}
+	SetXServeOptions(XServeOptions{AssetsManifest: name})
+	defer SetXServeOptions(XServeOptions{})
+
+	url := assets.Asset("js/app.js")
+	if url != "/static/"+m["js/app.js"] {
+		t.Fatalf("expected the fingerprinted URL, got %q", url)
+	}
+	client.Get(url).Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Cache-Control", assets.CacheControl).
+		ExpectBodyContains("app()")
+	response := client.Get("/static/js/app.js").Do().ExpectStatus(http.StatusOK)
+	if response.Header.Get("Cache-Control") == assets.CacheControl {
+		t.Errorf("the original name may change, it shouldn't be cached forever")
+	}
+	client.Get("/static/js/app.000000.js").Do().ExpectStatus(http.StatusNotFound)
+}
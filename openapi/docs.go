@@ -0,0 +1,75 @@
+package openapi
+
+import (
+	"bytes"
+	"html/template"
+)
+
+var docsPage = template.Must(template.New("docs").Parse(`<!doctype html>
+<html>
+<head>
+<title>{{.Title}}</title>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+{{- if not .ScriptURL}}
+<style>
+body{font-family:system-ui,sans-serif;max-width:960px;margin:0 auto;padding:1em;color:#222}
+.op{border:1px solid #ddd;border-radius:4px;margin:.5em 0}
+.op summary{cursor:pointer;padding:.5em}
+.op pre{background:#f6f6f6;margin:0;padding:.5em;overflow:auto}
+.method{display:inline-block;min-width:5em;font-weight:bold;text-transform:uppercase}
+</style>
+{{- end}}
+</head>
+<body>
+{{- if .ScriptURL}}
+<script id="api-reference" data-url="{{.SpecURL}}"></script>
+<script src="{{.ScriptURL}}"></script>
+{{- else}}
+<h1>{{.Title}}</h1>
+<div id="operations" data-url="{{.SpecURL}}"></div>
+<script>
+(function() {
+	var root = document.getElementById("operations");
+	function add(parent, tag, text) {
+		var el = document.createElement(tag);
+		if (text) el.textContent = text;
+		parent.appendChild(el);
+		return el;
+	}
+	fetch(root.dataset.url).then(function(res) { return res.json(); }).then(function(doc) {
+		if (doc.info && doc.info.description) add(root, "p", doc.info.description);
+		Object.keys(doc.paths).sort().forEach(function(path) {
+			Object.keys(doc.paths[path]).forEach(function(method) {
+				var op = doc.paths[path][method];
+				var details = add(root, "details");
+				details.className = "op";
+				var summary = add(details, "summary");
+				add(summary, "span", method).className = "method";
+				add(summary, "code", path);
+				if (op.summary) add(summary, "span", " " + op.summary);
+				if (op.description) add(details, "p", op.description);
+				add(details, "pre", JSON.stringify(op, null, 2));
+			});
+		});
+		if (doc.components) {
+			var details = add(root, "details");
+			details.className = "op";
+			add(details, "summary", "Schemas");
+			add(details, "pre", JSON.stringify(doc.components, null, 2));
+		}
+	}).catch(function(err) { add(root, "p", "Cannot load " + root.dataset.url + ": " + err); });
+})();
+</script>
+{{- end}}
+</body>
+</html>
+`))
+
+// DocsPage renders an HTML page displaying the document served at specURL. The page
+// renders the document by itself unless config.DocsScriptURL is set.
+func DocsPage(config Config, specURL string) []byte {
+	var buf bytes.Buffer
+	docsPage.Execute(&buf, map[string]string{"Title": config.Info.Title, "SpecURL": specURL, "ScriptURL": config.DocsScriptURL})
+	return buf.Bytes()
+}
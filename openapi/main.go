@@ -0,0 +1,211 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+const Version = "3.1.0"
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// Config describes where a mux serves its OpenAPI document.
+type Config struct {
+	Info    Info
+	Servers []Server
+	// Path of the JSON document, e.g. /openapi.json
+	Path string
+	// Path of the YAML document, e.g. /openapi.yaml; empty to disable it.
+	YAMLPath string
+	// Path of the docs page, e.g. /docs; empty to disable it.
+	DocsPath string
+	// Script rendering the docs page, e.g. https://cdn.jsdelivr.net/npm/@scalar/api-reference;
+	// empty to use the built-in renderer, which needs nothing outside the app.
+	DocsScriptURL string
+}
+
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []Server                        `json:"servers,omitempty"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components *Components                     `json:"components,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Generate builds the OpenAPI document of the given routes.
+func Generate(info Info, routes []helpers.RouteInfo, servers ...Server) *Document {
+	doc := &Document{OpenAPI: Version, Info: info, Servers: servers, Paths: map[string]map[string]Operation{}}
+	schemas := newSchemaRegistry()
+	for _, route := range routes {
+		path, params := convertPattern(route)
+		method := strings.ToLower(route.Method)
+		op := Operation{Summary: route.Summary, Description: route.Description, Tags: route.Tags, Parameters: params, Responses: map[string]Response{}}
+		if route.Name != "" {
+			op.OperationID = route.Name
+		} else {
+			op.OperationID = operationID(route.Method, route.Pattern)
+		}
+		if route.Request != nil {
+			if route.Method == "GET" || route.Method == "HEAD" || route.Method == "DELETE" {
+				op.Parameters = append(op.Parameters, schemas.queryParameters(route.Request)...)
+			} else {
+				op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"application/json": {Schema: schemas.schemaOf(route.Request)}}}
+			}
+		}
+		for status, t := range route.Responses {
+			response := Response{Description: http.StatusText(status)}
+			if t != nil {
+				response.Content = map[string]MediaType{"application/json": {Schema: schemas.schemaOf(t)}}
+			}
+			op.Responses[strconv.Itoa(status)] = response
+		}
+		if len(op.Responses) == 0 {
+			op.Responses["200"] = Response{Description: http.StatusText(http.StatusOK)}
+		}
+		if _, ok := doc.Paths[path]; !ok {
+			doc.Paths[path] = map[string]Operation{}
+		}
+		doc.Paths[path][method] = op
+	}
+	if len(schemas.schemas) > 0 {
+		doc.Components = &Components{Schemas: schemas.schemas}
+	}
+	return doc
+}
+
+func (this *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(this, "", "  ")
+}
+
+func (this *Document) YAML() ([]byte, error) {
+	data, err := json.Marshal(this)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToYAML(data)
+}
+
+// convertPattern turns an httptreemux pattern into an OpenAPI path template and its
+// path parameters, e.g. /users/:id -> /users/{id}. OpenAPI has no catch-all
+// parameters, so /files/*path becomes /files/{path} with a parameter described as
+// spanning the rest of the path; an unnamed catch-all is called "path".
+func convertPattern(route helpers.RouteInfo) (string, []Parameter) {
+	segments := strings.Split(route.Pattern, "/")
+	params := []Parameter{}
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		switch segment[0] {
+		case ':', '*':
+			name := segment[1:]
+			param := Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}}
+			if constraint, ok := route.Constraints[name]; ok {
+				param.Schema = constraintSchema(constraint)
+			}
+			if segment[0] == '*' {
+				if param.Name == "" {
+					param.Name = "path"
+				}
+				param.Description = "Rest of the path, slashes included; may be empty."
+			}
+			segments[i] = "{" + param.Name + "}"
+			params = append(params, param)
+		case '\\':
+			segments[i] = segment[1:]
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func constraintSchema(constraint helpers.Constraint) *Schema {
+	switch constraint.Kind {
+	case "int":
+		return &Schema{Type: "integer"}
+	case "uuid":
+		return &Schema{Type: "string", Format: "uuid"}
+	case "slug":
+		return &Schema{Type: "string", Pattern: `^[a-z0-9]+(?:-[a-z0-9]+)*$`}
+	case "regex":
+		if len(constraint.Values) > 0 {
+			return &Schema{Type: "string", Pattern: "^(?:" + constraint.Values[0] + ")$"}
+		}
+	case "enum":
+		values := make([]interface{}, len(constraint.Values))
+		for i, v := range constraint.Values {
+			values[i] = v
+		}
+		return &Schema{Type: "string", Enum: values}
+	}
+	return &Schema{Type: "string"}
+}
+
+func operationID(method string, pattern string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(pattern, "/") {
+		segment = strings.TrimLeft(segment, ":*\\")
+		if segment != "" {
+			id += strings.ToUpper(segment[:1]) + segment[1:]
+		}
+	}
+	return id
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
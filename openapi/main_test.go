@@ -0,0 +1,142 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+type createUser struct {
+	Name    string   `json:"name" description:"Full name"`
+	Email   string   `json:"email,omitempty"`
+	Age     *int     `json:"age"`
+	Address address  `json:"address"`
+	Tags    []string `json:"tags,omitempty"`
+	secret  string
+}
+
+type user struct {
+	ID      int      `json:"id"`
+	Friends []*user  `json:"friends,omitempty"`
+	Address *address `json:"address,omitempty"`
+}
+
+type listUsers struct {
+	Page  int    `query:"page"`
+	Order string `json:"order"`
+}
+
+func TestGenerate(t *testing.T) {
+	routes := []helpers.RouteInfo{
+		{Method: "GET", Pattern: "/users", Request: reflect.TypeOf(listUsers{}), Responses: map[int]reflect.Type{200: reflect.TypeOf([]user{})}},
+		{Method: "POST", Pattern: "/users", Name: "users.create", Summary: "Create a user", Tags: []string{"users"}, Request: reflect.TypeOf(createUser{}), Responses: map[int]reflect.Type{201: reflect.TypeOf(user{}), 400: nil}},
+		{Method: "GET", Pattern: "/users/:id", Constraints: map[string]helpers.Constraint{"id": helpers.IntConstraint}},
+		{Method: "GET", Pattern: "/files/*path"},
+		{Method: "GET", Pattern: "/raw/*"},
+		{Method: "GET", Pattern: "/\\:literal"},
+	}
+	doc := Generate(Info{Title: "API", Version: "1"}, routes, Server{URL: "https://example.com"})
+	data, err := doc.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	get := func(v interface{}, path ...string) interface{} {
+		for _, key := range path {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				t.Fatalf("no %v in %s", path, data)
+			}
+			v = m[key]
+		}
+		return v
+	}
+	if out["openapi"] != Version || get(out, "info", "title") != "API" {
+		t.Errorf("unexpected header %s", data)
+	}
+	paths := out["paths"].(map[string]interface{})
+	for _, path := range []string{"/users", "/users/{id}", "/files/{path}", "/raw/{path}", "/:literal"} {
+		if _, ok := paths[path]; !ok {
+			t.Errorf("missing path %s in %v", path, paths)
+		}
+	}
+
+	list := doc.Paths["/users"]["get"]
+	if list.OperationID != "getUsers" || len(list.Parameters) != 2 || list.Parameters[0].Name != "page" || list.Parameters[0].In != "query" || list.Parameters[1].Name != "order" {
+		t.Errorf("unexpected list operation %+v", list)
+	}
+	if items := list.Responses["200"].Content["application/json"].Schema; items.Type != "array" || items.Items.Ref != "#/components/schemas/user" {
+		t.Errorf("unexpected list response %+v", items)
+	}
+
+	create := doc.Paths["/users"]["post"]
+	if create.OperationID != "users.create" || create.Summary != "Create a user" || create.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/createUser" {
+		t.Errorf("unexpected create operation %+v", create)
+	}
+	if create.Responses["400"].Description != "Bad Request" || create.Responses["400"].Content != nil {
+		t.Errorf("unexpected 400 response %+v", create.Responses["400"])
+	}
+	schema := doc.Components.Schemas["createUser"]
+	if !reflect.DeepEqual(schema.Required, []string{"name", "address"}) || schema.Properties["name"].Description != "Full name" || len(schema.Properties) != 5 {
+		t.Errorf("unexpected createUser schema %+v", schema)
+	}
+	if friends := doc.Components.Schemas["user"].Properties["friends"]; friends.Items.Ref != "#/components/schemas/user" {
+		t.Errorf("recursive types should be referenced, got %+v", friends)
+	}
+
+	id := doc.Paths["/users/{id}"]["get"]
+	if len(id.Parameters) != 1 || id.Parameters[0].In != "path" || !id.Parameters[0].Required || id.Parameters[0].Schema.Type != "integer" || id.Responses["200"].Description != "OK" {
+		t.Errorf("unexpected path parameters %+v", id)
+	}
+	for path, name := range map[string]string{"/files/{path}": "path", "/raw/{path}": "path"} {
+		params := doc.Paths[path]["get"].Parameters
+		if len(params) != 1 || params[0].Name != name || params[0].Description == "" {
+			t.Errorf("the catch-all of %s should be documented as such, got %+v", path, params)
+		}
+	}
+}
+
+func TestGenerateWithoutSchemas(t *testing.T) {
+	doc := Generate(Info{Title: "API", Version: "1"}, []helpers.RouteInfo{{Method: "GET", Pattern: "/"}})
+	data, err := doc.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "components") {
+		t.Errorf("empty components should be omitted, got %s", data)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	doc := Generate(Info{Title: "API: v1", Version: "1"}, []helpers.RouteInfo{{Method: "GET", Pattern: "/users/:id", Tags: []string{"users"}}})
+	data, err := doc.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{`openapi: 3.1.0`, `  title: "API: v1"`, `  "/users/{id}":`, `    get:`, `        - users`} {
+		if !strings.Contains(string(data), line+"\n") {
+			t.Errorf("missing %q in\n%s", line, data)
+		}
+	}
+}
+
+func TestDocsPage(t *testing.T) {
+	page := string(DocsPage(Config{Info: Info{Title: "<API>"}}, "/openapi.json"))
+	if strings.Contains(page, "<script src=") || !strings.Contains(page, `data-url="/openapi.json"`) || !strings.Contains(page, "<title>&lt;API&gt;</title>") {
+		t.Errorf("the default page should render the document by itself, got\n%s", page)
+	}
+	page = string(DocsPage(Config{DocsScriptURL: "https://example.com/docs.js"}, "/openapi.json"))
+	if !strings.Contains(page, `<script src="https://example.com/docs.js">`) {
+		t.Errorf("the page should load the configured script, got\n%s", page)
+	}
+}
@@ -0,0 +1,103 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// jsonToYAML converts a JSON document to block style YAML.
+func jsonToYAML(data []byte) ([]byte, error) {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeYAML(&buf, v, 0)
+	return buf.Bytes(), nil
+}
+
+func writeYAML(buf *bytes.Buffer, v interface{}, indent int) {
+	prefix := strings.Repeat("  ", indent)
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			buf.WriteString(prefix + "{}\n")
+			return
+		}
+		for _, key := range sortedKeys(value) {
+			buf.WriteString(prefix + yamlString(key) + ":")
+			writeYAMLValue(buf, value[key], indent+1)
+		}
+	case []interface{}:
+		if len(value) == 0 {
+			buf.WriteString(prefix + "[]\n")
+			return
+		}
+		for _, item := range value {
+			if m, ok := item.(map[string]interface{}); ok && len(m) > 0 {
+				// the first key of a map item goes on the line of its dash
+				var item bytes.Buffer
+				writeYAML(&item, m, indent+1)
+				buf.WriteString(prefix + "- " + strings.TrimPrefix(item.String(), prefix+"  "))
+				continue
+			}
+			buf.WriteString(prefix + "-")
+			writeYAMLValue(buf, item, indent+1)
+		}
+	default:
+		buf.WriteString(prefix + yamlScalar(value) + "\n")
+	}
+}
+
+func writeYAMLValue(buf *bytes.Buffer, v interface{}, indent int) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			buf.WriteString(" {}\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeYAML(buf, value, indent)
+	case []interface{}:
+		if len(value) == 0 {
+			buf.WriteString(" []\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeYAML(buf, value, indent)
+	default:
+		buf.WriteString(" " + yamlScalar(value) + "\n")
+	}
+}
+
+func yamlScalar(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(value)
+	case json.Number:
+		return value.String()
+	case string:
+		return yamlString(value)
+	}
+	return ""
+}
+
+// yamlString quotes the strings that YAML would otherwise read as another type or
+// misparse; JSON strings are valid double quoted YAML strings.
+func yamlString(s string) string {
+	switch strings.ToLower(s) {
+	case "", "null", "~", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil || strings.ContainsAny(s, ":#{}[],&*!|>'\"%@`\n\t\\") || strings.TrimSpace(s) != s || strings.HasPrefix(s, "-") || strings.HasPrefix(s, "?") {
+		b, _ := json.Marshal(s)
+		return string(b)
+	}
+	return s
+}
@@ -0,0 +1,181 @@
+package pilltest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/antiCSRF"
+	"github.com/nehmeroumani/pill.go/auth"
+	"github.com/valyala/fasthttp"
+)
+
+// BaseURL is the origin requests are sent to, its host is the one the cookies are
+// stored for.
+var BaseURL = "http://example.com"
+
+// Client sends requests to a mux.Mux (or any http.Handler) or to a fastmux.Mux
+// without a network, keeping the cookies they set between calls.
+type Client struct {
+	t           testing.TB
+	handler     http.Handler
+	fastHandler fasthttp.RequestHandler
+	jar         *cookiejar.Jar
+	header      http.Header
+	baseURL     *url.URL
+	// AutoCSRF sends the masked csrf token in the X-Csrf-Token header of the unsafe
+	// requests when a csrf_base_token cookie was set, true by default.
+	AutoCSRF bool
+}
+
+func newClient(t testing.TB) *Client {
+	jar, _ := cookiejar.New(nil)
+	baseURL, err := url.Parse(BaseURL)
+	if err != nil {
+		t.Fatalf("pilltest: invalid base URL: %v", err)
+	}
+	return &Client{t: t, jar: jar, header: http.Header{}, baseURL: baseURL, AutoCSRF: true}
+}
+
+// New returns a client for a net/http handler, e.g. a mux.Mux or a mux.GlobalRouter.
+func New(t testing.TB, handler http.Handler) *Client {
+	this := newClient(t)
+	this.handler = handler
+	return this
+}
+
+// NewFastHttp returns a client for a fasthttp handler, e.g. fastMux.ServeHTTP.
+func NewFastHttp(t testing.TB, handler fasthttp.RequestHandler) *Client {
+	this := newClient(t)
+	this.fastHandler = handler
+	return this
+}
+
+// SetHeader sets a header sent with every request.
+func (this *Client) SetHeader(key string, value string) *Client {
+	this.header.Set(key, value)
+	return this
+}
+
+// InitAuth initializes auth with a key pair generated for the test, for LoginAs and
+// the handlers checking the access tokens without the key files of the app.
+func InitAuth(t testing.TB) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("pilltest: can't generate the auth key: %v", err)
+	}
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("pilltest: can't encode the auth key: %v", err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("pilltest: can't encode the auth key: %v", err)
+	}
+	dir := t.TempDir()
+	for name, block := range map[string]*pem.Block{
+		"private.pem": {Type: "PRIVATE KEY", Bytes: privateKey},
+		"public.pem":  {Type: "PUBLIC KEY", Bytes: publicKey},
+	} {
+		if err = os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatalf("pilltest: can't write the auth key: %v", err)
+		}
+	}
+	auth.JWTAuth = nil
+	auth.Init(filepath.Join(dir, "private.pem"), filepath.Join(dir, "public.pem"), "example.com", false)
+}
+
+// LoginAs generates an auth token for the user and stores it in the access_token
+// cookie, auth must be initialized, e.g. with InitAuth.
+func (this *Client) LoginAs(userID int, role string) *Client {
+	this.t.Helper()
+	token, err := auth.GetJWTAuth().GenerateToken(userID, role)
+	if err != nil {
+		this.t.Fatalf("pilltest: can't generate the auth token: %v", err)
+	}
+	return this.WithToken(token)
+}
+
+// WithToken stores token in the access_token cookie.
+func (this *Client) WithToken(token string) *Client {
+	this.SetCookie(&http.Cookie{Name: "access_token", Value: token, Path: "/"})
+	return this
+}
+
+// Logout removes the access_token cookie.
+func (this *Client) Logout() *Client {
+	this.SetCookie(&http.Cookie{Name: "access_token", Value: "", Path: "/", MaxAge: -1})
+	return this
+}
+
+func (this *Client) SetCookie(cookie *http.Cookie) {
+	this.jar.SetCookies(this.baseURL, []*http.Cookie{cookie})
+}
+
+// Cookie returns the value of a stored cookie, or "".
+func (this *Client) Cookie(name string) string {
+	for _, cookie := range this.jar.Cookies(this.baseURL) {
+		if cookie.Name == name {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// CSRFToken returns a masked token for the csrf_base_token cookie, as it would be
+// rendered in a form, or "" when there's no such cookie.
+func (this *Client) CSRFToken() string {
+	req := &http.Request{Header: http.Header{}}
+	for _, cookie := range this.jar.Cookies(this.baseURL) {
+		req.AddCookie(cookie)
+	}
+	token := antiCSRF.GetRequestCSRFToken(req)
+	if token == nil {
+		return ""
+	}
+	return token.WithMask()
+}
+
+func (this *Client) Get(path string) *Request {
+	return this.NewRequest("GET", path)
+}
+
+func (this *Client) Head(path string) *Request {
+	return this.NewRequest("HEAD", path)
+}
+
+func (this *Client) Post(path string) *Request {
+	return this.NewRequest("POST", path)
+}
+
+func (this *Client) Put(path string) *Request {
+	return this.NewRequest("PUT", path)
+}
+
+func (this *Client) Patch(path string) *Request {
+	return this.NewRequest("PATCH", path)
+}
+
+func (this *Client) Delete(path string) *Request {
+	return this.NewRequest("DELETE", path)
+}
+
+func (this *Client) Options(path string) *Request {
+	return this.NewRequest("OPTIONS", path)
+}
+
+func (this *Client) NewRequest(method string, path string) *Request {
+	header := http.Header{}
+	for key, values := range this.header {
+		header[key] = append([]string(nil), values...)
+	}
+	return &Request{client: this, method: method, path: path, header: header, query: url.Values{}}
+}
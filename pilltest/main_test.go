@@ -0,0 +1,176 @@
+package pilltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/antiCSRF"
+	"github.com/nehmeroumani/pill.go/auth"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// echo describes the request it receives as JSON.
+type echo struct {
+	Method      string
+	Path        string
+	Query       map[string][]string
+	Form        map[string][]string
+	Files       map[string]string
+	Body        string
+	ContentType string
+	Cookies     map[string]string
+	Host        string
+	User        int
+	ValidCSRF   bool
+}
+
+func echoHandler(w http.ResponseWriter, req *http.Request) {
+	e := echo{Method: req.Method, Path: req.URL.Path, Query: req.URL.Query(), ContentType: req.Header.Get("Content-Type"), Cookies: map[string]string{}, Files: map[string]string{}, Host: req.Host}
+	for _, cookie := range req.Cookies() {
+		e.Cookies[cookie.Name] = cookie.Value
+	}
+	if strings.HasPrefix(e.ContentType, "multipart/form-data") {
+		req.ParseMultipartForm(1 << 20)
+		for field, headers := range req.MultipartForm.File {
+			f, _ := headers[0].Open()
+			content, _ := ioutil.ReadAll(f)
+			f.Close()
+			e.Files[field] = headers[0].Filename + ":" + string(content)
+		}
+		e.Form = req.MultipartForm.Value
+	} else if e.ContentType == "application/x-www-form-urlencoded" {
+		req.ParseForm()
+		e.Form = req.PostForm
+	} else {
+		body, _ := ioutil.ReadAll(req.Body)
+		e.Body = string(body)
+	}
+	if cookie, err := req.Cookie("access_token"); err == nil {
+		_, e.User, _ = auth.IsAuthenticated(cookie.Value)
+	}
+	if token := antiCSRF.GetRequestCSRFToken(req); token != nil {
+		e.ValidCSRF = token.IsValidRequestToken()
+	}
+	switch req.URL.Path {
+	case "/login":
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/"})
+		antiCSRF.NewCSRFToken().SetCookie(w)
+	case "/logout":
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "", Path: "/", MaxAge: -1})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
+
+func clients(t *testing.T) map[string]*Client {
+	handler := http.HandlerFunc(echoHandler)
+	return map[string]*Client{
+		"net/http": New(t, handler),
+		"fasthttp": NewFastHttp(t, fasthttpadaptor.NewFastHTTPHandler(handler)),
+	}
+}
+
+func TestRequests(t *testing.T) {
+	for name, client := range clients(t) {
+		t.Run(name, func(t *testing.T) {
+			client.SetHeader("X-Client", "pilltest")
+			client.Get("/users?sort=name").Query("page", "2").Do().
+				ExpectStatus(http.StatusOK).
+				ExpectHeader("Content-Type", "application/json").
+				ExpectJSON("Method", "GET").
+				ExpectJSON("Path", "/users").
+				ExpectJSON("Query.sort", []string{"name"}).
+				ExpectJSON("Query.page", []string{"2"}).
+				ExpectJSON("Host", "example.com")
+			client.Post("/users").JSON(map[string]interface{}{"name": "Ada"}).Do().
+				ExpectJSON("ContentType", "application/json").
+				ExpectJSON("Body", `{"name":"Ada"}`)
+			client.Put("/users/1").Field("name", "Ada").Field("tags", "a").Field("tags", "b").Do().
+				ExpectJSON("Method", "PUT").
+				ExpectJSON("Form.tags", []string{"a", "b"})
+			client.Post("/avatars").Field("name", "Ada").File("avatar", "ada.png", []byte("png")).Do().
+				ExpectJSON("Form.name", []string{"Ada"}).
+				ExpectJSON("Files.avatar", "ada.png:png")
+			var e echo
+			client.Delete("/users/1").Do().DecodeJSON(&e)
+			if e.Method != "DELETE" {
+				t.Errorf("expected a DELETE request, got %+v", e)
+			}
+		})
+	}
+}
+
+func TestCookies(t *testing.T) {
+	antiCSRF.Init(32, "example.com")
+	for name, client := range clients(t) {
+		t.Run(name, func(t *testing.T) {
+			client.Post("/users").Do().ExpectJSON("ValidCSRF", false)
+			client.Get("/login").Do().ExpectCookie("session").ExpectCookie("csrf_base_token")
+			if client.Cookie("session") != "s1" || client.CSRFToken() == "" {
+				t.Fatalf("the cookies should be stored, got %q", client.Cookie("session"))
+			}
+			client.Get("/me").Do().ExpectJSON("Cookies.session", "s1")
+			// the masked token is sent with the unsafe requests
+			client.Post("/users").Do().ExpectJSON("ValidCSRF", true)
+			client.AutoCSRF = false
+			client.Post("/users").Do().ExpectJSON("ValidCSRF", false)
+			client.Get("/logout").Do()
+			if response := client.Get("/me").Do(); strings.Contains(string(response.Body), `"session"`) {
+				t.Errorf("the removed cookie shouldn't be sent: %s", response.Body)
+			}
+		})
+	}
+}
+
+func TestLoginAs(t *testing.T) {
+	InitAuth(t)
+	for name, client := range clients(t) {
+		t.Run(name, func(t *testing.T) {
+			client.LoginAs(42, "admin").Get("/me").Do().ExpectJSON("User", 42)
+			client.Logout().Get("/me").Do().ExpectJSON("User", 0)
+		})
+	}
+}
+
+// failures records the failures of the expectations.
+type failures struct {
+	testing.TB
+	messages []string
+}
+
+func (this *failures) Helper() {}
+
+func (this *failures) Errorf(format string, args ...interface{}) {
+	this.messages = append(this.messages, fmt.Sprintf(format, args...))
+}
+
+func TestExpectations(t *testing.T) {
+	recorded := &failures{TB: t}
+	response := &Response{t: recorded, StatusCode: http.StatusOK, Header: http.Header{"X-A": {"1", "2"}}, Body: []byte(`{"data": {"users": [{"name": "Ada"}]}}`)}
+	response.ExpectStatus(http.StatusOK).
+		ExpectHeader("X-A", "1").
+		ExpectHeaderContains("X-A", "2").
+		ExpectBodyContains("Ada").
+		ExpectJSON("data.users.0.name", "Ada").
+		ExpectJSON("data.users", []map[string]string{{"name": "Ada"}})
+	if len(recorded.messages) != 0 {
+		t.Fatalf("the expectations should be met, got %v", recorded.messages)
+	}
+	response.ExpectStatus(http.StatusCreated).
+		ExpectHeader("X-A", "2").
+		ExpectHeaderContains("X-B", "1").
+		ExpectCookie("session").
+		ExpectBodyContains("Grace").
+		ExpectJSON("data.users.1.name", "Ada").
+		ExpectJSON("data.users.0.name", "Grace")
+	if len(recorded.messages) != 7 {
+		t.Fatalf("every expectation should fail, got %v", recorded.messages)
+	}
+	if _, ok := response.JSONPath("data.users.x"); ok {
+		t.Error("an array should be walked by index")
+	}
+}
@@ -0,0 +1,207 @@
+package pilltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/nehmeroumani/pill.go/antiCSRF"
+	"github.com/valyala/fasthttp"
+)
+
+type Request struct {
+	client      *Client
+	method      string
+	host        string
+	path        string
+	header      http.Header
+	query       url.Values
+	body        []byte
+	contentType string
+	fields      map[string][]string
+	files       []file
+	multipart   bool
+}
+
+type file struct {
+	field    string
+	filename string
+	content  []byte
+}
+
+func (this *Request) Header(key string, value string) *Request {
+	this.header.Set(key, value)
+	return this
+}
+
+// Host sends the request to another host than the one of the client, e.g. to test
+// host-based routing; the cookies are those of that host.
+func (this *Request) Host(host string) *Request {
+	this.host = host
+	return this
+}
+
+func (this *Request) Query(key string, value string) *Request {
+	this.query.Add(key, value)
+	return this
+}
+
+// Body sends raw data with the given content type.
+func (this *Request) Body(contentType string, body []byte) *Request {
+	this.contentType, this.body = contentType, body
+	return this
+}
+
+func (this *Request) JSON(v interface{}) *Request {
+	this.client.t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		this.client.t.Fatalf("pilltest: can't encode the JSON body: %v", err)
+	}
+	return this.Body("application/json", body)
+}
+
+// Form sends values as an urlencoded form, or as multipart fields when files are
+// attached.
+func (this *Request) Form(values url.Values) *Request {
+	if this.fields == nil {
+		this.fields = map[string][]string{}
+	}
+	for key, vs := range values {
+		this.fields[key] = append(this.fields[key], vs...)
+	}
+	return this
+}
+
+// Field adds a form field.
+func (this *Request) Field(key string, value string) *Request {
+	return this.Form(url.Values{key: {value}})
+}
+
+// File attaches a file, making the body multipart.
+func (this *Request) File(field string, filename string, content []byte) *Request {
+	this.files = append(this.files, file{field: field, filename: filename, content: content})
+	this.multipart = true
+	return this
+}
+
+// Multipart sends the form fields as multipart even without files.
+func (this *Request) Multipart() *Request {
+	this.multipart = true
+	return this
+}
+
+func (this *Request) encodeBody() {
+	this.client.t.Helper()
+	if this.fields == nil && len(this.files) == 0 {
+		return
+	}
+	if !this.multipart {
+		this.Body("application/x-www-form-urlencoded", []byte(url.Values(this.fields).Encode()))
+		return
+	}
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for key, values := range this.fields {
+		for _, value := range values {
+			writer.WriteField(key, value)
+		}
+	}
+	for _, f := range this.files {
+		part, err := writer.CreateFormFile(f.field, f.filename)
+		if err == nil {
+			_, err = part.Write(f.content)
+		}
+		if err != nil {
+			this.client.t.Fatalf("pilltest: can't write the file %s: %v", f.filename, err)
+		}
+	}
+	writer.Close()
+	this.Body(writer.FormDataContentType(), body.Bytes())
+}
+
+// Do sends the request and stores the cookies of the response.
+func (this *Request) Do() *Response {
+	this.client.t.Helper()
+	this.encodeBody()
+	client := this.client
+	target := *client.baseURL
+	if this.host != "" {
+		target.Host = this.host
+	}
+	target.Path = this.path
+	if i := strings.Index(this.path, "?"); i >= 0 {
+		target.Path, target.RawQuery = this.path[:i], this.path[i+1:]
+	}
+	if len(this.query) > 0 {
+		query := target.Query()
+		for key, values := range this.query {
+			query[key] = append(query[key], values...)
+		}
+		target.RawQuery = query.Encode()
+	}
+	if this.contentType != "" {
+		this.header.Set("Content-Type", this.contentType)
+	}
+	cookies := client.jar.Cookies(&target)
+	if client.AutoCSRF && !antiCSRF.IsSafeMethod(this.method) && this.method != "GET" && this.header.Get("X-Csrf-Token") == "" {
+		if token := client.CSRFToken(); token != "" {
+			this.header.Set("X-Csrf-Token", token)
+		}
+	}
+
+	var response *Response
+	if client.handler != nil {
+		response = this.serveHTTP(&target, cookies)
+	} else {
+		response = this.serveFastHttp(&target, cookies)
+	}
+	client.jar.SetCookies(&target, (&http.Response{Header: response.Header}).Cookies())
+	return response
+}
+
+func (this *Request) serveHTTP(target *url.URL, cookies []*http.Cookie) *Response {
+	// the routers match the request URI, which must not be in the absolute form
+	req := httptest.NewRequest(this.method, target.RequestURI(), bytes.NewReader(this.body))
+	req.Host = target.Host
+	for key, values := range this.header {
+		req.Header[key] = values
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	this.client.handler.ServeHTTP(recorder, req)
+	result := recorder.Result()
+	return &Response{t: this.client.t, StatusCode: result.StatusCode, Header: result.Header, Body: recorder.Body.Bytes()}
+}
+
+func (this *Request) serveFastHttp(target *url.URL, cookies []*http.Cookie) *Response {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(this.method)
+	req.SetRequestURI(target.RequestURI())
+	req.Header.SetHost(target.Host)
+	for key, values := range this.header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	for _, cookie := range cookies {
+		req.Header.SetCookie(cookie.Name, cookie.Value)
+	}
+	req.SetBody(this.body)
+	requestCtx := &fasthttp.RequestCtx{}
+	requestCtx.Init(req, nil, nil)
+	this.client.fastHandler(requestCtx)
+
+	header := http.Header{}
+	requestCtx.Response.Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+	body := append([]byte(nil), requestCtx.Response.Body()...)
+	return &Response{t: this.client.t, StatusCode: requestCtx.Response.StatusCode(), Header: header, Body: body}
+}
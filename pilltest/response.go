@@ -0,0 +1,150 @@
+package pilltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/templates"
+)
+
+// Response is a recorded response, its Expect methods report failures to the test
+// and return the response so they can be chained.
+type Response struct {
+	t          testing.TB
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (this *Response) ExpectStatus(statusCode int) *Response {
+	this.t.Helper()
+	if this.StatusCode != statusCode {
+		this.t.Errorf("expected status %d, got %d: %s", statusCode, this.StatusCode, this.excerpt())
+	}
+	return this
+}
+
+func (this *Response) ExpectHeader(key string, value string) *Response {
+	this.t.Helper()
+	if actual := this.Header.Get(key); actual != value {
+		this.t.Errorf("expected header %s to be %q, got %q", key, value, actual)
+	}
+	return this
+}
+
+func (this *Response) ExpectHeaderContains(key string, value string) *Response {
+	this.t.Helper()
+	if actual := strings.Join(this.Header.Values(key), ", "); !strings.Contains(actual, value) {
+		this.t.Errorf("expected header %s to contain %q, got %q", key, value, actual)
+	}
+	return this
+}
+
+func (this *Response) ExpectCookie(name string) *Response {
+	this.t.Helper()
+	for _, cookie := range (&http.Response{Header: this.Header}).Cookies() {
+		if cookie.Name == name {
+			return this
+		}
+	}
+	this.t.Errorf("expected the cookie %s to be set", name)
+	return this
+}
+
+func (this *Response) ExpectBodyContains(s string) *Response {
+	this.t.Helper()
+	if !bytes.Contains(this.Body, []byte(s)) {
+		this.t.Errorf("expected the body to contain %q: %s", s, this.excerpt())
+	}
+	return this
+}
+
+// ExpectJSON compares the value at path, e.g. "data.users.0.name", with expected once
+// both are encoded to JSON; an empty path is the whole body.
+func (this *Response) ExpectJSON(path string, expected interface{}) *Response {
+	this.t.Helper()
+	actual, ok := this.JSONPath(path)
+	if !ok {
+		this.t.Errorf("expected a JSON value at %q: %s", path, this.excerpt())
+		return this
+	}
+	expectedJSON, err := json.Marshal(expected)
+	if err != nil {
+		this.t.Fatalf("pilltest: can't encode the expected value: %v", err)
+	}
+	var normalized interface{}
+	json.Unmarshal(expectedJSON, &normalized)
+	if !reflect.DeepEqual(actual, normalized) {
+		actualJSON, _ := json.Marshal(actual)
+		this.t.Errorf("expected %s at %q, got %s", expectedJSON, path, actualJSON)
+	}
+	return this
+}
+
+// JSONPath returns the value at path in the JSON body, objects are walked by key and
+// arrays by index.
+func (this *Response) JSONPath(path string) (interface{}, bool) {
+	var value interface{}
+	if err := json.Unmarshal(this.Body, &value); err != nil {
+		return nil, false
+	}
+	if path == "" {
+		return value, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if value, ok = v[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// DecodeJSON decodes the body into v.
+func (this *Response) DecodeJSON(v interface{}) *Response {
+	this.t.Helper()
+	if err := json.Unmarshal(this.Body, v); err != nil {
+		this.t.Errorf("can't decode the JSON body: %v: %s", err, this.excerpt())
+	}
+	return this
+}
+
+// ExpectTemplate checks that the body is the template named name rendered with data.
+func (this *Response) ExpectTemplate(name string, data interface{}) *Response {
+	this.t.Helper()
+	tmpl := templates.GetTemplate(name)
+	if tmpl == nil {
+		this.t.Fatalf("pilltest: template %q not found", name)
+	}
+	expected := &bytes.Buffer{}
+	if err := tmpl.Execute(expected, data); err != nil {
+		this.t.Fatalf("pilltest: can't render the template %q: %v", name, err)
+	}
+	if !bytes.Equal(this.Body, expected.Bytes()) {
+		this.t.Errorf("expected the template %q to be rendered, got: %s", name, this.excerpt())
+	}
+	return this
+}
+
+func (this *Response) excerpt() string {
+	if len(this.Body) > 512 {
+		return string(this.Body[:512]) + "…"
+	}
+	return string(this.Body)
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/nehmeroumani/pill.go/auth"
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/nehmeroumani/pill.go/middleware"
+	"github.com/valyala/fasthttp"
+)
+
+// ByIP limits per client IP, as resolved by the middleware.RealIP middleware.
+func ByIP(req *http.Request) string {
+	return "ip:" + middleware.GetClientIP(req)
+}
+
+func FastHttpByIP(requestCtx *fasthttp.RequestCtx) string {
+	return "ip:" + middleware.GetFastHttpClientIP(requestCtx)
+}
+
+// ByHeader limits per value of a header, e.g. ByHeader("X-API-Key"). The requests
+// without the header aren't limited.
+func ByHeader(header string) func(*http.Request) string {
+	return func(req *http.Request) string {
+		if value := req.Header.Get(header); value != "" {
+			return "header:" + header + ":" + value
+		}
+		return ""
+	}
+}
+
+func FastHttpByHeader(header string) func(*fasthttp.RequestCtx) string {
+	return func(requestCtx *fasthttp.RequestCtx) string {
+		if value := helpers.BytesToString(requestCtx.Request.Header.Peek(header)); value != "" {
+			return "header:" + header + ":" + value
+		}
+		return ""
+	}
+}
+
+// ByUser limits per user, the subject of the auth access token of the request, and
+// per IP for anonymous requests.
+func ByUser(req *http.Request) string {
+	if ok, userID, _ := auth.IsAuthenticated(auth.RequestToken(req)); ok {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return ByIP(req)
+}
+
+func FastHttpByUser(requestCtx *fasthttp.RequestCtx) string {
+	if ok, userID, _ := auth.IsAuthenticated(auth.FastHttpRequestToken(requestCtx)); ok {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return FastHttpByIP(requestCtx)
+}
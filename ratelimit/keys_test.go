@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/auth"
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestByUser(t *testing.T) {
+	pilltest.InitAuth(t)
+	token, err := auth.GetJWTAuth().GenerateToken(42, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			key := ByUser(req)
+			// the body is left to the handler
+			body, _ := io.ReadAll(req.Body)
+			w.Write([]byte(key + " " + string(body[:4])))
+		})),
+		"fasthttp": pilltest.NewFastHttp(t, func(requestCtx *fasthttp.RequestCtx) {
+			requestCtx.WriteString(FastHttpByUser(requestCtx) + " " + string(requestCtx.PostBody()[:4]))
+		}),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			client.Post("/").Header("Authorization", "Bearer "+token).Field("a", "b").Do().ExpectBodyContains("user:42 ")
+			client.Post("/").Query("access_token", token).Field("a", "b").Do().ExpectBodyContains("user:42 ")
+			client.SetCookie(&http.Cookie{Name: "access_token", Value: token})
+			client.Post("/").Field("a", "b").Do().ExpectBodyContains("user:42 ")
+			client.Logout()
+			// the token of the body isn't looked for
+			client.Post("/").Field("access_token", token).Multipart().Do().ExpectBodyContains("ip:")
+			client.Post("/").Header("Authorization", "Bearer "+token[:len(token)-2]+"xx").Field("a", "b").Do().ExpectBodyContains("ip:")
+		})
+	}
+}
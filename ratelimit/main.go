@@ -0,0 +1,232 @@
+// Package ratelimit limits the requests per key (client IP, user, API key...) with a
+// sliding window or a token bucket, stored in memory or in Redis.
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/fastchain"
+	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/valyala/fasthttp"
+)
+
+type Algorithm int
+
+const (
+	// SlidingWindow weights the count of the previous window by its overlap with the
+	// sliding window, which smooths the bursts at the windows boundaries.
+	SlidingWindow Algorithm = iota
+	// TokenBucket refills Limit tokens per Period up to Burst tokens.
+	TokenBucket
+)
+
+type Rate struct {
+	Limit  int
+	Period time.Duration
+	// Burst is the capacity of the token bucket, it defaults to Limit.
+	Burst int
+}
+
+func PerSecond(limit int) Rate {
+	return Rate{Limit: limit, Period: time.Second}
+}
+
+func PerMinute(limit int) Rate {
+	return Rate{Limit: limit, Period: time.Minute}
+}
+
+func PerHour(limit int) Rate {
+	return Rate{Limit: limit, Period: time.Hour}
+}
+
+func (this Rate) capacity() int {
+	if this.Burst > 0 {
+		return this.Burst
+	}
+	return this.Limit
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time left before the quota is fully restored.
+	Reset time.Duration
+	// RetryAfter is the time left before a denied request could be allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the state of the limiters and takes one request from the quota of a key.
+type Store interface {
+	Take(key string, rate Rate, algorithm Algorithm, now time.Time) (Result, error)
+}
+
+type Options struct {
+	Rate      Rate
+	Algorithm Algorithm
+	// Store defaults to a memory store shared by the limiters of the process.
+	Store Store
+	// Prefix separates the keys of the limiters sharing a store.
+	Prefix string
+	// KeyFunc identifies the client of a request, it defaults to ByIP. Requests with an
+	// empty key aren't limited.
+	KeyFunc         func(*http.Request) string
+	FastHttpKeyFunc func(*fasthttp.RequestCtx) string
+	// LimitedHandler answers the limited requests, after the Retry-After header is set.
+	LimitedHandler         http.HandlerFunc
+	FastHttpLimitedHandler fasthttp.RequestHandler
+	// FailClosed denies the requests when the store fails, instead of allowing them.
+	FailClosed bool
+}
+
+var (
+	defaultStore     Store
+	defaultStoreOnce sync.Once
+)
+
+// prepare validates the options and resolves their store, before the middleware
+// handles any request.
+func (this *Options) prepare() {
+	if this.Rate.Limit <= 0 || this.Rate.Period <= 0 {
+		panic("ratelimit: the limit and the period of the rate must be positive")
+	}
+	if this.Store == nil {
+		defaultStoreOnce.Do(func() {
+			defaultStore = NewMemoryStore()
+		})
+		this.Store = defaultStore
+	}
+}
+
+func (this *Options) take(key string) (Result, bool) {
+	result, err := this.Store.Take(this.Prefix+key, this.Rate, this.Algorithm, time.Now())
+	if err != nil {
+		clean.Error(err)
+		return Result{Allowed: !this.FailClosed, Limit: this.Rate.Limit}, false
+	}
+	return result, true
+}
+
+// headers returns the RateLimit-* headers of a result, see
+// https://datatracker.ietf.org/doc/draft-ietf-httpapi-ratelimit-headers/
+func (this *Options) headers(result Result) map[string]string {
+	headers := map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(result.Limit),
+		"RateLimit-Remaining": strconv.Itoa(result.Remaining),
+		"RateLimit-Reset":     strconv.Itoa(seconds(result.Reset)),
+		"RateLimit-Policy":    strconv.Itoa(this.Rate.Limit) + ";w=" + strconv.Itoa(seconds(this.Rate.Period)),
+	}
+	if !result.Allowed {
+		headers["Retry-After"] = strconv.Itoa(seconds(result.RetryAfter))
+	}
+	return headers
+}
+
+func Middleware(options Options) alice.Constructor {
+	options.prepare()
+	if options.KeyFunc == nil {
+		options.KeyFunc = ByIP
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			key := options.KeyFunc(req)
+			if key == "" {
+				h.ServeHTTP(w, req)
+				return
+			}
+			result, ok := options.take(key)
+			if ok {
+				for header, value := range options.headers(result) {
+					w.Header().Set(header, value)
+				}
+			}
+			if result.Allowed {
+				h.ServeHTTP(w, req)
+			} else if options.LimitedHandler != nil {
+				options.LimitedHandler(w, req)
+			} else {
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			}
+		})
+	}
+}
+
+func FastHttpMiddleware(options Options) fastchain.Constructor {
+	options.prepare()
+	if options.FastHttpKeyFunc == nil {
+		options.FastHttpKeyFunc = FastHttpByIP
+	}
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			key := options.FastHttpKeyFunc(requestCtx)
+			if key == "" {
+				h(requestCtx)
+				return
+			}
+			result, ok := options.take(key)
+			if ok {
+				for header, value := range options.headers(result) {
+					requestCtx.Response.Header.Set(header, value)
+				}
+			}
+			if result.Allowed {
+				h(requestCtx)
+			} else if options.FastHttpLimitedHandler != nil {
+				options.FastHttpLimitedHandler(requestCtx)
+			} else {
+				requestCtx.Error(fasthttp.StatusMessage(fasthttp.StatusTooManyRequests), fasthttp.StatusTooManyRequests)
+			}
+		}
+	}
+}
+
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// slidingWindowResult computes the result of a sliding window from the count of the
+// current window, the count of the previous one and the time elapsed in the current one.
+func slidingWindowResult(rate Rate, allowed bool, current int64, previous int64, elapsed time.Duration) Result {
+	weight := 1 - float64(elapsed)/float64(rate.Period)
+	estimate := float64(previous)*weight + float64(current)
+	result := Result{Allowed: allowed, Limit: rate.Limit, Remaining: int(float64(rate.Limit) - estimate)}
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	if previous > 0 {
+		// the previous window stops weighing at the end of the current one
+		result.Reset = 2*rate.Period - elapsed
+	} else {
+		result.Reset = rate.Period - elapsed
+	}
+	if !allowed {
+		if previous > 0 && current < int64(rate.Limit) {
+			// wait for the weight of the previous window to drop below the limit
+			excess := estimate - float64(rate.Limit)
+			result.RetryAfter = time.Duration(excess / float64(previous) * float64(rate.Period))
+		} else {
+			result.RetryAfter = rate.Period - elapsed
+		}
+	}
+	return result
+}
+
+// tokenBucketResult computes the result of a token bucket from the tokens left after
+// the request.
+func tokenBucketResult(rate Rate, allowed bool, tokens float64) Result {
+	perToken := float64(rate.Period) / float64(rate.Limit)
+	result := Result{Allowed: allowed, Limit: rate.capacity(), Remaining: int(tokens)}
+	result.Reset = time.Duration((float64(rate.capacity()) - tokens) * perToken)
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+	return result
+}
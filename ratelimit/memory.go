@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+const memoryStoreShards = 64
+
+// MemoryStore keeps the limiters in memory, split into shards to reduce the lock
+// contention. Expired entries are removed every minute.
+type MemoryStore struct {
+	shards [memoryStoreShards]memoryShard
+	stop   chan bool
+}
+
+type memoryShard struct {
+	sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	// sliding window state
+	window   int64
+	current  int64
+	previous int64
+	// token bucket state
+	tokens float64
+	last   time.Time
+
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{stop: make(chan bool)}
+	for i := range store.shards {
+		store.shards[i].entries = map[string]*memoryEntry{}
+	}
+	go store.cleanup(time.Minute)
+	return store
+}
+
+func (this *MemoryStore) Take(key string, rate Rate, algorithm Algorithm, now time.Time) (Result, error) {
+	shard := this.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	entry, ok := shard.entries[key]
+	if !ok {
+		entry = &memoryEntry{window: -1, tokens: float64(rate.capacity()), last: now}
+		shard.entries[key] = entry
+	}
+	entry.expiresAt = now.Add(2 * rate.Period)
+	if algorithm == TokenBucket {
+		perToken := float64(rate.Period) / float64(rate.Limit)
+		if elapsed := now.Sub(entry.last); elapsed > 0 {
+			entry.tokens = math.Min(float64(rate.capacity()), entry.tokens+float64(elapsed)/perToken)
+			entry.last = now
+		}
+		allowed := entry.tokens >= 1
+		if allowed {
+			entry.tokens--
+		}
+		return tokenBucketResult(rate, allowed, entry.tokens), nil
+	}
+	window := now.UnixNano() / int64(rate.Period)
+	switch window {
+	case entry.window:
+	case entry.window + 1:
+		entry.window, entry.previous, entry.current = window, entry.current, 0
+	default:
+		entry.window, entry.previous, entry.current = window, 0, 0
+	}
+	elapsed := time.Duration(now.UnixNano() - window*int64(rate.Period))
+	weight := 1 - float64(elapsed)/float64(rate.Period)
+	allowed := float64(entry.previous)*weight+float64(entry.current) < float64(rate.Limit)
+	if allowed {
+		entry.current++
+	}
+	return slidingWindowResult(rate, allowed, entry.current, entry.previous, elapsed), nil
+}
+
+// Close stops the cleanup of the expired entries.
+func (this *MemoryStore) Close() {
+	close(this.stop)
+}
+
+func (this *MemoryStore) shard(key string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &this.shards[h.Sum32()%memoryStoreShards]
+}
+
+func (this *MemoryStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-this.stop:
+			return
+		case now := <-ticker.C:
+			for i := range this.shards {
+				shard := &this.shards[i]
+				shard.Lock()
+				for key, entry := range shard.entries {
+					if now.After(entry.expiresAt) {
+						delete(shard.entries, key)
+					}
+				}
+				shard.Unlock()
+			}
+		}
+	}
+}
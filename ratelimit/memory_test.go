@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// start is the beginning of a window for the periods used by the tests.
+var start = time.Unix(1700000040, 0)
+
+func testSlidingWindow(t *testing.T, store Store) {
+	t.Helper()
+	rate := PerMinute(3)
+	take := func(key string, now time.Time) Result {
+		t.Helper()
+		result, err := store.Take(key, rate, SlidingWindow, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	for i := 0; i < 3; i++ {
+		result := take("a", start.Add(time.Duration(i)*time.Second))
+		if !result.Allowed || result.Remaining != 2-i || result.Limit != 3 {
+			t.Fatalf("request %d: got %+v", i, result)
+		}
+	}
+	result := take("a", start.Add(10*time.Second))
+	if result.Allowed || result.RetryAfter != 50*time.Second {
+		t.Fatalf("the 4th request should be denied until the next window, got %+v", result)
+	}
+	if result = take("b", start.Add(10*time.Second)); !result.Allowed {
+		t.Fatalf("the keys should be limited separately, got %+v", result)
+	}
+	if result = take("a", start.Add(61*time.Second)); !result.Allowed {
+		t.Fatalf("1s in the next window the estimate is 3*59/60, got %+v", result)
+	}
+	// 3*58/60+1 requests, the previous window must weigh less than 2
+	result = take("a", start.Add(62*time.Second))
+	if result.Allowed || result.RetryAfter < 18*time.Second-time.Millisecond || result.RetryAfter > 18*time.Second+time.Millisecond {
+		t.Fatalf("the previous window should still weigh, got %+v", result)
+	}
+	if result = take("a", start.Add(81*time.Second)); !result.Allowed {
+		t.Fatalf("the weight of the previous window should have dropped, got %+v", result)
+	}
+	if result = take("a", start.Add(10*time.Minute)); !result.Allowed || result.Remaining != 2 {
+		t.Fatalf("the quota should be restored, got %+v", result)
+	}
+}
+
+func testTokenBucket(t *testing.T, store Store) {
+	t.Helper()
+	rate := Rate{Limit: 2, Period: time.Second, Burst: 4}
+	take := func(now time.Time) Result {
+		t.Helper()
+		result, err := store.Take("bucket", rate, TokenBucket, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	for i := 0; i < 4; i++ {
+		if result := take(start); !result.Allowed || result.Remaining != 3-i || result.Limit != 4 {
+			t.Fatalf("request %d: got %+v", i, result)
+		}
+	}
+	result := take(start)
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond || result.Reset != 2*time.Second {
+		t.Fatalf("the bucket should be empty, got %+v", result)
+	}
+	if result = take(start.Add(500 * time.Millisecond)); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("one token should be refilled, got %+v", result)
+	}
+	if result = take(start.Add(time.Hour)); !result.Allowed || result.Remaining != 3 {
+		t.Fatalf("the bucket should be full again, got %+v", result)
+	}
+}
+
+func TestMemoryStoreSlidingWindow(t *testing.T) {
+	store := NewMemoryStore()
+	defer store.Close()
+	testSlidingWindow(t, store)
+}
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	defer store.Close()
+	testTokenBucket(t, store)
+}
+
+func TestMemoryStoreCleanup(t *testing.T) {
+	store := &MemoryStore{stop: make(chan bool)}
+	for i := range store.shards {
+		store.shards[i].entries = map[string]*memoryEntry{}
+	}
+	defer store.Close()
+	store.Take("old", PerSecond(1), SlidingWindow, time.Now().Add(-time.Hour))
+	store.Take("new", PerSecond(1), SlidingWindow, time.Now())
+	go store.cleanup(10 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	shard := store.shard("old")
+	shard.Lock()
+	_, old := shard.entries["old"]
+	shard.Unlock()
+	shard = store.shard("new")
+	shard.Lock()
+	_, recent := shard.entries["new"]
+	shard.Unlock()
+	if old || !recent {
+		t.Fatalf("only the expired entry should be removed, old: %v, new: %v", old, recent)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	store := NewMemoryStore()
+	defer store.Close()
+	handler := Middleware(Options{Rate: PerMinute(1), Store: store})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Policy") != "1;w=60" {
+		t.Fatalf("the first request should pass, got %d %v", w.Code, w.Header())
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("the second request should be limited, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestMiddlewareDefaultStore(t *testing.T) {
+	options := Options{Rate: PerMinute(1)}
+	first, second := options, options
+	first.prepare()
+	second.prepare()
+	if first.Store == nil || first.Store != second.Store {
+		t.Fatal("the middlewares without a store should share the default one")
+	}
+}
+
+func TestMiddlewareInvalidRate(t *testing.T) {
+	for _, rate := range []Rate{{}, {Limit: 1}, {Period: time.Second}, {Limit: -1, Period: time.Second}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%+v should be refused", rate)
+				}
+			}()
+			Middleware(Options{Rate: rate})
+		}()
+	}
+}
@@ -0,0 +1,231 @@
+package ratelimit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tokenBucketScript = `
+local capacity = tonumber(ARGV[1])
+local perToken = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil then
+	tokens = capacity
+	last = now
+end
+if now > last then
+	tokens = math.min(capacity, tokens + (now - last) / perToken)
+	last = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "last", tostring(last))
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`
+
+const slidingWindowScript = `
+local limit = tonumber(ARGV[1])
+local weight = tonumber(ARGV[2])
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local previous = tonumber(redis.call("GET", KEYS[2]) or "0")
+local allowed = 0
+if previous * weight + current < limit then
+	current = redis.call("INCR", KEYS[1])
+	redis.call("PEXPIRE", KEYS[1], ARGV[3])
+	allowed = 1
+end
+return {allowed, current, previous}
+`
+
+type RedisOptions struct {
+	// Network defaults to tcp.
+	Network  string
+	Address  string
+	Password string
+	DB       int
+	// KeyPrefix defaults to ratelimit:
+	KeyPrefix string
+	// Timeout of the dials and commands, it defaults to one second.
+	Timeout time.Duration
+	// MaxIdle is the number of idle connections kept open, it defaults to 8.
+	MaxIdle int
+}
+
+// RedisStore keeps the limiters in Redis, or any server speaking its protocol and
+// running Lua scripts, so they're shared by all the instances of an app.
+type RedisStore struct {
+	options RedisOptions
+	idle    chan *respConn
+	scripts map[string]string
+}
+
+func NewRedisStore(options RedisOptions) *RedisStore {
+	if options.Network == "" {
+		options.Network = "tcp"
+	}
+	if options.KeyPrefix == "" {
+		options.KeyPrefix = "ratelimit:"
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = time.Second
+	}
+	if options.MaxIdle <= 0 {
+		options.MaxIdle = 8
+	}
+	return &RedisStore{options: options, idle: make(chan *respConn, options.MaxIdle), scripts: map[string]string{
+		tokenBucketScript:   sha1Hex(tokenBucketScript),
+		slidingWindowScript: sha1Hex(slidingWindowScript),
+	}}
+}
+
+func (this *RedisStore) Take(key string, rate Rate, algorithm Algorithm, now time.Time) (Result, error) {
+	// the braces keep the keys of a limiter in the same cluster slot
+	key = this.options.KeyPrefix + "{" + key + "}"
+	ttl := strconv.FormatInt(int64(2*rate.Period/time.Millisecond)+1000, 10)
+	if algorithm == TokenBucket {
+		perToken := float64(rate.Period/time.Millisecond) / float64(rate.Limit)
+		reply, err := this.eval(tokenBucketScript, []string{key}, strconv.Itoa(rate.capacity()), strconv.FormatFloat(perToken, 'f', -1, 64), strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10), ttl)
+		if err != nil {
+			return Result{}, err
+		}
+		if len(reply) != 2 {
+			return Result{}, errors.New("redis: unexpected token bucket reply")
+		}
+		tokens, err := strconv.ParseFloat(toString(reply[1]), 64)
+		if err != nil {
+			return Result{}, err
+		}
+		return tokenBucketResult(rate, toInt(reply[0]) == 1, tokens), nil
+	}
+	window := now.UnixNano() / int64(rate.Period)
+	elapsed := time.Duration(now.UnixNano() - window*int64(rate.Period))
+	weight := 1 - float64(elapsed)/float64(rate.Period)
+	reply, err := this.eval(slidingWindowScript, []string{key + ":" + strconv.FormatInt(window, 10), key + ":" + strconv.FormatInt(window-1, 10)}, strconv.Itoa(rate.Limit), strconv.FormatFloat(weight, 'f', -1, 64), ttl)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 3 {
+		return Result{}, errors.New("redis: unexpected sliding window reply")
+	}
+	return slidingWindowResult(rate, toInt(reply[0]) == 1, toInt(reply[1]), toInt(reply[2]), elapsed), nil
+}
+
+// eval runs a script by its hash, loading it on the first call.
+func (this *RedisStore) eval(script string, keys []string, args ...string) ([]interface{}, error) {
+	conn, err := this.get()
+	if err != nil {
+		return nil, err
+	}
+	command := append([]string{"EVALSHA", this.scripts[script], strconv.Itoa(len(keys))}, keys...)
+	command = append(command, args...)
+	reply, err := conn.do(this.options.Timeout, command...)
+	if replyErr, ok := reply.(respError); err == nil && ok && strings.HasPrefix(string(replyErr), "NOSCRIPT") {
+		command[0], command[1] = "EVAL", script
+		reply, err = conn.do(this.options.Timeout, command...)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	this.put(conn)
+	if replyErr, ok := reply.(respError); ok {
+		return nil, replyErr
+	}
+	items, ok := reply.([]interface{})
+	if !ok {
+		return nil, errors.New("redis: unexpected script reply")
+	}
+	return items, nil
+}
+
+func (this *RedisStore) get() (*respConn, error) {
+	select {
+	case conn := <-this.idle:
+		return conn, nil
+	default:
+	}
+	conn, err := dialRESP(this.options.Network, this.options.Address, this.options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if this.options.Password != "" {
+		if err = checkReply(conn.do(this.options.Timeout, "AUTH", this.options.Password)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if this.options.DB != 0 {
+		if err = checkReply(conn.do(this.options.Timeout, "SELECT", strconv.Itoa(this.options.DB))); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (this *RedisStore) put(conn *respConn) {
+	select {
+	case this.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// Close closes the idle connections.
+func (this *RedisStore) Close() {
+	for {
+		select {
+		case conn := <-this.idle:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func checkReply(reply interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	if replyErr, ok := reply.(respError); ok {
+		return replyErr
+	}
+	return nil
+}
+
+func toInt(v interface{}) int64 {
+	switch value := v.(type) {
+	case int64:
+		return value
+	case string:
+		i, _ := strconv.ParseInt(value, 10, 64)
+		return i
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case int64:
+		return strconv.FormatInt(value, 10)
+	}
+	return ""
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
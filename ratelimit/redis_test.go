@@ -0,0 +1,252 @@
+package ratelimit
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis speaks enough of the Redis protocol to run the limiter scripts, whose
+// Lua code is mirrored in Go. Set PILL_TEST_REDIS to the address of a real server to
+// run the scripts themselves.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mutex    sync.Mutex
+	scripts  map[string]string
+	strings  map[string]int64
+	hashes   map[string]map[string]string
+	commands []string
+	db       string
+	fail     string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	this := &fakeRedis{listener: listener, password: password, scripts: map[string]string{}, strings: map[string]int64{}, hashes: map[string]map[string]string{}}
+	go this.serve()
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	return this
+}
+
+func (this *fakeRedis) serve() {
+	for {
+		conn, err := this.listener.Accept()
+		if err != nil {
+			return
+		}
+		go this.handle(conn)
+	}
+}
+
+func (this *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader, writer := bufio.NewReader(conn), bufio.NewWriter(conn)
+	authenticated := this.password == ""
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		this.mutex.Lock()
+		this.commands = append(this.commands, strings.ToUpper(args[0]))
+		var reply string
+		switch {
+		case this.fail != "":
+			reply = "-" + this.fail + "\r\n"
+		case strings.ToUpper(args[0]) == "AUTH":
+			if authenticated = args[1] == this.password; authenticated {
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authenticated:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case strings.ToUpper(args[0]) == "SELECT":
+			this.db = args[1]
+			reply = "+OK\r\n"
+		case strings.ToUpper(args[0]) == "EVALSHA":
+			script, ok := this.scripts[args[1]]
+			if !ok {
+				reply = "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+				break
+			}
+			reply = this.run(script, args[2:])
+		case strings.ToUpper(args[0]) == "EVAL":
+			this.scripts[sha1Hex(args[1])] = args[1]
+			reply = this.run(args[1], args[2:])
+		default:
+			reply = "-ERR unknown command '" + args[0] + "'\r\n"
+		}
+		this.mutex.Unlock()
+		writer.WriteString(reply)
+		if writer.Flush() != nil {
+			return
+		}
+	}
+}
+
+// run executes a script with its numkeys, keys and args, and returns its RESP reply.
+func (this *fakeRedis) run(script string, args []string) string {
+	numKeys, _ := strconv.Atoi(args[0])
+	keys, argv := args[1:1+numKeys], args[1+numKeys:]
+	number := func(s string) float64 {
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+	switch script {
+	case tokenBucketScript:
+		capacity, perToken, now := number(argv[0]), number(argv[1]), number(argv[2])
+		state, ok := this.hashes[keys[0]]
+		tokens, last := capacity, now
+		if ok {
+			tokens, last = number(state["tokens"]), number(state["last"])
+		}
+		if now > last {
+			tokens = math.Min(capacity, tokens+(now-last)/perToken)
+			last = now
+		}
+		allowed := 0
+		if tokens >= 1 {
+			tokens--
+			allowed = 1
+		}
+		this.hashes[keys[0]] = map[string]string{"tokens": luaNumber(tokens), "last": luaNumber(last)}
+		return "*2\r\n:" + strconv.Itoa(allowed) + "\r\n" + bulk(luaNumber(tokens))
+	case slidingWindowScript:
+		limit, weight := number(argv[0]), number(argv[1])
+		current, previous := this.strings[keys[0]], this.strings[keys[1]]
+		allowed := 0
+		if float64(previous)*weight+float64(current) < limit {
+			this.strings[keys[0]]++
+			current = this.strings[keys[0]]
+			allowed = 1
+		}
+		return "*3\r\n:" + strconv.Itoa(allowed) + "\r\n:" + strconv.FormatInt(current, 10) + "\r\n:" + strconv.FormatInt(previous, 10) + "\r\n"
+	}
+	return "-ERR unknown script\r\n"
+}
+
+func (this *fakeRedis) count(command string) int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	n := 0
+	for _, c := range this.commands {
+		if c == command {
+			n++
+		}
+	}
+	return n
+}
+
+func (this *fakeRedis) setFailure(message string) {
+	this.mutex.Lock()
+	this.fail = message
+	this.mutex.Unlock()
+}
+
+// readCommand reads an array of bulk strings, the framing of the client commands.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") || !strings.HasSuffix(line, "\r\n") {
+		return nil, errors.New("invalid command header " + strconv.Quote(line))
+	}
+	count, err := strconv.Atoi(line[1 : len(line)-2])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, errors.New("invalid bulk header " + strconv.Quote(line))
+		}
+		size, err := strconv.Atoi(line[1 : len(line)-2])
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		if string(data[size:]) != "\r\n" {
+			return nil, errors.New("unterminated bulk string")
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+// luaNumber formats a number like tostring in Lua 5.1.
+func luaNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', 14, 64)
+}
+
+func newTestRedisStore(t *testing.T) (*RedisStore, *fakeRedis) {
+	if address := os.Getenv("PILL_TEST_REDIS"); address != "" {
+		store := NewRedisStore(RedisOptions{Address: address, KeyPrefix: "pilltest:" + strconv.FormatInt(time.Now().UnixNano(), 36) + ":"})
+		t.Cleanup(store.Close)
+		return store, nil
+	}
+	fake := newFakeRedis(t, "secret")
+	store := NewRedisStore(RedisOptions{Address: fake.listener.Addr().String(), Password: "secret", DB: 2})
+	t.Cleanup(store.Close)
+	return store, fake
+}
+
+func TestRedisStoreSlidingWindow(t *testing.T) {
+	store, fake := newTestRedisStore(t)
+	testSlidingWindow(t, store)
+	if fake != nil {
+		if fake.count("EVAL") != 1 || fake.count("EVALSHA") < 2 {
+			t.Errorf("the script should be loaded once and then run by hash, got %d EVAL and %d EVALSHA", fake.count("EVAL"), fake.count("EVALSHA"))
+		}
+		if fake.count("AUTH") != 1 || fake.db != "2" {
+			t.Errorf("the connection should be authenticated and select db 2, got %d AUTH and db %q", fake.count("AUTH"), fake.db)
+		}
+	}
+}
+
+func TestRedisStoreTokenBucket(t *testing.T) {
+	store, _ := newTestRedisStore(t)
+	testTokenBucket(t, store)
+}
+
+func TestRedisStoreError(t *testing.T) {
+	if os.Getenv("PILL_TEST_REDIS") != "" {
+		t.Skip("needs the fake server")
+	}
+	store, fake := newTestRedisStore(t)
+	fake.setFailure("ERR out of memory")
+	if _, err := store.Take("k", PerMinute(1), SlidingWindow, time.Now()); err == nil || err.Error() != "ERR out of memory" {
+		t.Fatalf("expected the error reply, got %v", err)
+	}
+	store.options.Address = "127.0.0.1:1"
+	store.Close()
+	if _, err := store.Take("k", PerMinute(1), SlidingWindow, time.Now()); err == nil {
+		t.Fatal("expected a dial error")
+	}
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// respConn is a minimal client of the Redis protocol (RESP2), enough to run the
+// limiter scripts without a Redis library.
+type respConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+type respError string
+
+func (this respError) Error() string {
+	return string(this)
+}
+
+func dialRESP(network string, address string, timeout time.Duration) (*respConn, error) {
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &respConn{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}, nil
+}
+
+// do sends a command and reads its reply: a string, an int64, nil, a []interface{} or
+// a respError.
+func (this *respConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	if timeout > 0 {
+		this.conn.SetDeadline(time.Now().Add(timeout))
+	}
+	this.writer.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		this.writer.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	if err := this.writer.Flush(); err != nil {
+		return nil, err
+	}
+	return this.readReply()
+}
+
+func (this *respConn) readReply() (interface{}, error) {
+	line, err := this.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("redis: invalid reply " + strconv.Quote(line))
+	}
+	payload := line[1 : len(line)-2]
+	switch line[0] {
+	case '+':
+		return payload, nil
+	case '-':
+		return respError(payload), nil
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err = io.ReadFull(this.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = this.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, errors.New("redis: invalid reply " + strconv.Quote(line))
+}
+
+func (this *respConn) Close() error {
+	return this.conn.Close()
+}
@@ -0,0 +1,113 @@
+package render
+
+import (
+	"net/http"
+
+	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/valyala/fasthttp"
+)
+
+func fastHttpWrite(requestCtx *fasthttp.RequestCtx, status int, contentType string, body []byte) {
+	requestCtx.SetContentType(contentType)
+	requestCtx.SetStatusCode(status)
+	requestCtx.SetBody(body)
+}
+
+func fastHttpInternalError(requestCtx *fasthttp.RequestCtx, err error) {
+	clean.Error(err)
+	requestCtx.Error(http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// FastHttpJSON writes v as JSON, without the excluded fields (see clean.JSON).
+func FastHttpJSON(requestCtx *fasthttp.RequestCtx, status int, v interface{}, excludedFields ...string) error {
+	body, err := encodeJSON(v, excludedFields...)
+	if err != nil {
+		fastHttpInternalError(requestCtx, err)
+		return err
+	}
+	fastHttpWrite(requestCtx, status, ContentTypeJSON, body)
+	return nil
+}
+
+func FastHttpXML(requestCtx *fasthttp.RequestCtx, status int, v interface{}) error {
+	body, err := encodeXML(v)
+	if err != nil {
+		fastHttpInternalError(requestCtx, err)
+		return err
+	}
+	fastHttpWrite(requestCtx, status, ContentTypeXML, body)
+	return nil
+}
+
+// FastHttpHTML executes the template named name (see templates.GetTemplate) with data.
+func FastHttpHTML(requestCtx *fasthttp.RequestCtx, status int, name string, data interface{}) error {
+	body, err := executeTemplate(name, data)
+	if err != nil {
+		fastHttpInternalError(requestCtx, err)
+		return err
+	}
+	fastHttpWrite(requestCtx, status, ContentTypeHTML, body)
+	return nil
+}
+
+func FastHttpText(requestCtx *fasthttp.RequestCtx, status int, text string) {
+	fastHttpWrite(requestCtx, status, ContentTypeText, []byte(text))
+}
+
+func FastHttpNoContent(requestCtx *fasthttp.RequestCtx) {
+	requestCtx.SetStatusCode(http.StatusNoContent)
+	requestCtx.ResetBody()
+}
+
+// FastHttpRedirect redirects to url with the given status, 302 Found by default.
+func FastHttpRedirect(requestCtx *fasthttp.RequestCtx, url string, status ...int) {
+	statusCode := http.StatusFound
+	if len(status) > 0 && status[0] != 0 {
+		statusCode = status[0]
+	}
+	requestCtx.Redirect(url, statusCode)
+}
+
+// FastHttpNegotiated writes v as JSON or XML, or with the template when one is given
+// and HTML is preferred, according to the Accept header. It responds with a 406
+// problem when none of them is acceptable.
+func FastHttpNegotiated(requestCtx *fasthttp.RequestCtx, status int, v interface{}, template ...string) error {
+	name := ""
+	if len(template) > 0 {
+		name = template[0]
+	}
+	requestCtx.Response.Header.Add("Vary", "Accept")
+	switch Negotiate(helpers.BytesToString(requestCtx.Request.Header.Peek("Accept")), offers(name)...) {
+	case "application/json":
+		return FastHttpJSON(requestCtx, status, v)
+	case "application/xml", "text/xml":
+		return FastHttpXML(requestCtx, status, v)
+	case "text/html":
+		return FastHttpHTML(requestCtx, status, name, v)
+	}
+	return FastHttpProblemResponse(requestCtx, NewProblem(http.StatusNotAcceptable, ""))
+}
+
+// FastHttpProblemResponse writes problem as application/problem+json, its status
+// defaults to 500 Internal Server Error.
+func FastHttpProblemResponse(requestCtx *fasthttp.RequestCtx, problem *Problem) error {
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	body, err := encodeJSON(problem)
+	if err != nil {
+		fastHttpInternalError(requestCtx, err)
+		return err
+	}
+	fastHttpWrite(requestCtx, problem.Status, ContentTypeProblem, body)
+	return nil
+}
+
+// FastHttpError writes a problem made of status and detail.
+func FastHttpError(requestCtx *fasthttp.RequestCtx, status int, detail string) error {
+	return FastHttpProblemResponse(requestCtx, NewProblem(status, detail))
+}
@@ -0,0 +1,106 @@
+package render
+
+import (
+	"net/http"
+
+	"github.com/nehmeroumani/pill.go/clean"
+)
+
+func write(w http.ResponseWriter, status int, contentType string, body []byte) error {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, err := w.Write(body)
+	return err
+}
+
+// JSON writes v as JSON, without the excluded fields (see clean.JSON).
+func JSON(w http.ResponseWriter, status int, v interface{}, excludedFields ...string) error {
+	body, err := encodeJSON(v, excludedFields...)
+	if err != nil {
+		clean.Error(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	return write(w, status, ContentTypeJSON, body)
+}
+
+func XML(w http.ResponseWriter, status int, v interface{}) error {
+	body, err := encodeXML(v)
+	if err != nil {
+		clean.Error(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	return write(w, status, ContentTypeXML, body)
+}
+
+// HTML executes the template named name (see templates.GetTemplate) with data.
+func HTML(w http.ResponseWriter, status int, name string, data interface{}) error {
+	body, err := executeTemplate(name, data)
+	if err != nil {
+		clean.Error(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	return write(w, status, ContentTypeHTML, body)
+}
+
+func Text(w http.ResponseWriter, status int, text string) error {
+	return write(w, status, ContentTypeText, []byte(text))
+}
+
+func NoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Redirect redirects to url with the given status, 302 Found by default.
+func Redirect(w http.ResponseWriter, req *http.Request, url string, status ...int) {
+	statusCode := http.StatusFound
+	if len(status) > 0 && status[0] != 0 {
+		statusCode = status[0]
+	}
+	http.Redirect(w, req, url, statusCode)
+}
+
+// Negotiated writes v as JSON or XML, or with the template when one is given and HTML
+// is preferred, according to the Accept header. It responds with a 406 problem when
+// none of them is acceptable.
+func Negotiated(w http.ResponseWriter, req *http.Request, status int, v interface{}, template ...string) error {
+	name := ""
+	if len(template) > 0 {
+		name = template[0]
+	}
+	w.Header().Add("Vary", "Accept")
+	switch Negotiate(req.Header.Get("Accept"), offers(name)...) {
+	case "application/json":
+		return JSON(w, status, v)
+	case "application/xml", "text/xml":
+		return XML(w, status, v)
+	case "text/html":
+		return HTML(w, status, name, v)
+	}
+	return ProblemResponse(w, NewProblem(http.StatusNotAcceptable, ""))
+}
+
+// ProblemResponse writes problem as application/problem+json, its status defaults to
+// 500 Internal Server Error.
+func ProblemResponse(w http.ResponseWriter, problem *Problem) error {
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	body, err := encodeJSON(problem)
+	if err != nil {
+		clean.Error(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	return write(w, problem.Status, ContentTypeProblem, body)
+}
+
+// Error writes a problem made of status and detail.
+func Error(w http.ResponseWriter, status int, detail string) error {
+	return ProblemResponse(w, NewProblem(status, detail))
+}
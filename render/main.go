@@ -0,0 +1,190 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/nehmeroumani/pill.go/templates"
+)
+
+const (
+	ContentTypeJSON    = "application/json; charset=utf-8"
+	ContentTypeXML     = "application/xml; charset=utf-8"
+	ContentTypeHTML    = "text/html; charset=utf-8"
+	ContentTypeText    = "text/plain; charset=utf-8"
+	ContentTypeProblem = "application/problem+json"
+)
+
+var ErrTemplateNotFound = errors.New("render: template not found")
+
+// Problem is an error response as described by RFC 9457, the extension members are
+// written next to the standard ones.
+type Problem struct {
+	Type       string                 `json:"type,omitempty"`
+	Title      string                 `json:"title,omitempty"`
+	Status     int                    `json:"status,omitempty"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Extensions map[string]interface{} `json:"-"`
+}
+
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+func (this *Problem) With(key string, value interface{}) *Problem {
+	if this.Extensions == nil {
+		this.Extensions = map[string]interface{}{}
+	}
+	this.Extensions[key] = value
+	return this
+}
+
+func (this *Problem) Error() string {
+	if this.Detail != "" {
+		return this.Title + ": " + this.Detail
+	}
+	return this.Title
+}
+
+func (this Problem) MarshalJSON() ([]byte, error) {
+	out := map[string]interface{}{}
+	for key, value := range this.Extensions {
+		out[key] = value
+	}
+	type problem Problem
+	data, err := json.Marshal(problem(this))
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// encodeJSON marshals v, removing the excluded fields with clean.JSON from it or from
+// each of its elements when it's a slice.
+func encodeJSON(v interface{}, excludedFields ...string) ([]byte, error) {
+	if len(excludedFields) > 0 && v != nil {
+		value := reflect.ValueOf(v)
+		if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+			cleaned := make([]map[string]interface{}, value.Len())
+			for i := range cleaned {
+				cleaned[i] = clean.JSON(value.Index(i).Interface(), excludedFields...)
+			}
+			v = cleaned
+		} else {
+			v = clean.JSON(v, excludedFields...)
+		}
+	}
+	return json.Marshal(v)
+}
+
+func encodeXML(v interface{}) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func executeTemplate(name string, data interface{}) ([]byte, error) {
+	tmpl := templates.GetTemplate(name)
+	if tmpl == nil {
+		return nil, ErrTemplateNotFound
+	}
+	buffer := &bytes.Buffer{}
+	if err := tmpl.Execute(buffer, data); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	ranges := []acceptRange{}
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+	}
+	// the most specific ranges come first
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return specificity(ranges[i].mediaType) > specificity(ranges[j].mediaType)
+	})
+	return ranges
+}
+
+func specificity(mediaType string) int {
+	if mediaType == "*/*" {
+		return 0
+	}
+	if strings.HasSuffix(mediaType, "/*") {
+		return 1
+	}
+	return 2
+}
+
+// Negotiate returns the offered media type preferred by an Accept header, the first
+// offer wins ties and an empty header accepts anything. It returns "" when no offer
+// is acceptable.
+func Negotiate(accept string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+	ranges := parseAccept(accept)
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		mediaType := strings.ToLower(offer)
+		if i := strings.Index(mediaType, ";"); i >= 0 {
+			mediaType = strings.TrimSpace(mediaType[:i])
+		}
+		for _, r := range ranges {
+			if r.mediaType == mediaType || r.mediaType == "*/*" ||
+				(strings.HasSuffix(r.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(r.mediaType, "*"))) {
+				if r.quality > bestQuality {
+					best, bestQuality = offer, r.quality
+				}
+				break
+			}
+		}
+	}
+	return best
+}
+
+// offers returns the media types a value can be negotiated to, HTML is only offered
+// when a template is given.
+func offers(template string) []string {
+	if template != "" {
+		return []string{"application/json", "application/xml", "text/xml", "text/html"}
+	}
+	return []string{"application/json", "application/xml", "text/xml"}
+}
@@ -0,0 +1,108 @@
+package render
+
+import (
+	"net/http"
+	"testing"
+	"text/template"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/nehmeroumani/pill.go/templates"
+	"github.com/valyala/fasthttp"
+)
+
+type article struct {
+	ID       int    `json:"id" xml:"id"`
+	Title    string `json:"title" xml:"title"`
+	Password string `json:"password,omitempty" xml:"-"`
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "application/xml", "text/html"}
+	for accept, expected := range map[string]string{
+		"":                                  "application/json",
+		"*/*":                               "application/json",
+		"text/html":                         "text/html",
+		"text/*":                            "text/html",
+		"application/xml, application/json": "application/json", // the first offer wins ties
+		"application/json;q=0.5, application/xml;q=0.9": "application/xml",
+		"text/html;q=0, */*;q=0.1":                      "application/json",
+		"TEXT/HTML":                                     "text/html",
+		"image/png":                                     "",
+		"application/json;q=0":                          "",
+	} {
+		if offer := Negotiate(accept, offers...); offer != expected {
+			t.Errorf("Negotiate(%q): expected %q, got %q", accept, expected, offer)
+		}
+	}
+	if offer := Negotiate("text/plain", "text/plain; charset=utf-8"); offer != "text/plain; charset=utf-8" {
+		t.Errorf("the parameters of the offers should be ignored, got %q", offer)
+	}
+	if offer := Negotiate("*/*"); offer != "" {
+		t.Errorf("nothing should be negotiated without offers, got %q", offer)
+	}
+}
+
+func TestProblem(t *testing.T) {
+	problem := NewProblem(http.StatusConflict, "already taken").With("field", "email")
+	data, err := problem.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"detail":"already taken","field":"email","status":409,"title":"Conflict"}` {
+		t.Errorf("unexpected problem %s", data)
+	}
+	if problem.Error() != "Conflict: already taken" {
+		t.Errorf("unexpected error %q", problem.Error())
+	}
+}
+
+func TestNegotiated(t *testing.T) {
+	templates.Templates = template.Must(template.New("article.html").Parse(`<h1>{{.Title}}</h1>`))
+	t.Cleanup(func() { templates.Templates = nil })
+	value := article{ID: 1, Title: "Hello", Password: "secret"}
+	clients := map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/html" {
+				Negotiated(w, req, http.StatusCreated, value, "article.html")
+			} else {
+				Negotiated(w, req, http.StatusCreated, value)
+			}
+		})),
+		"fasthttp": pilltest.NewFastHttp(t, func(requestCtx *fasthttp.RequestCtx) {
+			if string(requestCtx.Path()) == "/html" {
+				FastHttpNegotiated(requestCtx, http.StatusCreated, value, "article.html")
+			} else {
+				FastHttpNegotiated(requestCtx, http.StatusCreated, value)
+			}
+		}),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			client.Get("/").Do().ExpectStatus(http.StatusCreated).ExpectHeader("Content-Type", ContentTypeJSON).ExpectHeader("Vary", "Accept").ExpectJSON("title", "Hello")
+			client.Get("/").Header("Accept", "application/xml").Do().ExpectHeader("Content-Type", ContentTypeXML).ExpectBodyContains("<title>Hello</title>")
+			client.Get("/").Header("Accept", "text/xml;q=0.9, application/json;q=0.1").Do().ExpectHeader("Content-Type", ContentTypeXML)
+			// HTML is only offered with a template
+			client.Get("/").Header("Accept", "text/html").Do().ExpectStatus(http.StatusNotAcceptable).ExpectHeader("Content-Type", ContentTypeProblem).ExpectJSON("status", 406)
+			client.Get("/html").Header("Accept", "text/html,application/xhtml+xml,*/*;q=0.8").Do().ExpectStatus(http.StatusCreated).ExpectHeader("Content-Type", ContentTypeHTML).ExpectTemplate("article.html", value)
+		})
+	}
+}
+
+func TestJSONExcludedFields(t *testing.T) {
+	clients := map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			JSON(w, http.StatusOK, []article{{ID: 1, Password: "secret"}}, "password")
+		})),
+		"fasthttp": pilltest.NewFastHttp(t, func(requestCtx *fasthttp.RequestCtx) {
+			FastHttpJSON(requestCtx, http.StatusOK, []article{{ID: 1, Password: "secret"}}, "password")
+		}),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			response := client.Get("/").Do().ExpectJSON("0.id", 1)
+			if _, ok := response.JSONPath("0.password"); ok {
+				t.Errorf("the excluded field should be removed, got %s", response.Body)
+			}
+		})
+	}
+}
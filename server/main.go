@@ -0,0 +1,277 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/nehmeroumani/pill.go/health"
+	"github.com/nehmeroumani/pill.go/mailer"
+	"github.com/nehmeroumani/pill.go/sse"
+	"github.com/nehmeroumani/pill.go/ws"
+	"github.com/valyala/fasthttp"
+)
+
+type Config struct {
+	// Addr to listen on, ":8080" by default.
+	Addr string
+
+	// ReadTimeout bounds the reading of a whole request, 1 minute by default.
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout bounds the reading of the request headers on net/http servers,
+	// 10 seconds by default.
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout isn't set by default since it would cut the event streams.
+	WriteTimeout time.Duration
+	// IdleTimeout is how long keep-alive connections wait for the next request, 2
+	// minutes by default.
+	IdleTimeout time.Duration
+
+	// CertFile and KeyFile enable TLS, they're reloaded when they change so renewed
+	// certificates are picked without a restart.
+	CertFile string
+	KeyFile  string
+	// CertCheckInterval is how often the certificate files are checked for changes,
+	// 1 minute by default; a SIGHUP forces a reload.
+	CertCheckInterval time.Duration
+	// DisableHTTP2 turns HTTP/2 off on TLS net/http servers.
+	DisableHTTP2 bool
+
+	// MaxRequestBodySize is only used by fasthttp servers, it defaults to fasthttp's.
+	MaxRequestBodySize int
+
+	// DrainDelay is how long requests are still accepted once the shutdown began and
+	// readiness turned off, so load balancers have time to stop routing to the server.
+	DrainDelay time.Duration
+	// ShutdownTimeout is how long in-flight requests and shutdown hooks are waited
+	// for, 30 seconds by default.
+	ShutdownTimeout time.Duration
+	// Signals starting the graceful shutdown, SIGINT and SIGTERM by default.
+	Signals []os.Signal
+}
+
+// Server runs a net/http handler (mux.Mux, mux.GlobalRouter) or a fasthttp one
+// (fastmux.Mux, fastmux.GlobalRouter) until it's shut down.
+type Server struct {
+	config       Config
+	httpServer   *http.Server
+	fastServer   *fasthttp.Server
+	certs        *certReloader
+	hooks        []func(context.Context) error
+	hooksMutex   sync.Mutex
+	shutdownOnce sync.Once
+	shutdownErr  error
+	shuttingDown chan struct{}
+	stopped      chan struct{}
+}
+
+func newServer(config Config) *Server {
+	if config.Addr == "" {
+		config.Addr = ":8080"
+	}
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = time.Minute
+	}
+	if config.ReadHeaderTimeout <= 0 {
+		config.ReadHeaderTimeout = 10 * time.Second
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 2 * time.Minute
+	}
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = 30 * time.Second
+	}
+	if config.CertCheckInterval <= 0 {
+		config.CertCheckInterval = time.Minute
+	}
+	if len(config.Signals) == 0 {
+		config.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	this := &Server{config: config, shuttingDown: make(chan struct{}), stopped: make(chan struct{})}
+	this.OnShutdown(ws.DefaultHub.Shutdown)
+	this.OnShutdown(mailer.Shutdown)
+	return this
+}
+
+// New returns a server for a net/http handler.
+func New(handler http.Handler, config Config) *Server {
+	this := newServer(config)
+	this.httpServer = &http.Server{
+		Addr:              this.config.Addr,
+		Handler:           handler,
+		ReadTimeout:       this.config.ReadTimeout,
+		ReadHeaderTimeout: this.config.ReadHeaderTimeout,
+		WriteTimeout:      this.config.WriteTimeout,
+		IdleTimeout:       this.config.IdleTimeout,
+	}
+	if this.config.DisableHTTP2 {
+		this.httpServer.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	return this
+}
+
+// NewFastHttp returns a server for a fasthttp handler, e.g. fastMux.ServeHTTP.
+func NewFastHttp(handler fasthttp.RequestHandler, config Config) *Server {
+	this := newServer(config)
+	this.fastServer = &fasthttp.Server{
+		Handler:            handler,
+		ReadTimeout:        this.config.ReadTimeout,
+		WriteTimeout:       this.config.WriteTimeout,
+		IdleTimeout:        this.config.IdleTimeout,
+		MaxRequestBodySize: this.config.MaxRequestBodySize,
+	}
+	return this
+}
+
+// OnShutdown adds a hook run once the server stopped accepting requests, hooks run in
+// the order they were added. Stopping ws.DefaultHub and flushing the mailer are added
+// by default.
+func (this *Server) OnShutdown(hook func(context.Context) error) {
+	this.hooksMutex.Lock()
+	this.hooks = append(this.hooks, hook)
+	this.hooksMutex.Unlock()
+}
+
+// ShuttingDown is closed as soon as the shutdown begins, when health.DefaultRegistry
+// turns readiness off.
+func (this *Server) ShuttingDown() <-chan struct{} {
+	return this.shuttingDown
+}
+
+// ListenAndServe serves until one of the configured signals is received, then drains
+// the in-flight requests and runs the shutdown hooks within the shutdown timeout.
+func (this *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", this.config.Addr)
+	if err != nil {
+		return err
+	}
+	return this.Serve(listener)
+}
+
+// Serve is ListenAndServe with a custom listener.
+func (this *Server) Serve(listener net.Listener) error {
+	if this.config.CertFile != "" || this.config.KeyFile != "" {
+		certs, err := newCertReloader(this.config.CertFile, this.config.KeyFile, this.config.CertCheckInterval)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		this.certs = certs
+		listener = tls.NewListener(listener, this.tlsConfig())
+	}
+
+	notified := append([]os.Signal{}, this.config.Signals...)
+	if this.certs != nil {
+		// without certificates, SIGHUP keeps its default behavior
+		notified = append(notified, syscall.SIGHUP)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, notified...)
+	defer signal.Stop(signals)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if this.httpServer != nil {
+			serveErr <- this.httpServer.Serve(listener)
+		} else {
+			serveErr <- this.fastServer.Serve(listener)
+		}
+	}()
+
+	for {
+		select {
+		case err := <-serveErr:
+			select {
+			case <-this.shuttingDown:
+				// Shutdown was called directly, wait for it to finish
+				<-this.stopped
+				return this.shutdownErr
+			default:
+			}
+			if err == nil {
+				err = errors.New("server: stopped serving")
+			}
+			return err
+		case sig := <-signals:
+			if sig == syscall.SIGHUP && this.certs != nil {
+				if err := this.certs.reload(); err != nil {
+					clean.Error(err)
+				}
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), this.config.ShutdownTimeout)
+			err := this.Shutdown(ctx)
+			cancel()
+			return err
+		}
+	}
+}
+
+func (this *Server) tlsConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: this.certs.getCertificate,
+		NextProtos:     []string{"http/1.1"},
+	}
+	if this.httpServer != nil && !this.config.DisableHTTP2 {
+		// net/http serves HTTP/2 on the TLS connections of its listener when its
+		// TLSConfig offers h2
+		config.NextProtos = []string{"h2", "http/1.1"}
+		this.httpServer.TLSConfig = config
+	}
+	return config
+}
+
+// Shutdown stops accepting requests, waits for the in-flight ones and runs the hooks,
+// until ctx is done.
+func (this *Server) Shutdown(ctx context.Context) error {
+	this.shutdownOnce.Do(func() {
+		close(this.shuttingDown)
+		health.SetShuttingDown(true)
+		if this.config.DrainDelay > 0 {
+			select {
+			case <-time.After(this.config.DrainDelay):
+			case <-ctx.Done():
+			}
+		}
+		// the event streams would keep the in-flight requests from finishing
+		sse.DefaultBroker.Shutdown(ctx)
+		var err error
+		if this.httpServer != nil {
+			err = this.httpServer.Shutdown(ctx)
+		} else {
+			done := make(chan error, 1)
+			go func() { done <- this.fastServer.Shutdown() }()
+			select {
+			case err = <-done:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		}
+		this.hooksMutex.Lock()
+		hooks := append([]func(context.Context) error(nil), this.hooks...)
+		this.hooksMutex.Unlock()
+		for _, hook := range hooks {
+			if hookErr := hook(ctx); hookErr != nil {
+				clean.Error(hookErr)
+				if err == nil {
+					err = hookErr
+				}
+			}
+		}
+		if this.certs != nil {
+			this.certs.close()
+		}
+		this.shutdownErr = err
+		close(this.stopped)
+	})
+	return this.shutdownErr
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nehmeroumani/pill.go/health"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDefaults(t *testing.T) {
+	this := New(http.NotFoundHandler(), Config{})
+	if this.httpServer.Addr != ":8080" || this.httpServer.ReadTimeout != time.Minute || this.httpServer.ReadHeaderTimeout != 10*time.Second || this.httpServer.IdleTimeout != 2*time.Minute || this.httpServer.WriteTimeout != 0 {
+		t.Errorf("unexpected net/http defaults %+v", this.httpServer)
+	}
+	if this.config.ShutdownTimeout != 30*time.Second || len(this.config.Signals) != 2 || len(this.hooks) != 2 {
+		t.Errorf("unexpected defaults %+v", this.config)
+	}
+	fast := NewFastHttp(func(requestCtx *fasthttp.RequestCtx) {}, Config{ReadTimeout: time.Second})
+	if fast.fastServer.ReadTimeout != time.Second || fast.fastServer.IdleTimeout != 2*time.Minute {
+		t.Errorf("unexpected fasthttp defaults %+v", fast.fastServer)
+	}
+}
+
+// serve starts the server on a random port and returns its URL and the result of
+// Serve.
+func serve(t *testing.T, this *Server) (string, <-chan error) {
+	t.Cleanup(func() { health.SetShuttingDown(false) })
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(chan error, 1)
+	go func() { result <- this.Serve(listener) }()
+	url := "http://" + listener.Addr().String()
+	for i := 0; ; i++ {
+		if res, err := http.Get(url + "/ping"); err == nil {
+			res.Body.Close()
+			break
+		} else if i == 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return url, result
+}
+
+func TestShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	this := New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		w.Write([]byte("done"))
+	}), Config{})
+	var order []string
+	this.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "first")
+		return errors.New("first failed")
+	})
+	this.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "second")
+		return nil
+	})
+	url, result := serve(t, this)
+
+	slow := make(chan string, 1)
+	go func() {
+		res, err := http.Get(url + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		slow <- string(body)
+	}()
+	<-started
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- this.Shutdown(context.Background()) }()
+	select {
+	case <-this.ShuttingDown():
+	case <-time.After(time.Second):
+		t.Fatal("ShuttingDown should be closed once the shutdown began")
+	}
+	if !health.DefaultRegistry.ShuttingDown() {
+		t.Error("readiness should be turned off")
+	}
+	close(release)
+	if body := <-slow; body != "done" {
+		t.Errorf("the in-flight request should complete, got %q", body)
+	}
+	if err := <-shutdown; err == nil || err.Error() != "first failed" {
+		t.Errorf("expected the error of the first hook, got %v", err)
+	}
+	if err := <-result; err == nil || err.Error() != "first failed" {
+		t.Errorf("Serve should return the shutdown error, got %v", err)
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("the hooks should all run in order, got %v", order)
+	}
+	if err := this.Shutdown(context.Background()); err == nil {
+		t.Error("a second Shutdown should return the result of the first one")
+	}
+}
+
+func TestShutdownOnSignal(t *testing.T) {
+	this := NewFastHttp(func(requestCtx *fasthttp.RequestCtx) {}, Config{Signals: []os.Signal{syscall.SIGUSR1}, DrainDelay: 10 * time.Millisecond})
+	_, result := serve(t, this)
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the signal should shut the server down")
+	}
+}
+
+// writeCert writes a self-signed certificate for 127.0.0.1 and its key in dir.
+func writeCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestServeTLS(t *testing.T) {
+	certFile, keyFile := writeCert(t, t.TempDir())
+	signals := make([]os.Signal, 1, 2)
+	signals[0] = syscall.SIGUSR2
+	this := New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.Proto))
+	}), Config{CertFile: certFile, KeyFile: keyFile, Signals: signals})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { health.SetShuttingDown(false) })
+	result := make(chan error, 1)
+	go func() { result <- this.Serve(listener) }()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, ForceAttemptHTTP2: true}}
+	var res *http.Response
+	for i := 0; i < 100; i++ {
+		if res, err = client.Get("https://" + listener.Addr().String()); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "HTTP/2.0" {
+		t.Errorf("expected HTTP/2 over TLS, got %s", body)
+	}
+	if signals[:2][1] != nil {
+		t.Errorf("the configured signals shouldn't be modified, got %v", signals[:2])
+	}
+	if err := this.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+}
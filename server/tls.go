@@ -0,0 +1,90 @@
+package server
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves the certificate of a cert/key pair of files and reloads it when
+// one of them is modified.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	mutex    sync.RWMutex
+	cert     *tls.Certificate
+	modTime  time.Time
+	stop     chan struct{}
+}
+
+func newCertReloader(certFile string, keyFile string, interval time.Duration) (*certReloader, error) {
+	this := &certReloader{certFile: certFile, keyFile: keyFile, interval: interval, stop: make(chan struct{})}
+	if err := this.reload(); err != nil {
+		return nil, err
+	}
+	go this.watch()
+	return this, nil
+}
+
+func (this *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(this.certFile, this.keyFile)
+	if err != nil {
+		return err
+	}
+	modTime, _ := this.lastModified()
+	this.mutex.Lock()
+	this.cert = &cert
+	this.modTime = modTime
+	this.mutex.Unlock()
+	return nil
+}
+
+func (this *certReloader) lastModified() (time.Time, error) {
+	var modTime time.Time
+	for _, file := range []string{this.certFile, this.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime, nil
+}
+
+// watch reloads the certificate when its files change, a pair which doesn't load yet
+// (e.g. the key was written but not the cert) is retried on the next tick.
+func (this *certReloader) watch() {
+	ticker := time.NewTicker(this.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			modTime, err := this.lastModified()
+			if err != nil {
+				continue
+			}
+			this.mutex.RLock()
+			changed := modTime.After(this.modTime)
+			this.mutex.RUnlock()
+			if changed {
+				this.reload()
+			}
+		case <-this.stop:
+			return
+		}
+	}
+}
+
+func (this *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.cert, nil
+}
+
+func (this *certReloader) close() {
+	close(this.stop)
+}
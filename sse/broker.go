@@ -0,0 +1,307 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	// ReplaySize is the number of events kept to be replayed to reconnecting clients,
+	// 256 by default.
+	ReplaySize int
+	// Heartbeat is the period of the comments keeping the connections open through
+	// proxies and detecting the disconnected clients, 15 seconds by default.
+	Heartbeat time.Duration
+	// ClientBuffer is the number of events waiting to be written to a client before
+	// it's considered too slow and disconnected, 64 by default; it can resume with
+	// Last-Event-ID.
+	ClientBuffer int
+	// Retry is sent to the clients when they connect, 0 leaves the browser's default.
+	Retry time.Duration
+}
+
+var defaultOptions = Options{ReplaySize: 256, Heartbeat: 15 * time.Second, ClientBuffer: 64}
+
+// Client is an open event stream, addressed by the ID of its user like ws.User; 0 is
+// used for anonymous clients.
+type Client struct {
+	ID     int32
+	Topics []string
+	events chan *Event
+}
+
+// Broker fans the published events out to its clients, by user ID, by topic or to
+// all of them.
+type Broker struct {
+	options     Options
+	mutex       sync.RWMutex
+	connections map[*Client]bool
+	onlineUsers map[int32][]*Client
+	topics      map[string]map[*Client]bool
+	replay      []*replayEntry
+	lastID      uint64
+	stopped     bool
+}
+
+// replayEntry is a published event with its recipients.
+type replayEntry struct {
+	id    uint64
+	event *Event
+	all   bool
+	topic string
+	users map[int32]bool
+}
+
+func (this *replayEntry) matches(client *Client) bool {
+	if this.all || this.users[client.ID] {
+		return true
+	}
+	if this.topic != "" {
+		for _, topic := range client.Topics {
+			if topic == this.topic {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var DefaultBroker = NewBroker()
+
+func NewBroker(opts ...Options) *Broker {
+	options := defaultOptions
+	if len(opts) > 0 {
+		options = opts[0]
+		if options.ReplaySize < 0 {
+			options.ReplaySize = 0
+		} else if options.ReplaySize == 0 {
+			options.ReplaySize = defaultOptions.ReplaySize
+		}
+		if options.Heartbeat <= 0 {
+			options.Heartbeat = defaultOptions.Heartbeat
+		}
+		if options.ClientBuffer <= 0 {
+			options.ClientBuffer = defaultOptions.ClientBuffer
+		}
+	}
+	return &Broker{
+		options:     options,
+		connections: map[*Client]bool{},
+		onlineUsers: map[int32][]*Client{},
+		topics:      map[string]map[*Client]bool{},
+	}
+}
+
+// Broadcast sends event to all the clients.
+func (this *Broker) Broadcast(event Event) string {
+	return this.publish(&replayEntry{event: &event, all: true})
+}
+
+// Publish sends event to the clients subscribed to topic.
+func (this *Broker) Publish(topic string, event Event) string {
+	return this.publish(&replayEntry{event: &event, topic: topic})
+}
+
+// SendToUser sends event to all the clients of a user.
+func (this *Broker) SendToUser(userID int32, event Event) string {
+	return this.SendToUsers([]int32{userID}, event)
+}
+
+// SendToUsers sends event to all the clients of the users, it returns the ID given to
+// the event.
+func (this *Broker) SendToUsers(usersList []int32, event Event) string {
+	users := make(map[int32]bool, len(usersList))
+	for _, userID := range usersList {
+		users[userID] = true
+	}
+	return this.publish(&replayEntry{event: &event, users: users})
+}
+
+func (this *Broker) publish(entry *replayEntry) string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.lastID++
+	entry.id = this.lastID
+	entry.event.ID = strconv.FormatUint(entry.id, 10)
+	if this.options.ReplaySize > 0 {
+		if len(this.replay) == this.options.ReplaySize {
+			copy(this.replay, this.replay[1:])
+			this.replay = this.replay[:len(this.replay)-1]
+		}
+		this.replay = append(this.replay, entry)
+	}
+	switch {
+	case entry.all:
+		for client := range this.connections {
+			this.deliver(client, entry.event)
+		}
+	case entry.topic != "":
+		for client := range this.topics[entry.topic] {
+			this.deliver(client, entry.event)
+		}
+	default:
+		for userID := range entry.users {
+			for _, client := range this.onlineUsers[userID] {
+				this.deliver(client, entry.event)
+			}
+		}
+	}
+	return entry.event.ID
+}
+
+// deliver queues event without blocking, the clients which can't keep up are
+// disconnected. The broker must be locked.
+func (this *Broker) deliver(client *Client, event *Event) {
+	select {
+	case client.events <- event:
+	default:
+		this.unregister(client)
+	}
+}
+
+// register adds a client and queues the events it missed since lastEventID, under
+// the same lock as the publications so none is lost or sent twice.
+func (this *Broker) register(userID int32, topics []string, lastEventID string) *Client {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	client := &Client{ID: userID, Topics: topics}
+	var missed []*Event
+	if lastEventID != "" {
+		if last, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+			for _, entry := range this.replay {
+				if entry.id > last && entry.matches(client) {
+					missed = append(missed, entry.event)
+				}
+			}
+		}
+	}
+	client.events = make(chan *Event, this.options.ClientBuffer+len(missed))
+	for _, event := range missed {
+		client.events <- event
+	}
+	if this.stopped {
+		close(client.events)
+		return client
+	}
+	this.connections[client] = true
+	if userID != 0 {
+		this.onlineUsers[userID] = append(this.onlineUsers[userID], client)
+	}
+	for _, topic := range topics {
+		if this.topics[topic] == nil {
+			this.topics[topic] = map[*Client]bool{}
+		}
+		this.topics[topic][client] = true
+	}
+	return client
+}
+
+func (this *Broker) remove(client *Client) {
+	this.mutex.Lock()
+	this.unregister(client)
+	this.mutex.Unlock()
+}
+
+// unregister removes a client and closes its events channel. The broker must be
+// locked.
+func (this *Broker) unregister(client *Client) {
+	if !this.connections[client] {
+		return
+	}
+	delete(this.connections, client)
+	if conns, ok := this.onlineUsers[client.ID]; ok {
+		for i, conn := range conns {
+			if conn == client {
+				this.onlineUsers[client.ID] = append(conns[:i], conns[i+1:]...)
+				break
+			}
+		}
+		if len(this.onlineUsers[client.ID]) == 0 {
+			delete(this.onlineUsers, client.ID)
+		}
+	}
+	for _, topic := range client.Topics {
+		delete(this.topics[topic], client)
+		if len(this.topics[topic]) == 0 {
+			delete(this.topics, topic)
+		}
+	}
+	close(client.events)
+}
+
+// stream writes the events of client until it's unregistered, the connection fails
+// or done is closed.
+func (this *Broker) stream(client *Client, w *bufio.Writer, flush func() error, done <-chan struct{}) {
+	defer this.remove(client)
+	if this.options.Retry > 0 {
+		w.WriteString("retry: " + strconv.FormatInt(int64(this.options.Retry/time.Millisecond), 10) + "\n\n")
+	} else {
+		// sends the headers, so the client knows it's connected
+		writeComment(w, "connected")
+	}
+	if flush() != nil {
+		return
+	}
+	heartbeat := time.NewTicker(this.options.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event, ok := <-client.events:
+			if !ok {
+				return
+			}
+			WriteEvent(w, event)
+			// write the queued events before flushing
+			for n := len(client.events); n > 0; n-- {
+				if event, ok = <-client.events; !ok {
+					flush()
+					return
+				}
+				WriteEvent(w, event)
+			}
+		case <-heartbeat.C:
+			writeComment(w, "ping")
+		case <-done:
+			return
+		}
+		if flush() != nil {
+			return
+		}
+	}
+}
+
+// GetUser returns the clients of a user.
+func (this *Broker) GetUser(userID int32) []*Client {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	if conns, ok := this.onlineUsers[userID]; ok {
+		return append([]*Client(nil), conns...)
+	}
+	return nil
+}
+
+func (this *Broker) ConnectionsCount() int {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return len(this.connections)
+}
+
+func (this *Broker) OnlineUsersCount() int {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return len(this.onlineUsers)
+}
+
+// Shutdown ends all the streams, the clients reconnecting are closed at once.
+func (this *Broker) Shutdown(ctx context.Context) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.stopped = true
+	for client := range this.connections {
+		this.unregister(client)
+	}
+	return nil
+}
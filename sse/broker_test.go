@@ -0,0 +1,217 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+var stacks = []string{"net/http", "fasthttp"}
+
+// serve starts a server streaming the events of user 7 and of the news topic, on
+// both stacks.
+func serve(t *testing.T, broker *Broker) map[string]string {
+	userID := func(query string) int32 {
+		if query == "anonymous" {
+			return 0
+		}
+		return 7
+	}
+	server := httptest.NewServer(Handler(func(req *http.Request) int32 {
+		return userID(req.URL.Query().Get("user"))
+	}, []string{"news"}, broker))
+	t.Cleanup(server.Close)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fastServer := &fasthttp.Server{Handler: FastHttpHandler(func(requestCtx *fasthttp.RequestCtx) int32 {
+		return userID(string(requestCtx.QueryArgs().Peek("user")))
+	}, []string{"news"}, broker)}
+	go fastServer.Serve(listener)
+	t.Cleanup(func() {
+		fastServer.Shutdown()
+	})
+	return map[string]string{"net/http": server.URL, "fasthttp": "http://" + listener.Addr().String()}
+}
+
+// connect opens a stream and waits for its first comment, the client is registered
+// once it's received.
+func connect(t *testing.T, url string, lastEventID string) (*http.Response, *bufio.Reader) {
+	req, _ := http.NewRequest("GET", url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		response.Body.Close()
+	})
+	if response.Header.Get("Content-Type") != ContentType || response.Header.Get("Cache-Control") != "no-cache" {
+		t.Fatalf("unexpected headers %v", response.Header)
+	}
+	reader := bufio.NewReader(response.Body)
+	if line, err := reader.ReadString('\n'); err != nil || line != ": connected\n" {
+		t.Fatalf("expected the connected comment, got %q, %v", line, err)
+	}
+	reader.ReadString('\n')
+	return response, reader
+}
+
+// readEvents reads n events and returns their ids and data, ignoring the comments.
+func readEvents(t *testing.T, reader *bufio.Reader, n int) []string {
+	var events []string
+	event := ""
+	for len(events) < n {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended after %v: %v", events, err)
+		}
+		switch {
+		case line == "\n" && event != "":
+			events = append(events, event)
+			event = ""
+		case strings.HasPrefix(line, "id: "):
+			event = strings.TrimSpace(line[4:])
+		case strings.HasPrefix(line, "data: "):
+			event += " " + strings.TrimSpace(line[6:])
+		}
+	}
+	return events
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	for _, name := range stacks {
+		t.Run(name, func(t *testing.T) {
+			broker := NewBroker(Options{ReplaySize: 4})
+			url := serve(t, broker)[name]
+			first := broker.Broadcast(Event{Data: []byte("a")})
+			broker.Publish("news", Event{Data: []byte("b")})
+			broker.Publish("sports", Event{Data: []byte("c")})
+			broker.SendToUser(7, Event{Data: []byte("d")})
+			broker.SendToUsers([]int32{8, 9}, Event{Data: []byte("e")})
+			broker.Broadcast(Event{Data: []byte("f")})
+
+			// b fell out of the replay buffer, c and e aren't for the client
+			_, reader := connect(t, url, first)
+			if events := readEvents(t, reader, 2); strings.Join(events, ",") != "4 d,6 f" {
+				t.Errorf("expected the missed events d and f, got %v", events)
+			}
+			broker.Publish("news", Event{Data: []byte("g")})
+			if events := readEvents(t, reader, 1); events[0] != "7 g" {
+				t.Errorf("expected the live event g, got %v", events)
+			}
+
+			// without Last-Event-ID, nothing is replayed
+			_, reader = connect(t, url+"?user=anonymous", "")
+			broker.SendToUser(7, Event{Data: []byte("h")})
+			broker.Broadcast(Event{Data: []byte("i")})
+			if events := readEvents(t, reader, 1); events[0] != "9 i" {
+				t.Errorf("expected only the live broadcast, got %v", events)
+			}
+		})
+	}
+}
+
+func TestSlowClient(t *testing.T) {
+	broker := NewBroker(Options{ClientBuffer: 2})
+	client := broker.register(7, []string{"news"}, "")
+	for _, data := range []string{"a", "b", "c"} {
+		broker.SendToUser(7, Event{Data: []byte(data)})
+	}
+	if broker.ConnectionsCount() != 0 || broker.OnlineUsersCount() != 0 || broker.GetUser(7) != nil {
+		t.Fatalf("the client should be disconnected once its buffer is full")
+	}
+	broker.Publish("news", Event{Data: []byte("d")})
+
+	// the stream writes what was queued and ends
+	buffer := &bytes.Buffer{}
+	w := bufio.NewWriter(buffer)
+	done := make(chan struct{})
+	go func() {
+		broker.stream(client, w, w.Flush, nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the stream of a disconnected client should end")
+	}
+	if stream := buffer.String(); !strings.Contains(stream, "id: 2\ndata: b\n\n") || strings.Contains(stream, "data: c") {
+		t.Errorf("expected the queued events only, got %q", stream)
+	}
+
+	// it resumes with what it missed
+	client = broker.register(7, []string{"news"}, "2")
+	if len(client.events) != 2 || (<-client.events).ID != "3" || (<-client.events).ID != "4" {
+		t.Errorf("expected the events c and d to be replayed")
+	}
+}
+
+func TestSlowClientDisconnected(t *testing.T) {
+	for _, name := range stacks {
+		t.Run(name, func(t *testing.T) {
+			broker := NewBroker(Options{ClientBuffer: 1})
+			response, _ := connect(t, serve(t, broker)[name], "")
+			// nothing is read, the buffers fill up until the client's is full
+			data := bytes.Repeat([]byte("x"), 64*1024)
+			waitFor(t, func() bool {
+				broker.Broadcast(Event{Data: data})
+				return broker.ConnectionsCount() == 0
+			})
+			response.Body.Close()
+		})
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	for _, name := range stacks {
+		t.Run(name, func(t *testing.T) {
+			broker := NewBroker()
+			url := serve(t, broker)[name]
+			_, reader := connect(t, url, "")
+			broker.Shutdown(context.Background())
+			if _, err := reader.ReadString('\n'); err == nil {
+				t.Errorf("the stream should end on shutdown")
+			}
+			if broker.ConnectionsCount() != 0 {
+				t.Errorf("expected no connection left")
+			}
+			client := broker.register(7, nil, "")
+			if _, ok := <-client.events; ok || broker.ConnectionsCount() != 0 {
+				t.Errorf("the clients connecting after shutdown should be closed at once")
+			}
+		})
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	w := bufio.NewWriter(buffer)
+	WriteEvent(w, &Event{ID: "1\n", Name: "update", Data: []byte("line 1\r\nline 2"), Retry: 3 * time.Second})
+	w.Flush()
+	if expected := "id: 1\nevent: update\nretry: 3000\ndata: line 1\ndata: line 2\n\n"; buffer.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buffer.String())
+	}
+}
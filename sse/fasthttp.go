@@ -0,0 +1,36 @@
+package sse
+
+import (
+	"bufio"
+
+	"github.com/valyala/fasthttp"
+)
+
+// FastHttpServe is the fasthttp version of Serve. The stream is written once the
+// handler returned, the disconnected clients are detected on the next write, at the
+// latest on the next heartbeat.
+func (this *Broker) FastHttpServe(requestCtx *fasthttp.RequestCtx, userID int32, topics ...string) {
+	setHeaders(requestCtx.Response.Header.Set)
+	lastEventID := string(requestCtx.Request.Header.Peek("Last-Event-ID"))
+	if lastEventID == "" {
+		lastEventID = string(requestCtx.QueryArgs().Peek("lastEventId"))
+	}
+	// the server's Done channel is closed when it shuts down
+	done := requestCtx.Done()
+	requestCtx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		client := this.register(userID, topics, lastEventID)
+		this.stream(client, w, w.Flush, done)
+	})
+}
+
+// FastHttpHandler is the fasthttp version of Handler.
+func FastHttpHandler(userID func(requestCtx *fasthttp.RequestCtx) int32, topics []string, broker ...*Broker) fasthttp.RequestHandler {
+	b := getBroker(broker)
+	return func(requestCtx *fasthttp.RequestCtx) {
+		var id int32
+		if userID != nil {
+			id = userID(requestCtx)
+		}
+		b.FastHttpServe(requestCtx, id, topics...)
+	}
+}
@@ -0,0 +1,68 @@
+package sse
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Serve streams the events of user userID and of topics to the client until it
+// disconnects or the broker shuts down, e.g.
+//
+//	m.Get("/events").ThenFunc(func(w http.ResponseWriter, req *http.Request) {
+//		sse.DefaultBroker.Serve(w, req, userID, "news")
+//	})
+//
+// It resumes from the Last-Event-ID header, or the lastEventId query param. It answers
+// 500 if w can't be flushed.
+func (this *Broker) Serve(w http.ResponseWriter, req *http.Request, userID int32, topics ...string) {
+	controller := http.NewResponseController(w)
+	setHeaders(w.Header().Set)
+	if err := controller.Flush(); errors.Is(err, http.ErrNotSupported) {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	// the stream outlives the server's write timeout
+	controller.SetWriteDeadline(time.Time{})
+
+	lastEventID := req.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = req.URL.Query().Get("lastEventId")
+	}
+	client := this.register(userID, topics, lastEventID)
+	buffer := bufio.NewWriter(w)
+	this.stream(client, buffer, func() error {
+		if err := buffer.Flush(); err != nil {
+			return err
+		}
+		return controller.Flush()
+	}, req.Context().Done())
+}
+
+// Handler serves the streams of the topics of a broker, DefaultBroker by default, with
+// userID returning the ID of the user of a request.
+func Handler(userID func(req *http.Request) int32, topics []string, broker ...*Broker) http.Handler {
+	b := getBroker(broker)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var id int32
+		if userID != nil {
+			id = userID(req)
+		}
+		b.Serve(w, req, id, topics...)
+	})
+}
+
+func setHeaders(set func(key, value string)) {
+	set("Content-Type", ContentType)
+	set("Cache-Control", "no-cache")
+	// nginx buffers the responses by default
+	set("X-Accel-Buffering", "no")
+}
+
+func getBroker(broker []*Broker) *Broker {
+	if len(broker) > 0 && broker[0] != nil {
+		return broker[0]
+	}
+	return DefaultBroker
+}
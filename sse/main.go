@@ -0,0 +1,69 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ContentType = "text/event-stream"
+
+// Event is a server-sent event. The ID of the events published through a Broker is
+// set by the broker, clients resume from it with the Last-Event-ID header.
+type Event struct {
+	ID   string
+	Name string
+	Data []byte
+	// Retry tells the client how long to wait before reconnecting.
+	Retry time.Duration
+}
+
+// NewEvent returns an event named name with v encoded to JSON as data.
+func NewEvent(name string, v interface{}) (*Event, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{Name: name, Data: data}, nil
+}
+
+// WriteEvent writes event in the text/event-stream format, multiline data is split
+// in several data fields.
+func WriteEvent(w *bufio.Writer, event *Event) error {
+	if event.ID != "" {
+		w.WriteString("id: ")
+		w.WriteString(stripNewlines(event.ID))
+		w.WriteByte('\n')
+	}
+	if event.Name != "" {
+		w.WriteString("event: ")
+		w.WriteString(stripNewlines(event.Name))
+		w.WriteByte('\n')
+	}
+	if event.Retry > 0 {
+		w.WriteString("retry: ")
+		w.WriteString(strconv.FormatInt(int64(event.Retry/time.Millisecond), 10))
+		w.WriteByte('\n')
+	}
+	data := bytes.Replace(event.Data, []byte("\r\n"), []byte("\n"), -1)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		w.WriteString("data: ")
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	_, err := w.WriteString("\n")
+	return err
+}
+
+// writeComment writes a comment line, ignored by the clients; it's used as heartbeat.
+func writeComment(w *bufio.Writer, comment string) error {
+	_, err := w.WriteString(": " + stripNewlines(comment) + "\n\n")
+	return err
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -1,6 +1,8 @@
 package templates
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -8,6 +10,7 @@ import (
 	"text/template"
 
 	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/nehmeroumani/pill.go/health"
 )
 
 var tmplFuncs template.FuncMap
@@ -16,12 +19,22 @@ var Templates *template.Template
 var templatesPath string
 var tmplDelims []string
 
+var routeResolvers []func(string, ...interface{}) (string, error)
+
 func Setup(tmplsPath string, delims ...string) {
 	templatesPath = filepath.FromSlash(tmplsPath)
 	if delims != nil && len(delims) > 1 {
 		tmplDelims = []string{delims[0], delims[1]}
 	}
 	GetTemplates()
+	health.Register("templates", checkTemplates)
+}
+
+func checkTemplates(ctx context.Context) error {
+	if Templates == nil || len(Templates.Templates()) == 0 {
+		return errors.New("no template loaded from " + templatesPath)
+	}
+	return nil
 }
 
 func compileTemplates(filePaths []string) (*template.Template, error) {
@@ -77,6 +90,14 @@ func AddTmplFunc(funcName string, function interface{}) {
 	}
 }
 
+// AddRouteResolver makes the routes named in a router available to the RouteURL
+// template function, e.g. AddRouteResolver(m.URL) for a mux m.
+func AddRouteResolver(resolver func(name string, params ...interface{}) (string, error)) {
+	if resolver != nil {
+		routeResolvers = append(routeResolvers, resolver)
+	}
+}
+
 func GetTemplates() *template.Template {
 	if Templates == nil {
 		RegisterTmplFunc()
@@ -126,6 +147,8 @@ func RegisterTmplFunc() {
 	AddTmplFunc("URLPath", URLPath)
 	AddTmplFunc("YoutubeVideoID", YoutubeVideoID)
 	AddTmplFunc("IsSelectedNumVal", IsSelectedNumVal)
+	AddTmplFunc("RouteURL", RouteURL)
+	AddTmplFunc("Asset", Asset)
 }
 func GetTemplate(templateName string) *template.Template {
 	if Templates == nil {
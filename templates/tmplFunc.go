@@ -13,7 +13,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/nehmeroumani/pill.go/assets"
 	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/nehmeroumani/pill.go/helpers"
 	"github.com/nehmeroumani/pill.go/sanitize"
 )
 
@@ -625,6 +627,14 @@ func URL(u string, query map[string]string) string {
 	}
 	return u
 }
+
+// Asset returns the URL of a static file, fingerprinted by pill-assets.
+//
+// e.g. {{Asset "js/app.js"}} -> /static/js/app.3f9c1a.js
+func Asset(name string) string {
+	return assets.Asset(name)
+}
+
 func URLPath(u string) string {
 	return sanitize.URLPath(u)
 }
@@ -638,3 +648,25 @@ func IsSelectedNumVal(firstVal int32, secondVal int) string {
 	}
 	return ""
 }
+
+// RouteURL builds the path of a named route using the resolvers added with
+// AddRouteResolver.
+//
+// e.g. {{RouteURL "users.show" "id" .ID}} -> /users/5
+func RouteURL(name string, params ...interface{}) (string, error) {
+	var firstErr error
+	for _, resolver := range routeResolvers {
+		u, err := resolver(name, params...)
+		if err == nil {
+			return u, nil
+		}
+		// the error of the resolver knowing the route, e.g. a missing param, wins
+		if _, unknown := err.(helpers.UnknownRouteError); !unknown && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = helpers.UnknownRouteError(name)
+	}
+	return "", firstErr
+}
@@ -0,0 +1,42 @@
+package templates
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+)
+
+func TestRouteURL(t *testing.T) {
+	defer func(resolvers []func(string, ...interface{}) (string, error)) {
+		routeResolvers = resolvers
+	}(routeResolvers)
+	missingParam := errors.New("missing route param 'id'")
+	routeResolvers = nil
+	AddRouteResolver(func(name string, params ...interface{}) (string, error) {
+		switch name {
+		case "users.show":
+			return "", missingParam
+		case "home":
+			return "/", nil
+		}
+		return "", helpers.UnknownRouteError(name)
+	})
+	AddRouteResolver(func(name string, params ...interface{}) (string, error) {
+		if name == "api.status" {
+			return "/api/status", nil
+		}
+		return "", helpers.UnknownRouteError(name)
+	})
+	for name, expected := range map[string]string{"home": "/", "api.status": "/api/status"} {
+		if u, err := RouteURL(name); err != nil || u != expected {
+			t.Errorf("%s: expected %s, got %s, %v", name, expected, u, err)
+		}
+	}
+	if _, err := RouteURL("users.show"); err != missingParam {
+		t.Errorf("the error of the resolver knowing the route should be returned, got %v", err)
+	}
+	if _, err := RouteURL("posts.show"); err != helpers.UnknownRouteError("posts.show") {
+		t.Errorf("expected an unknown route error, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nehmeroumani/pill.go/clean"
+)
+
+// Exporter receives the ended and sampled spans, it must not block for long.
+type Exporter interface {
+	ExportSpan(span *Span)
+}
+
+var (
+	exporter      Exporter
+	exporterMutex sync.RWMutex
+)
+
+// SetExporter sets where spans go, nil (the default) drops them.
+func SetExporter(e Exporter) {
+	exporterMutex.Lock()
+	exporter = e
+	exporterMutex.Unlock()
+}
+
+func getExporter() Exporter {
+	exporterMutex.RLock()
+	defer exporterMutex.RUnlock()
+	return exporter
+}
+
+// JSONExporter writes each span as a line of JSON.
+type JSONExporter struct {
+	w     io.Writer
+	mutex sync.Mutex
+}
+
+// NewJSONExporter returns an exporter writing to w, os.Stdout by default.
+func NewJSONExporter(w ...io.Writer) *JSONExporter {
+	if len(w) > 0 && w[0] != nil {
+		return &JSONExporter{w: w[0]}
+	}
+	return &JSONExporter{w: os.Stdout}
+}
+
+type jsonSpan struct {
+	TraceID      string                 `json:"trace_id"`
+	SpanID       string                 `json:"span_id"`
+	ParentSpanID string                 `json:"parent_span_id,omitempty"`
+	Name         string                 `json:"name"`
+	Kind         SpanKind               `json:"kind"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	DurationMs   float64                `json:"duration_ms"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+func (this *JSONExporter) ExportSpan(span *Span) {
+	span.mutex.Lock()
+	out := jsonSpan{
+		TraceID:    span.Context.TraceID.String(),
+		SpanID:     span.Context.SpanID.String(),
+		Name:       span.Name,
+		Kind:       span.Kind,
+		Start:      span.Start,
+		End:        span.EndTime,
+		DurationMs: float64(span.EndTime.Sub(span.Start)) / float64(time.Millisecond),
+		Attributes: span.Attributes,
+		Error:      span.Error,
+	}
+	if span.ParentSpanID.IsValid() {
+		out.ParentSpanID = span.ParentSpanID.String()
+	}
+	data, err := json.Marshal(out)
+	span.mutex.Unlock()
+	if err != nil {
+		clean.Error(err)
+		return
+	}
+	this.mutex.Lock()
+	this.w.Write(append(data, '\n'))
+	this.mutex.Unlock()
+}
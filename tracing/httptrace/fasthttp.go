@@ -0,0 +1,40 @@
+package httptrace
+
+import (
+	"github.com/nehmeroumani/fastchain"
+	"github.com/nehmeroumani/pill.go/fastmux"
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/nehmeroumani/pill.go/tracing"
+	"github.com/valyala/fasthttp"
+)
+
+// FastHttpMiddleware is the fasthttp version of Middleware, the request context then
+// carries the span: tracing.SpanFromContext(requestCtx).
+func FastHttpMiddleware() fastchain.Constructor {
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(requestCtx *fasthttp.RequestCtx) {
+			traceparent := helpers.BytesToString(requestCtx.Request.Header.Peek(tracing.TraceparentHeader))
+			tracestate := helpers.BytesToString(requestCtx.Request.Header.Peek(tracing.TracestateHeader))
+			if sc, ok := tracing.Extract(traceparent, tracestate); ok {
+				tracing.SetRemote(requestCtx, sc)
+			}
+			method := string(requestCtx.Method())
+			route := fastmux.RoutePattern(requestCtx)
+			_, span := tracing.StartSpan(requestCtx, method+" "+routeOrPath(route, string(requestCtx.Path())), tracing.KindServer)
+			tracing.SetSpan(requestCtx, span)
+			span.SetAttribute("http.method", method)
+			span.SetAttribute("http.target", string(requestCtx.RequestURI()))
+			if route != "" {
+				span.SetAttribute("http.route", route)
+			}
+			requestCtx.Response.Header.Set(tracing.TraceparentHeader, span.Context.Traceparent())
+			if span.Context.TraceState != "" {
+				requestCtx.Response.Header.Set(tracing.TracestateHeader, span.Context.TraceState)
+			}
+			defer func() {
+				endServerSpan(span, requestCtx.Response.StatusCode())
+			}()
+			h(requestCtx)
+		}
+	}
+}
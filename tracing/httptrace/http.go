@@ -0,0 +1,62 @@
+// Package httptrace traces the requests handled by mux and fastmux with the tracing
+// package, which doesn't depend on the routers.
+package httptrace
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/justinas/alice"
+	"github.com/nehmeroumani/pill.go/middleware"
+	"github.com/nehmeroumani/pill.go/mux"
+	"github.com/nehmeroumani/pill.go/tracing"
+)
+
+// Middleware continues the trace of the traceparent header, or starts one, with a
+// server span for the request; its traceparent is sent back in the response. Used in
+// the route chains, the span is named after the route pattern instead of the path.
+func Middleware() alice.Constructor {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := req.Context()
+			if sc, ok := tracing.Extract(req.Header.Get(tracing.TraceparentHeader), req.Header.Get(tracing.TracestateHeader)); ok {
+				ctx = tracing.ContextWithRemote(ctx, sc)
+			}
+			route := mux.RoutePattern(req)
+			ctx, span := tracing.StartSpan(ctx, req.Method+" "+routeOrPath(route, req.URL.Path), tracing.KindServer)
+			span.SetAttribute("http.method", req.Method)
+			span.SetAttribute("http.target", req.URL.RequestURI())
+			if route != "" {
+				span.SetAttribute("http.route", route)
+			}
+			w.Header().Set(tracing.TraceparentHeader, span.Context.Traceparent())
+			if span.Context.TraceState != "" {
+				w.Header().Set(tracing.TracestateHeader, span.Context.TraceState)
+			}
+			recorder := middleware.NewStatusRecorder(w)
+			defer func() {
+				status := recorder.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				endServerSpan(span, status)
+			}()
+			h.ServeHTTP(recorder, req.WithContext(ctx))
+		})
+	}
+}
+
+func routeOrPath(route string, path string) string {
+	if route != "" {
+		return route
+	}
+	return path
+}
+
+func endServerSpan(span *tracing.Span, status int) {
+	span.SetAttribute("http.status_code", status)
+	if status >= 500 {
+		span.SetError(errors.New(http.StatusText(status)))
+	}
+	span.End()
+}
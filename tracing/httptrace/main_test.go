@@ -0,0 +1,105 @@
+package httptrace
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nehmeroumani/pill.go/pilltest"
+	"github.com/nehmeroumani/pill.go/tracing"
+	"github.com/valyala/fasthttp"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+type recordingExporter struct {
+	mutex sync.Mutex
+	spans []*tracing.Span
+}
+
+func (this *recordingExporter) ExportSpan(span *tracing.Span) {
+	this.mutex.Lock()
+	this.spans = append(this.spans, span)
+	this.mutex.Unlock()
+}
+
+func (this *recordingExporter) last() *tracing.Span {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if len(this.spans) == 0 {
+		return nil
+	}
+	return this.spans[len(this.spans)-1]
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracing.SetExporter(exporter)
+	defer tracing.SetExporter(nil)
+
+	clients := map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/fail" {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			w.Write([]byte(tracing.TraceIDFromContext(req.Context())))
+		}))),
+		"fasthttp": pilltest.NewFastHttp(t, FastHttpMiddleware()(func(requestCtx *fasthttp.RequestCtx) {
+			if string(requestCtx.Path()) == "/fail" {
+				requestCtx.SetStatusCode(fasthttp.StatusInternalServerError)
+			}
+			requestCtx.WriteString(tracing.TraceIDFromContext(requestCtx))
+		})),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			response := client.Get("/users?page=2").
+				Header(tracing.TraceparentHeader, traceparent).
+				Header(tracing.TracestateHeader, "vendor=value").
+				Do().
+				ExpectStatus(http.StatusOK).
+				ExpectBodyContains("4bf92f3577b34da6a3ce929d0e0e4736").
+				ExpectHeader(tracing.TracestateHeader, "vendor=value")
+			sc, ok := tracing.ParseTraceparent(response.Header.Get(tracing.TraceparentHeader))
+			span := exporter.last()
+			if !ok || span == nil || sc != (tracing.SpanContext{TraceID: span.Context.TraceID, SpanID: span.Context.SpanID, Flags: span.Context.Flags}) {
+				t.Fatalf("expected the traceparent of the exported span, got %q", response.Header.Get(tracing.TraceparentHeader))
+			}
+			if span.ParentSpanID.String() != "00f067aa0ba902b7" || span.Kind != tracing.KindServer || span.Name != "GET /users" {
+				t.Errorf("expected a server span child of the remote one, got %+v", span)
+			}
+			if span.Attributes["http.method"] != "GET" || span.Attributes["http.target"] != "/users?page=2" || span.Attributes["http.status_code"] != 200 || span.Error != "" {
+				t.Errorf("unexpected attributes %v and error %q", span.Attributes, span.Error)
+			}
+
+			response = client.Get("/fail").Do().ExpectStatus(http.StatusInternalServerError)
+			span = exporter.last()
+			if strings.Contains(response.Header.Get(tracing.TraceparentHeader), "4bf92f3577b34da6a3ce929d0e0e4736") || string(response.Body) != span.Context.TraceID.String() {
+				t.Errorf("expected a new trace, got %q", response.Header.Get(tracing.TraceparentHeader))
+			}
+			if span.ParentSpanID.IsValid() || span.Attributes["http.status_code"] != 500 || span.Error == "" {
+				t.Errorf("expected a failed root span, got %+v", span)
+			}
+		})
+	}
+}
+
+func TestMiddlewareInvalidTraceparent(t *testing.T) {
+	clients := map[string]*pilltest.Client{
+		"net/http": pilltest.New(t, Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))),
+		"fasthttp": pilltest.NewFastHttp(t, FastHttpMiddleware()(func(requestCtx *fasthttp.RequestCtx) {})),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			response := client.Get("/").Header(tracing.TraceparentHeader, "00-invalid").Do()
+			sc, ok := tracing.ParseTraceparent(response.Header.Get(tracing.TraceparentHeader))
+			if !ok || !sc.IsSampled() {
+				t.Errorf("expected a new sampled trace, got %q", response.Header.Get(tracing.TraceparentHeader))
+			}
+			if response.Header.Get(tracing.TracestateHeader) != "" {
+				t.Errorf("expected no tracestate")
+			}
+		})
+	}
+}
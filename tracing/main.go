@@ -0,0 +1,252 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+
+	FlagSampled byte = 0x01
+)
+
+type TraceID [16]byte
+
+func (this TraceID) String() string {
+	return hex.EncodeToString(this[:])
+}
+
+func (this TraceID) IsValid() bool {
+	return this != TraceID{}
+}
+
+type SpanID [8]byte
+
+func (this SpanID) String() string {
+	return hex.EncodeToString(this[:])
+}
+
+func (this SpanID) IsValid() bool {
+	return this != SpanID{}
+}
+
+// SpanContext is what's propagated between services in the traceparent and
+// tracestate headers.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+func (this SpanContext) IsValid() bool {
+	return this.TraceID.IsValid() && this.SpanID.IsValid()
+}
+
+func (this SpanContext) IsSampled() bool {
+	return this.Flags&FlagSampled != 0
+}
+
+// Traceparent formats the span context as a version 00 traceparent header.
+func (this SpanContext) Traceparent() string {
+	return "00-" + this.TraceID.String() + "-" + this.SpanID.String() + "-" + hex.EncodeToString([]byte{this.Flags})
+}
+
+// ParseTraceparent parses a traceparent header, future versions are read as version
+// 00 as the specification requires.
+func ParseTraceparent(traceparent string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, false
+	}
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || len(version) != 1 {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 || !isLowerHex(parts[1]+parts[2]+parts[3]) {
+		return sc, false
+	}
+	hex.Decode(sc.TraceID[:], []byte(parts[1]))
+	hex.Decode(sc.SpanID[:], []byte(parts[2]))
+	flags, _ := hex.DecodeString(parts[3])
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+type SpanKind string
+
+const (
+	KindInternal SpanKind = "internal"
+	KindServer   SpanKind = "server"
+	KindClient   SpanKind = "client"
+	KindProducer SpanKind = "producer"
+)
+
+type Span struct {
+	Name         string
+	Kind         SpanKind
+	Context      SpanContext
+	ParentSpanID SpanID
+	Start        time.Time
+	EndTime      time.Time
+	Attributes   map[string]interface{}
+	Error        string
+	mutex        sync.Mutex
+	ended        bool
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// StartSpan starts a span, child of the span of ctx or of the remote span context it
+// carries, and returns a context carrying it. Spans must be ended.
+func StartSpan(ctx context.Context, name string, kind ...SpanKind) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span := &Span{Name: name, Kind: KindInternal, Start: time.Now(), Attributes: map[string]interface{}{}}
+	if len(kind) > 0 {
+		span.Kind = kind[0]
+	}
+	parent, ok := parentContext(ctx)
+	if ok {
+		span.Context = SpanContext{TraceID: parent.TraceID, Flags: parent.Flags, TraceState: parent.TraceState}
+		span.ParentSpanID = parent.SpanID
+	} else {
+		rand.Read(span.Context.TraceID[:])
+		span.Context.Flags = FlagSampled
+	}
+	rand.Read(span.Context.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func parentContext(ctx context.Context) (SpanContext, bool) {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.Context, true
+	}
+	if sc, ok := ctx.Value(remoteKey{}).(SpanContext); ok && sc.IsValid() {
+		return sc, true
+	}
+	return SpanContext{}, false
+}
+
+// ContextWithRemote returns a context carrying a span context received from another
+// service, the next span started from it will be its child.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// UserValueSetter is a context storing its values in place, e.g. a
+// *fasthttp.RequestCtx.
+type UserValueSetter interface {
+	SetUserValue(key interface{}, value interface{})
+}
+
+// SetRemote is the version of ContextWithRemote for the contexts storing their values
+// in place.
+func SetRemote(ctx UserValueSetter, sc SpanContext) {
+	ctx.SetUserValue(remoteKey{}, sc)
+}
+
+// SetSpan makes span the span of a context storing its values in place.
+func SetSpan(ctx UserValueSetter, span *Span) {
+	ctx.SetUserValue(spanKey{}, span)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// TraceIDFromContext returns the trace id of the span of ctx, to be added to logs, or
+// "" when there's none.
+func TraceIDFromContext(ctx context.Context) string {
+	if sc, ok := parentContext(ctx); ok {
+		return sc.TraceID.String()
+	}
+	return ""
+}
+
+// Inject writes the traceparent and tracestate headers of the span of ctx, for an
+// outgoing request.
+func Inject(ctx context.Context, header http.Header) {
+	if sc, ok := parentContext(ctx); ok {
+		header.Set(TraceparentHeader, sc.Traceparent())
+		if sc.TraceState != "" {
+			header.Set(TracestateHeader, sc.TraceState)
+		}
+	}
+}
+
+// Extract reads the traceparent and tracestate headers of an incoming request.
+func Extract(traceparent string, tracestate string) (SpanContext, bool) {
+	sc, ok := ParseTraceparent(traceparent)
+	if ok {
+		sc.TraceState = strings.TrimSpace(tracestate)
+	}
+	return sc, ok
+}
+
+func (this *Span) SetAttribute(key string, value interface{}) {
+	if this == nil {
+		return
+	}
+	this.mutex.Lock()
+	this.Attributes[key] = value
+	this.mutex.Unlock()
+}
+
+// SetError marks the span as failed, a nil err is ignored.
+func (this *Span) SetError(err error) {
+	if this == nil || err == nil {
+		return
+	}
+	this.mutex.Lock()
+	this.Error = err.Error()
+	this.mutex.Unlock()
+}
+
+// End ends the span and hands it to the exporter if it's sampled, only the first call
+// counts.
+func (this *Span) End() {
+	if this == nil {
+		return
+	}
+	this.mutex.Lock()
+	if this.ended {
+		this.mutex.Unlock()
+		return
+	}
+	this.ended = true
+	this.EndTime = time.Now()
+	this.mutex.Unlock()
+	if this.Context.IsSampled() {
+		if exporter := getExporter(); exporter != nil {
+			exporter.ExportSpan(this)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+type recordingExporter struct {
+	mutex sync.Mutex
+	spans []*Span
+}
+
+func (this *recordingExporter) ExportSpan(span *Span) {
+	this.mutex.Lock()
+	this.spans = append(this.spans, span)
+	this.mutex.Unlock()
+}
+
+func TestParseTraceparent(t *testing.T) {
+	for _, test := range []struct {
+		traceparent string
+		valid       bool
+	}{
+		{traceparent, true},
+		{" " + traceparent + " ", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true},
+		// future versions may have more fields
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"0-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"zz-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false},
+		{"", false},
+	} {
+		sc, ok := ParseTraceparent(test.traceparent)
+		if ok != test.valid {
+			t.Errorf("%q: expected valid to be %v", test.traceparent, test.valid)
+			continue
+		}
+		if ok && sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || ok && sc.SpanID.String() != "00f067aa0ba902b7" {
+			t.Errorf("%q: unexpected ids %s and %s", test.traceparent, sc.TraceID, sc.SpanID)
+		}
+	}
+	sc, _ := ParseTraceparent(traceparent)
+	if !sc.IsSampled() || sc.Traceparent() != traceparent {
+		t.Errorf("expected a sampled span context formatted as %q, got %q", traceparent, sc.Traceparent())
+	}
+}
+
+func TestExtractAndInject(t *testing.T) {
+	sc, ok := Extract(traceparent, " vendor=value ")
+	if !ok || sc.TraceState != "vendor=value" {
+		t.Fatalf("expected the tracestate to be kept, got %q", sc.TraceState)
+	}
+	if _, ok := Extract("invalid", "vendor=value"); ok {
+		t.Errorf("an invalid traceparent should not be extracted")
+	}
+
+	header := http.Header{}
+	Inject(context.Background(), header)
+	if len(header) != 0 {
+		t.Errorf("nothing should be injected without a span, got %v", header)
+	}
+	ctx, span := StartSpan(ContextWithRemote(context.Background(), sc), "call", KindClient)
+	Inject(ctx, header)
+	if header.Get(TraceparentHeader) != span.Context.Traceparent() || header.Get(TracestateHeader) != "vendor=value" {
+		t.Errorf("unexpected headers %v", header)
+	}
+}
+
+func TestStartSpan(t *testing.T) {
+	ctx, root := StartSpan(nil, "root")
+	if !root.Context.IsValid() || !root.Context.IsSampled() || root.ParentSpanID.IsValid() || root.Kind != KindInternal {
+		t.Errorf("expected a sampled root span, got %+v", root.Context)
+	}
+	if SpanFromContext(ctx) != root || TraceIDFromContext(ctx) != root.Context.TraceID.String() {
+		t.Errorf("the context should carry the span")
+	}
+	_, child := StartSpan(ctx, "child")
+	if child.Context.TraceID != root.Context.TraceID || child.ParentSpanID != root.Context.SpanID || child.Context.SpanID == root.Context.SpanID {
+		t.Errorf("expected a child of the root span, got %+v", child)
+	}
+
+	remote, _ := Extract("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "vendor=value")
+	_, span := StartSpan(ContextWithRemote(context.Background(), remote), "server", KindServer)
+	if span.Context.TraceID != remote.TraceID || span.ParentSpanID != remote.SpanID || span.Context.IsSampled() || span.Context.TraceState != "vendor=value" {
+		t.Errorf("expected an unsampled child of the remote span, got %+v", span)
+	}
+	if TraceIDFromContext(context.Background()) != "" {
+		t.Errorf("expected no trace id without a span")
+	}
+}
+
+func TestEnd(t *testing.T) {
+	exporter := &recordingExporter{}
+	SetExporter(exporter)
+	defer SetExporter(nil)
+
+	_, span := StartSpan(context.Background(), "sampled")
+	span.SetAttribute("key", "value")
+	span.SetError(nil)
+	span.SetError(errors.New("failed"))
+	span.End()
+	span.End()
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, unsampled := StartSpan(ContextWithRemote(context.Background(), remote), "unsampled")
+	unsampled.End()
+	var nilSpan *Span
+	nilSpan.SetAttribute("key", "value")
+	nilSpan.End()
+
+	if len(exporter.spans) != 1 || exporter.spans[0] != span {
+		t.Fatalf("expected the sampled span to be exported once, got %d spans", len(exporter.spans))
+	}
+	if span.Error != "failed" || span.Attributes["key"] != "value" || span.EndTime.Before(span.Start) {
+		t.Errorf("unexpected span %+v", span)
+	}
+}
+
+func TestJSONExporter(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	ctx, parent := StartSpan(context.Background(), "parent")
+	_, span := StartSpan(ctx, "child", KindClient)
+	span.SetAttribute("http.status_code", 200)
+	span.End()
+	NewJSONExporter(buffer).ExportSpan(span)
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["trace_id"] != parent.Context.TraceID.String() || out["parent_span_id"] != parent.Context.SpanID.String() || out["kind"] != "client" {
+		t.Errorf("unexpected span %s", buffer.Bytes())
+	}
+	if attributes, _ := out["attributes"].(map[string]interface{}); attributes["http.status_code"] != 200.0 {
+		t.Errorf("unexpected attributes %s", buffer.Bytes())
+	}
+}
@@ -1,7 +1,9 @@
 package twilio
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -10,6 +12,7 @@ import (
 	"time"
 
 	"github.com/nehmeroumani/pill.go/clean"
+	"github.com/nehmeroumani/pill.go/tracing"
 )
 
 var accountSid, authToken, messagingServiceSid, from string
@@ -22,6 +25,14 @@ func Init(AccountSid, AuthToken, MessagingServiceSid, From string) {
 }
 
 func SendSms(to, body string) *SmsResponse {
+	return SendSmsContext(context.Background(), to, body)
+}
+
+// SendSmsContext is SendSms with a context, the request is recorded as a span.
+func SendSmsContext(ctx context.Context, to, body string) *SmsResponse {
+	ctx, span := tracing.StartSpan(ctx, "twilio.SendSms", tracing.KindClient)
+	defer span.End()
+
 	// Set initial variables
 	urlStr := "https://api.twilio.com/2010-04-01/Accounts/" + accountSid + "/Messages.json"
 
@@ -37,15 +48,22 @@ func SendSms(to, body string) *SmsResponse {
 	client := newClient()
 
 	req, _ := http.NewRequest("POST", urlStr, &rb)
+	req = req.WithContext(ctx)
+	tracing.Inject(ctx, req.Header)
 
 	req.SetBasicAuth(accountSid, authToken)
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
 	// Make request
-	resp, _ := client.Do(req)
+	resp, err := client.Do(req)
+	span.SetError(err)
 	if resp != nil {
 		defer resp.Body.Close()
+		span.SetAttribute("http.status_code", resp.StatusCode)
+		if resp.StatusCode >= 300 {
+			span.SetError(errors.New(resp.Status))
+		}
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			smsResponse := &SmsResponse{}
 			bodyBytes, _ := ioutil.ReadAll(resp.Body)
@@ -54,6 +72,7 @@ func SendSms(to, body string) *SmsResponse {
 				return smsResponse
 			} else {
 				clean.Error(err)
+				span.SetError(err)
 			}
 		}
 	}
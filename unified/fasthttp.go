@@ -0,0 +1,154 @@
+package unified
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/valyala/fasthttp"
+)
+
+type fastHttpContext struct {
+	requestCtx *fasthttp.RequestCtx
+	params     map[string]string
+}
+
+// NewFastHttpContext wraps a fasthttp request, params are its route params;
+// fastmux.Unified passes the ones of the mux.
+func NewFastHttpContext(requestCtx *fasthttp.RequestCtx, params ...map[string]string) Context {
+	this := &fastHttpContext{requestCtx: requestCtx}
+	if len(params) > 0 {
+		this.params = params[0]
+	}
+	return this
+}
+
+func (this *fastHttpContext) Method() string {
+	return string(this.requestCtx.Method())
+}
+
+func (this *fastHttpContext) Host() string {
+	return string(this.requestCtx.Host())
+}
+
+func (this *fastHttpContext) Path() string {
+	return string(this.requestCtx.Path())
+}
+
+func (this *fastHttpContext) Header(key string) string {
+	return string(this.requestCtx.Request.Header.Peek(key))
+}
+
+func (this *fastHttpContext) Query(key string) string {
+	return string(this.requestCtx.QueryArgs().Peek(key))
+}
+
+func (this *fastHttpContext) FormValue(key string) string {
+	return string(this.requestCtx.FormValue(key))
+}
+
+func (this *fastHttpContext) PostFormValue(key string) string {
+	if value := this.requestCtx.PostArgs().Peek(key); len(value) > 0 {
+		return string(value)
+	}
+	if form, err := this.requestCtx.MultipartForm(); err == nil {
+		if values := form.Value[key]; len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func (this *fastHttpContext) Cookie(name string) string {
+	return string(this.requestCtx.Request.Header.Cookie(name))
+}
+
+func (this *fastHttpContext) Body() ([]byte, error) {
+	return this.requestCtx.PostBody(), nil
+}
+
+func (this *fastHttpContext) RemoteIP() string {
+	return this.requestCtx.RemoteIP().String()
+}
+
+func (this *fastHttpContext) Param(key string) string {
+	return this.params[key]
+}
+
+func (this *fastHttpContext) Params() map[string]string {
+	return this.params
+}
+
+func (this *fastHttpContext) SetHeader(key string, value string) {
+	this.requestCtx.Response.Header.Set(key, value)
+}
+
+func (this *fastHttpContext) AddHeader(key string, value string) {
+	this.requestCtx.Response.Header.Add(key, value)
+}
+
+func (this *fastHttpContext) SetCookie(cookie *http.Cookie) {
+	this.requestCtx.Response.Header.SetCookie(toFastHttpCookie(cookie))
+}
+
+func (this *fastHttpContext) Status(statusCode int) {
+	this.requestCtx.SetStatusCode(statusCode)
+}
+
+func (this *fastHttpContext) Write(data []byte) (int, error) {
+	return this.requestCtx.Write(data)
+}
+
+func (this *fastHttpContext) Redirect(url string, statusCode int) {
+	this.requestCtx.Redirect(url, statusCode)
+}
+
+func (this *fastHttpContext) Value(key string) interface{} {
+	return this.requestCtx.UserValue(key)
+}
+
+func (this *fastHttpContext) SetValue(key string, value interface{}) {
+	this.requestCtx.SetUserValue(key, value)
+}
+
+func (this *fastHttpContext) Context() context.Context {
+	return this.requestCtx
+}
+
+func (this *fastHttpContext) Request() *http.Request {
+	return nil
+}
+
+func (this *fastHttpContext) ResponseWriter() http.ResponseWriter {
+	return nil
+}
+
+func (this *fastHttpContext) RequestCtx() *fasthttp.RequestCtx {
+	return this.requestCtx
+}
+
+func toFastHttpCookie(cookie *http.Cookie) *fasthttp.Cookie {
+	c := &fasthttp.Cookie{}
+	c.SetKey(cookie.Name)
+	c.SetValue(cookie.Value)
+	c.SetPath(cookie.Path)
+	c.SetDomain(cookie.Domain)
+	if !cookie.Expires.IsZero() {
+		c.SetExpire(cookie.Expires)
+	}
+	if cookie.MaxAge > 0 {
+		c.SetMaxAge(cookie.MaxAge)
+	} else if cookie.MaxAge < 0 {
+		c.SetExpire(fasthttp.CookieExpireDelete)
+	}
+	c.SetSecure(cookie.Secure)
+	c.SetHTTPOnly(cookie.HttpOnly)
+	switch cookie.SameSite {
+	case http.SameSiteLaxMode:
+		c.SetSameSite(fasthttp.CookieSameSiteLaxMode)
+	case http.SameSiteStrictMode:
+		c.SetSameSite(fasthttp.CookieSameSiteStrictMode)
+	case http.SameSiteNoneMode:
+		c.SetSameSite(fasthttp.CookieSameSiteNoneMode)
+	}
+	return c
+}
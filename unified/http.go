@@ -0,0 +1,151 @@
+package unified
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/nehmeroumani/pill.go/helpers"
+	"github.com/valyala/fasthttp"
+)
+
+type httpContext struct {
+	w      http.ResponseWriter
+	req    *http.Request
+	params map[string]string
+	body   []byte
+}
+
+// NewHTTPContext wraps a net/http request, params are its route params; mux.Unified
+// passes the ones of the mux.
+func NewHTTPContext(w http.ResponseWriter, req *http.Request, params ...map[string]string) Context {
+	this := &httpContext{w: w, req: req}
+	if len(params) > 0 {
+		this.params = params[0]
+	}
+	return this
+}
+
+func (this *httpContext) Method() string {
+	return this.req.Method
+}
+
+func (this *httpContext) Host() string {
+	return this.req.Host
+}
+
+func (this *httpContext) Path() string {
+	return this.req.URL.Path
+}
+
+func (this *httpContext) Header(key string) string {
+	return this.req.Header.Get(key)
+}
+
+func (this *httpContext) Query(key string) string {
+	return this.req.URL.Query().Get(key)
+}
+
+func (this *httpContext) FormValue(key string) string {
+	if value := this.req.URL.Query().Get(key); value != "" {
+		return value
+	}
+	return this.PostFormValue(key)
+}
+
+func (this *httpContext) PostFormValue(key string) string {
+	if !strings.HasPrefix(this.req.Header.Get("Content-Type"), "multipart/") {
+		// keep the urlencoded body readable by Body
+		this.Body()
+	}
+	return this.req.PostFormValue(key)
+}
+
+func (this *httpContext) Cookie(name string) string {
+	if cookie, err := this.req.Cookie(name); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// Body reads the body once and puts it back for the next readers.
+func (this *httpContext) Body() ([]byte, error) {
+	if this.body == nil {
+		if this.req.Body == nil {
+			return nil, nil
+		}
+		body, err := ioutil.ReadAll(this.req.Body)
+		if err != nil {
+			return nil, err
+		}
+		this.body = body
+		this.req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return this.body, nil
+}
+
+func (this *httpContext) RemoteIP() string {
+	if host, _, err := net.SplitHostPort(this.req.RemoteAddr); err == nil {
+		return host
+	}
+	return this.req.RemoteAddr
+}
+
+func (this *httpContext) Param(key string) string {
+	return this.params[key]
+}
+
+func (this *httpContext) Params() map[string]string {
+	return this.params
+}
+
+func (this *httpContext) SetHeader(key string, value string) {
+	this.w.Header().Set(key, value)
+}
+
+func (this *httpContext) AddHeader(key string, value string) {
+	this.w.Header().Add(key, value)
+}
+
+func (this *httpContext) SetCookie(cookie *http.Cookie) {
+	http.SetCookie(this.w, cookie)
+}
+
+func (this *httpContext) Status(statusCode int) {
+	this.w.WriteHeader(statusCode)
+}
+
+func (this *httpContext) Write(data []byte) (int, error) {
+	return this.w.Write(data)
+}
+
+func (this *httpContext) Redirect(url string, statusCode int) {
+	http.Redirect(this.w, this.req, url, statusCode)
+}
+
+func (this *httpContext) Value(key string) interface{} {
+	return this.req.Context().Value(helpers.ContextKey(key))
+}
+
+func (this *httpContext) SetValue(key string, value interface{}) {
+	this.req = this.req.WithContext(context.WithValue(this.req.Context(), helpers.ContextKey(key), value))
+}
+
+func (this *httpContext) Context() context.Context {
+	return this.req.Context()
+}
+
+func (this *httpContext) Request() *http.Request {
+	return this.req
+}
+
+func (this *httpContext) ResponseWriter() http.ResponseWriter {
+	return this.w
+}
+
+func (this *httpContext) RequestCtx() *fasthttp.RequestCtx {
+	return nil
+}
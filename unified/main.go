@@ -0,0 +1,60 @@
+package unified
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Context gives handlers and middlewares the same access to the request and the
+// response whether they run on net/http or on fasthttp.
+type Context interface {
+	Method() string
+	Host() string
+	Path() string
+	Header(key string) string
+	Query(key string) string
+	// FormValue looks in the query string, then in the urlencoded or multipart body.
+	FormValue(key string) string
+	// PostFormValue only looks in the urlencoded or multipart body. On net/http a
+	// multipart body is consumed by the form, Body returns nothing afterwards.
+	PostFormValue(key string) string
+	Cookie(name string) string
+	Body() ([]byte, error)
+	RemoteIP() string
+	// Param and Params return the route params the context was created with.
+	Param(key string) string
+	Params() map[string]string
+
+	SetHeader(key string, value string)
+	AddHeader(key string, value string)
+	SetCookie(cookie *http.Cookie)
+	Status(statusCode int)
+	Write(data []byte) (int, error)
+	Redirect(url string, statusCode int)
+
+	// Value and SetValue use the request context under helpers.ContextKey(key) on
+	// net/http and the user value key on fasthttp, where the pill middlewares store
+	// theirs, e.g. Value("requestID").
+	Value(key string) interface{}
+	SetValue(key string, value interface{})
+	Context() context.Context
+
+	// Request and ResponseWriter are nil on fasthttp, RequestCtx is nil on net/http.
+	Request() *http.Request
+	ResponseWriter() http.ResponseWriter
+	RequestCtx() *fasthttp.RequestCtx
+}
+
+type HandlerFunc func(c Context)
+
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Chain applies middlewares to h, the first one being the outermost.
+func Chain(h HandlerFunc, middlewares ...Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
@@ -0,0 +1,119 @@
+package unified
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+const form = "name=bob&tag=go"
+
+// newContexts returns the same POST /users?page=2&name=alice request on both stacks,
+// and functions returning the status, a header and the body of their responses.
+func newContexts() map[string]func() (Context, func() (int, http.Header, string)) {
+	return map[string]func() (Context, func() (int, http.Header, string)){
+		"net/http": func() (Context, func() (int, http.Header, string)) {
+			req := httptest.NewRequest("POST", "http://example.com/users?page=2&name=alice", strings.NewReader(form))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("X-Test", "yes")
+			req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+			w := httptest.NewRecorder()
+			return NewHTTPContext(w, req, map[string]string{"id": "7"}), func() (int, http.Header, string) {
+				return w.Code, w.Header(), w.Body.String()
+			}
+		},
+		"fasthttp": func() (Context, func() (int, http.Header, string)) {
+			requestCtx := &fasthttp.RequestCtx{}
+			requestCtx.Request.Header.SetMethod("POST")
+			requestCtx.Request.SetRequestURI("http://example.com/users?page=2&name=alice")
+			requestCtx.Request.Header.SetContentType("application/x-www-form-urlencoded")
+			requestCtx.Request.Header.Set("X-Test", "yes")
+			requestCtx.Request.Header.SetCookie("session", "abc")
+			requestCtx.Request.SetBodyString(form)
+			return NewFastHttpContext(requestCtx, map[string]string{"id": "7"}), func() (int, http.Header, string) {
+				header := http.Header{}
+				requestCtx.Response.Header.VisitAll(func(key []byte, value []byte) {
+					header.Add(string(key), string(value))
+				})
+				return requestCtx.Response.StatusCode(), header, string(requestCtx.Response.Body())
+			}
+		},
+	}
+}
+
+func TestContext(t *testing.T) {
+	for name, newContext := range newContexts() {
+		t.Run(name, func(t *testing.T) {
+			c, response := newContext()
+			for _, test := range []struct{ name, got, expected string }{
+				{"Method", c.Method(), "POST"},
+				{"Host", c.Host(), "example.com"},
+				{"Path", c.Path(), "/users"},
+				{"Header", c.Header("X-Test"), "yes"},
+				{"Query", c.Query("page"), "2"},
+				{"FormValue", c.FormValue("name"), "alice"},
+				{"FormValue of the body", c.FormValue("tag"), "go"},
+				{"PostFormValue", c.PostFormValue("name"), "bob"},
+				{"PostFormValue of the query string", c.PostFormValue("page"), ""},
+				{"Cookie", c.Cookie("session"), "abc"},
+				{"missing Cookie", c.Cookie("missing"), ""},
+				{"Param", c.Param("id"), "7"},
+			} {
+				if test.got != test.expected {
+					t.Errorf("%s: expected %q, got %q", test.name, test.expected, test.got)
+				}
+			}
+			if body, err := c.Body(); err != nil || string(body) != form {
+				t.Errorf("Body: expected %q, got %q, %v", form, body, err)
+			}
+
+			c.SetHeader("X-One", "1")
+			c.AddHeader("X-Many", "a")
+			c.AddHeader("X-Many", "b")
+			c.SetCookie(&http.Cookie{Name: "theme", Value: "dark", Path: "/", HttpOnly: true})
+			c.Status(http.StatusCreated)
+			c.Write([]byte("created"))
+			status, header, body := response()
+			if status != http.StatusCreated || body != "created" {
+				t.Errorf("expected 201 created, got %d %q", status, body)
+			}
+			if header.Get("X-One") != "1" || strings.Join(header.Values("X-Many"), ",") != "a,b" {
+				t.Errorf("unexpected headers %v", header)
+			}
+			if cookie := header.Get("Set-Cookie"); !strings.HasPrefix(cookie, "theme=dark") || !strings.Contains(strings.ToLower(cookie), "httponly") {
+				t.Errorf("unexpected cookie %q", cookie)
+			}
+		})
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	for name, newContext := range newContexts() {
+		t.Run(name, func(t *testing.T) {
+			c, response := newContext()
+			c.Redirect("/login", http.StatusSeeOther)
+			status, header, _ := response()
+			if status != http.StatusSeeOther || !strings.HasSuffix(header.Get("Location"), "/login") {
+				t.Errorf("expected a 303 to /login, got %d %q", status, header.Get("Location"))
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	for name, newContext := range newContexts() {
+		t.Run(name, func(t *testing.T) {
+			c, _ := newContext()
+			if c.Value("user") != nil {
+				t.Errorf("expected no value, got %v", c.Value("user"))
+			}
+			c.SetValue("user", 42)
+			if c.Value("user") != 42 {
+				t.Errorf("expected 42, got %v", c.Value("user"))
+			}
+		})
+	}
+}
@@ -1,10 +1,24 @@
 package ws
 
+import (
+	"context"
+	"sync/atomic"
+)
+
 type Hub struct {
+	// Register hands a user to Run; once the hub stopped, the users sent on it are
+	// closed at once.
 	Register    chan *User
 	Unregister  chan *User
 	connections map[*User]bool
 	onlineUsers map[int32][]*User
+	stop        chan chan struct{}
+	// stopped is closed when Run returns, nothing receives from Unregister anymore
+	stopped chan struct{}
+	running int32
+	// counts kept apart from the maps, which only Run may touch
+	connectionsCount int64
+	usersCount       int64
 }
 
 var DefaultHub = Hub{
@@ -12,19 +26,89 @@ var DefaultHub = Hub{
 	Unregister:  make(chan *User),
 	connections: make(map[*User]bool),
 	onlineUsers: make(map[int32][]*User),
+	stop:        make(chan chan struct{}),
+	stopped:     make(chan struct{}),
 }
 
+// Run serves the hub until Shutdown, it returns at once if the hub is already running
+// or was stopped; a stopped hub doesn't restart.
 func (this *Hub) Run() {
+	if this.stop == nil {
+		this.stop = make(chan chan struct{})
+	}
+	if this.stopped == nil {
+		this.stopped = make(chan struct{})
+	}
+	select {
+	case <-this.stopped:
+		return
+	default:
+	}
+	if !atomic.CompareAndSwapInt32(&this.running, 0, 1) {
+		return
+	}
 	for {
 		select {
 		case user := <-this.Register:
 			this.RegisterUser(user)
 		case user := <-this.Unregister:
 			this.UnregisterUser(user)
+		case done := <-this.stop:
+			// closing the Send channels makes the write pumps send a close message
+			for user := range this.connections {
+				this.UnregisterUser(user)
+			}
+			// closed before running is reset, so Run can't start again
+			close(this.stopped)
+			atomic.StoreInt32(&this.running, 0)
+			close(done)
+			go this.reject()
+			return
 		}
 	}
 }
 
+// Shutdown stops the hub and closes all its connections, it returns at once if the
+// hub isn't running.
+func (this *Hub) Shutdown(ctx context.Context) error {
+	if atomic.LoadInt32(&this.running) == 0 {
+		return nil
+	}
+	done := make(chan struct{})
+	select {
+	case this.stop <- done:
+	case <-this.stopped:
+		// stopped by a concurrent Shutdown
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reject closes the users registered once the hub stopped, so that their senders
+// don't block and their write pumps send a close message.
+func (this *Hub) reject() {
+	for user := range this.Register {
+		if user != nil {
+			close(user.Send)
+		}
+	}
+}
+
+// unregister hands a user to Run, or drops it once the hub is shut down.
+func (this *Hub) unregister(user *User) {
+	select {
+	case this.Unregister <- user:
+	case <-this.stopped:
+	}
+}
+
 func (this *Hub) RegisterUser(user *User) {
 	if user != nil {
 		this.connections[user] = true
@@ -32,13 +116,19 @@ func (this *Hub) RegisterUser(user *User) {
 			this.onlineUsers[user.ID] = append(conns, user)
 		} else {
 			this.onlineUsers[user.ID] = []*User{user}
+			atomic.AddInt64(&this.usersCount, 1)
 		}
+		atomic.AddInt64(&this.connectionsCount, 1)
 	}
 }
 
 func (this *Hub) UnregisterUser(user *User) {
 	if user != nil {
+		if _, ok := this.connections[user]; !ok {
+			return
+		}
 		delete(this.connections, user)
+		atomic.AddInt64(&this.connectionsCount, -1)
 		close(user.Send)
 		if conns, ok := this.onlineUsers[user.ID]; ok {
 			index := -1
@@ -53,11 +143,23 @@ func (this *Hub) UnregisterUser(user *User) {
 			}
 			if len(this.onlineUsers[user.ID]) == 0 {
 				delete(this.onlineUsers, user.ID)
+				atomic.AddInt64(&this.usersCount, -1)
 			}
 		}
 	}
 }
 
+// ConnectionsCount returns the number of open connections, it's safe to call from any
+// goroutine.
+func (this *Hub) ConnectionsCount() int {
+	return int(atomic.LoadInt64(&this.connectionsCount))
+}
+
+// OnlineUsersCount returns the number of users with at least one open connection.
+func (this *Hub) OnlineUsersCount() int {
+	return int(atomic.LoadInt64(&this.usersCount))
+}
+
 func (this *Hub) GetUser(userID int32) []*User {
 	if userID != 0 {
 		if conns, ok := this.onlineUsers[userID]; ok {
@@ -0,0 +1,117 @@
+package ws
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newHub() *Hub {
+	return &Hub{
+		Register:    make(chan *User),
+		Unregister:  make(chan *User),
+		connections: make(map[*User]bool),
+		onlineUsers: make(map[int32][]*User),
+		stop:        make(chan chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+}
+
+// runHub starts the hub and returns once Run handles the messages.
+func runHub(hub *Hub) {
+	go hub.Run()
+	user := &User{ID: 99, Send: make(chan []byte, 1)}
+	hub.Register <- user
+	hub.Unregister <- user
+}
+
+// closed reports if the Send channel of user gets closed.
+func closed(user *User) bool {
+	select {
+	case _, ok := <-user.Send:
+		return !ok
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestHub(t *testing.T) {
+	hub := newHub()
+	runHub(hub)
+	first, second, other := &User{ID: 1, Send: make(chan []byte, 1)}, &User{ID: 1, Send: make(chan []byte, 1)}, &User{ID: 2, Send: make(chan []byte, 1)}
+	for _, user := range []*User{first, second, other} {
+		hub.Register <- user
+	}
+	hub.Unregister <- second
+	// the hub handles the messages in order, so the unregistration is done once it
+	// received the next one
+	hub.Register <- nil
+	if hub.ConnectionsCount() != 2 || hub.OnlineUsersCount() != 2 {
+		t.Errorf("expected 2 connections of 2 users, got %d and %d", hub.ConnectionsCount(), hub.OnlineUsersCount())
+	}
+	if !closed(second) {
+		t.Error("the Send channel of an unregistered user should be closed")
+	}
+	if err := hub.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !closed(first) || !closed(other) || hub.ConnectionsCount() != 0 || hub.OnlineUsersCount() != 0 {
+		t.Error("the shutdown should close all the connections")
+	}
+}
+
+func TestHubShutdownTwice(t *testing.T) {
+	hub := newHub()
+	runHub(hub)
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			errs <- hub.Shutdown(ctx)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent shutdowns should all succeed, got %v", err)
+		}
+	}
+	if err := hub.Shutdown(context.Background()); err != nil {
+		t.Errorf("shutting a stopped hub down should succeed, got %v", err)
+	}
+	// Run doesn't restart a stopped hub, it would close stopped twice
+	hub.Run()
+}
+
+func TestHubAfterShutdown(t *testing.T) {
+	hub := newHub()
+	runHub(hub)
+	if err := hub.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	late := &User{ID: 3, Send: make(chan []byte, 1)}
+	select {
+	case hub.Register <- late:
+	case <-time.After(time.Second):
+		t.Fatal("registering on a stopped hub shouldn't block")
+	}
+	if !closed(late) {
+		t.Error("a user registered on a stopped hub should be closed at once")
+	}
+	done := make(chan struct{})
+	go func() {
+		hub.unregister(late)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("unregistering from a stopped hub shouldn't block")
+	}
+}
@@ -0,0 +1,20 @@
+package ws
+
+import "github.com/nehmeroumani/pill.go/metrics"
+
+// RegisterMetrics adds the connections and online users gauges of DefaultHub to a
+// registry, metrics.DefaultRegistry by default.
+func RegisterMetrics(registry ...*metrics.Registry) {
+	r := metrics.DefaultRegistry
+	if len(registry) > 0 && registry[0] != nil {
+		r = registry[0]
+	}
+	r.MustRegister(
+		metrics.NewGaugeFunc("ws_connections", "Number of open websocket connections.", func() float64 {
+			return float64(DefaultHub.ConnectionsCount())
+		}),
+		metrics.NewGaugeFunc("ws_online_users", "Number of users with an open websocket connection.", func() float64 {
+			return float64(DefaultHub.OnlineUsersCount())
+		}),
+	)
+}
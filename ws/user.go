@@ -49,15 +49,20 @@ func (this *User) ReadPump() {
 
 func (this *User) WritePump() {
 	ticker := time.NewTicker(pingPeriod)
+	unregistered := false
 	defer func() {
 		ticker.Stop()
 		this.ws.Close()
-		DefaultHub.Unregister <- this
+		if !unregistered {
+			DefaultHub.unregister(this)
+		}
 	}()
 	for {
 		select {
 		case message, ok := <-this.Send:
 			if !ok {
+				// the hub closed the channel, the user is already unregistered
+				unregistered = true
 				this.Write(websocket.CloseMessage, []byte{})
 				return
 			}